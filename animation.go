@@ -0,0 +1,374 @@
+package jtracer
+
+import (
+	"fmt"
+	"math"
+)
+
+// An animation moves a scene over a number of frames. Any property of the camera, the light or an object that moves
+// has a track of keyframes, each giving its value at a frame. Between keyframes the value is interpolated, easing as
+// the later keyframe says, and before the first keyframe or after the last it holds still. In a scene file the
+// tracks of a thing are given under its animate key:
+//
+//	- add: animation
+//	  frames: 48
+//	  fps: 24
+//	- add: sphere
+//	  material:
+//	    color: [1, 0.2, 0.2]
+//	  animate:
+//	    translate:
+//	      - {frame: 0, value: [0, 0, 0]}
+//	      - {frame: 47, value: [0, 2, 0], ease: ease-in-out}
+//	    reflective:
+//	      - {frame: 24, value: 0}
+//	      - {frame: 47, value: 0.8}
+//
+// The animation entry may be left out, when the animation runs until the last keyframe at 24 frames a second.
+
+// Easing says how a value moves between two keyframes
+type Easing int
+
+const (
+	Linear    Easing = iota // at a steady rate
+	EaseIn                  // starting slowly
+	EaseOut                 // slowing at the end
+	EaseInOut               // starting slowly and slowing at the end
+)
+
+var easingNames = []string{"linear", "ease-in", "ease-out", "ease-in-out"}
+
+func (e Easing) String() string {
+	if int(e) < len(easingNames) {
+		return easingNames[e]
+	}
+	return fmt.Sprintf("Easing(%d)", int(e))
+}
+
+// parseEasing returns the easing with the name String gives it
+func parseEasing(name string) (Easing, error) {
+	for e, n := range easingNames {
+		if n == name {
+			return Easing(e), nil
+		}
+	}
+	return Linear, fmt.Errorf("%w %q", ErrUnknownType, name)
+}
+
+// apply maps the fraction t of the time between two keyframes to the fraction of the way between their values
+func (e Easing) apply(t float64) float64 {
+	switch e {
+	case EaseIn:
+		return t * t * t
+	case EaseOut:
+		return 1 - math.Pow(1-t, 3)
+	case EaseInOut:
+		if t < 0.5 {
+			return 4 * t * t * t
+		}
+		return 1 - math.Pow(2-2*t, 3)/2
+	}
+	return t
+}
+
+// Keyframe is the value of a property at a frame, which may fall between whole frames
+type Keyframe struct {
+	Frame float64
+	Value []float64
+	Ease  Easing // how the value moves from the keyframe before
+}
+
+// Track is the keyframes of a property, in order of frame
+type Track []Keyframe
+
+// At returns the value of the property at a frame
+func (t Track) At(frame float64) []float64 {
+	if frame <= t[0].Frame {
+		return t[0].Value
+	}
+
+	for i := 1; i < len(t); i++ {
+		from, to := t[i-1], t[i]
+		if frame >= to.Frame {
+			continue
+		}
+
+		s := to.Ease.apply((frame - from.Frame) / (to.Frame - from.Frame))
+		v := make([]float64, len(to.Value))
+		for j := range v {
+			v[j] = from.Value[j] + s*(to.Value[j]-from.Value[j])
+		}
+		return v
+	}
+	return t[len(t)-1].Value
+}
+
+// Tracks holds the tracks of the properties of a thing by the names of the properties
+type Tracks map[string]Track
+
+// Animation holds the tracks of a scene's camera, light and objects
+type Animation struct {
+	Frames  int     // the frames are numbered from 0 to Frames-1
+	FPS     float64 // frames a second, for animated images
+	Camera  Tracks
+	Light   Tracks
+	Objects map[int]Tracks // by index in the scene's objects
+}
+
+// DefaultFPS is the frame rate of an animation that doesn't give one
+const DefaultFPS = 24
+
+// trackProperty is a property that can be animated, with the number of values it takes
+type trackProperty struct {
+	name string
+	size int
+}
+
+// The properties each kind of thing can animate, in the order they are saved
+var (
+	cameraProperties = []trackProperty{{"from", 3}, {"to", 3}, {"up", 3}, {"field-of-view", 1}}
+	lightProperties  = []trackProperty{{"at", 3}, {"intensity", 3}}
+	objectProperties = []trackProperty{
+		{"translate", 3}, {"scale", 3}, {"rotate-x", 1}, {"rotate-y", 1}, {"rotate-z", 1},
+		{"color", 3}, {"ambient", 1}, {"diffuse", 1}, {"specular", 1}, {"shininess", 1}, {"reflective", 1},
+		{"transparency", 1}, {"refractive-index", 1}, {"metallic", 1}, {"roughness", 1},
+	}
+)
+
+// trackProperties holds the properties each kind of thing can animate by the kind
+var trackProperties = map[string][]trackProperty{
+	"camera": cameraProperties,
+	"light":  lightProperties,
+	"plane":  objectProperties,
+	"sphere": objectProperties,
+}
+
+// checkTrack reports a track of a property that isn't among props, that has no keyframes, or whose keyframes have the
+// wrong number of values or are out of order
+func checkTrack(props []trackProperty, name string, t Track) error {
+	size := 0
+	for _, p := range props {
+		if p.name == name {
+			size = p.size
+		}
+	}
+	if size == 0 {
+		return fmt.Errorf("%w %q", ErrUnknownKey, name)
+	}
+
+	if len(t) == 0 {
+		return fmt.Errorf("%w: %s has no keyframes", ErrInvalidValue, name)
+	}
+	for i, k := range t {
+		if len(k.Value) != size {
+			return fmt.Errorf("%w: keyframe %d of %s has %d values, expected %d", ErrInvalidValue, i, name, len(k.Value), size)
+		}
+		if i > 0 && k.Frame <= t[i-1].Frame {
+			return fmt.Errorf("%w: keyframe %d of %s is at frame %g, which isn't after %g", ErrInvalidValue, i, name, k.Frame, t[i-1].Frame)
+		}
+	}
+	return nil
+}
+
+// holdView gives a camera that moves tracks that hold still the parts of its view that don't, as the view transform
+// alone doesn't say how far away the point the camera looks at is
+func (t Tracks) holdView(from, to, up []float64) {
+	view := map[string][]float64{"from": from, "to": to, "up": up}
+	if t["from"] == nil && t["to"] == nil && t["up"] == nil {
+		return
+	}
+	for name, v := range view {
+		if t[name] == nil {
+			t[name] = Track{{Value: v}}
+		}
+	}
+}
+
+// animation returns the scene's animation, adding one if it has none
+func (s *Scene) animation() *Animation {
+	if s.Animation == nil {
+		s.Animation = &Animation{FPS: DefaultFPS}
+	}
+	return s.Animation
+}
+
+// setTracks sets the tracks of the camera, the light, or the object just added, by the kind of thing being animated
+func (s *Scene) setTracks(kind string, tracks Tracks) {
+	if tracks == nil && s.Animation == nil {
+		return
+	}
+
+	a := s.animation()
+	switch kind {
+	case "camera":
+		a.Camera = tracks
+	case "light":
+		a.Light = tracks
+	default:
+		if tracks == nil {
+			break
+		}
+		if a.Objects == nil {
+			a.Objects = make(map[int]Tracks)
+		}
+		a.Objects[len(s.Objects)-1] = tracks
+	}
+}
+
+// setFrames makes an animation that wasn't given a length run until its last keyframe
+func (a *Animation) setFrames() {
+	if a.Frames > 0 {
+		return
+	}
+
+	last := 0.0
+	for _, tracks := range append([]Tracks{a.Camera, a.Light}, objectTracks(a.Objects)...) {
+		for _, t := range tracks {
+			last = math.Max(last, t[len(t)-1].Frame)
+		}
+	}
+	a.Frames = int(math.Floor(last)) + 1
+}
+
+func objectTracks(objects map[int]Tracks) []Tracks {
+	var all []Tracks
+	for _, tracks := range objects {
+		all = append(all, tracks)
+	}
+	return all
+}
+
+// Frame returns the scene as it is at a frame of its animation, as a scene without one. A scene that isn't animated
+// is the same at every frame. The objects that move are copies, so that frames can be rendered while others are made,
+// but only planes and spheres can be animated.
+func (s *Scene) Frame(n int) *Scene {
+	frame := *s
+	frame.Animation = nil
+
+	a := s.Animation
+	if a == nil {
+		return &frame
+	}
+
+	at := float64(n)
+	if len(a.Camera) > 0 {
+		frame.Camera = a.Camera.camera(s.Camera, at)
+	}
+	if len(a.Light) > 0 {
+		frame.Light = a.Light.light(s.Light, at)
+	}
+	if len(a.Objects) > 0 {
+		frame.Objects = append([]Shape(nil), s.Objects...)
+		for i, tracks := range a.Objects {
+			if i >= 0 && i < len(frame.Objects) && len(tracks) > 0 {
+				frame.Objects[i] = tracks.shape(s.Objects[i], at)
+			}
+		}
+	}
+	return &frame
+}
+
+// camera returns c moved to where the tracks have it at a frame. The camera's progress is reported on the same channel.
+func (t Tracks) camera(c Camera, frame float64) Camera {
+	fov := c.Fov
+	if track, ok := t["field-of-view"]; ok {
+		fov = track.At(frame)[0]
+	}
+
+	moved := NewCamera(c.Hsize, c.Vsize, fov)
+	moved.Progress = c.Progress
+	moved.Transform = c.Transform
+
+	from, to, up := cameraVectors(c)
+	aimed := false
+	for _, v := range []struct {
+		name  string
+		dst   **Tuple
+		point bool
+	}{{"from", &from, true}, {"to", &to, true}, {"up", &up, false}} {
+		if track, ok := t[v.name]; ok {
+			xyz := track.At(frame)
+			if v.point {
+				*v.dst = NewPoint(xyz[0], xyz[1], xyz[2])
+			} else {
+				*v.dst = NewVector(xyz[0], xyz[1], xyz[2])
+			}
+			aimed = true
+		}
+	}
+	if aimed {
+		moved.Transform = ViewTransform(from, to, up)
+	}
+	return moved
+}
+
+// light returns l moved to where the tracks have it at a frame
+func (t Tracks) light(l Light, frame float64) Light {
+	if track, ok := t["at"]; ok {
+		at := track.At(frame)
+		l.Position = *NewPoint(at[0], at[1], at[2])
+	}
+	if track, ok := t["intensity"]; ok {
+		i := track.At(frame)
+		l.Intensity = Color{i[0], i[1], i[2]}
+	}
+	return l
+}
+
+// shape returns a copy of s as the tracks have it at a frame. The animated scale, rotations and translation are
+// applied in that order after the shape's own transform, and the animated properties of its material replace its own.
+func (t Tracks) shape(s Shape, frame float64) Shape {
+	var moved Shape
+	var abstract *AbstractShape
+	switch s := s.(type) {
+	case *Sphere:
+		c := *s
+		moved, abstract = &c, &c.AbstractShape
+	case *Plane:
+		c := *s
+		moved, abstract = &c, &c.AbstractShape
+	default:
+		return s
+	}
+
+	motion := IdentityMatrix
+	if track, ok := t["scale"]; ok {
+		v := track.At(frame)
+		motion = Scaling(v[0], v[1], v[2]).Multiply(motion)
+	}
+	for _, r := range []struct {
+		name     string
+		rotation func(float64) Matrix
+	}{{"rotate-x", RotationX}, {"rotate-y", RotationY}, {"rotate-z", RotationZ}} {
+		if track, ok := t[r.name]; ok {
+			motion = r.rotation(track.At(frame)[0]).Multiply(motion)
+		}
+	}
+	if track, ok := t["translate"]; ok {
+		v := track.At(frame)
+		motion = NewTranslation(v[0], v[1], v[2]).Multiply(motion)
+	}
+	abstract.SetTransform(motion.Multiply(s.GetTransform()))
+
+	m := &abstract.Material
+	if track, ok := t["color"]; ok {
+		c := track.At(frame)
+		m.Color = Color{c[0], c[1], c[2]}
+	}
+	for name, dst := range map[string]*float64{
+		"ambient":          &m.Ambient,
+		"diffuse":          &m.Diffuse,
+		"specular":         &m.Specular,
+		"shininess":        &m.Shininess,
+		"reflective":       &m.Reflectivity,
+		"transparency":     &m.Transparency,
+		"refractive-index": &m.RefractiveIndex,
+		"metallic":         &m.Metallic,
+		"roughness":        &m.Roughness,
+	} {
+		if track, ok := t[name]; ok {
+			*dst = track.At(frame)[0]
+		}
+	}
+	return moved
+}
@@ -0,0 +1,233 @@
+package jtracer
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEasing_apply(t *testing.T) {
+	tests := []struct {
+		ease Easing
+		t    float64
+		want float64
+	}{
+		{ease: Linear, t: 0.25, want: 0.25},
+		{ease: EaseIn, t: 0.5, want: 0.125},
+		{ease: EaseOut, t: 0.5, want: 0.875},
+		{ease: EaseInOut, t: 0.25, want: 0.0625},
+		{ease: EaseInOut, t: 0.5, want: 0.5},
+		{ease: EaseInOut, t: 0.75, want: 0.9375},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ease.String(), func(t *testing.T) {
+			if got := tt.ease.apply(tt.t); math.Abs(got-tt.want) > epsilon {
+				t.Errorf("apply(%v) = %v, want %v", tt.t, got, tt.want)
+			}
+			if got := tt.ease.apply(0); got != 0 {
+				t.Errorf("apply(0) = %v, want 0", got)
+			}
+			if got := tt.ease.apply(1); math.Abs(got-1) > epsilon {
+				t.Errorf("apply(1) = %v, want 1", got)
+			}
+		})
+	}
+}
+
+func TestTrack_At(t *testing.T) {
+	track := Track{
+		{Frame: 10, Value: []float64{0, 10}},
+		{Frame: 20, Value: []float64{10, 20}},
+		{Frame: 30, Value: []float64{0, 10}, Ease: EaseIn},
+	}
+
+	tests := []struct {
+		name  string
+		frame float64
+		want  []float64
+	}{
+		{name: "before the first keyframe", frame: 0, want: []float64{0, 10}},
+		{name: "at a keyframe", frame: 20, want: []float64{10, 20}},
+		{name: "between keyframes", frame: 12.5, want: []float64{2.5, 12.5}},
+		{name: "easing in", frame: 25, want: []float64{8.75, 18.75}},
+		{name: "after the last keyframe", frame: 31, want: []float64{0, 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := track.At(tt.frame); !cmp.Equal(got, tt.want, float64Comparer) {
+				t.Errorf("At(%v) = %v, want %v", tt.frame, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScene_Frame(t *testing.T) {
+	scene, err := ParseScene([]byte(`
+- add: animation
+  frames: 11
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+  from: [0, 0, -5]
+  to: [0, 0, 0]
+  up: [0, 1, 0]
+  animate:
+    from:
+      - {frame: 0, value: [0, 0, -5]}
+      - {frame: 10, value: [0, 10, -5]}
+    field-of-view:
+      - {frame: 0, value: 1}
+      - {frame: 10, value: 2}
+- add: light
+  at: [0, 10, 0]
+  intensity: [1, 1, 1]
+  animate:
+    intensity:
+      - {frame: 0, value: [1, 1, 1]}
+      - {frame: 10, value: [0, 0, 0], ease: ease-out}
+- add: sphere
+  transform:
+    - [scale, 2, 2, 2]
+  material:
+    reflective: 0.5
+  animate:
+    translate:
+      - {frame: 0, value: [0, 0, 0]}
+      - {frame: 10, value: [10, 0, 0]}
+    rotate-y:
+      - {frame: 5, value: pi / 2}
+    color:
+      - {frame: 0, value: [1, 1, 1]}
+      - {frame: 10, value: [1, 0, 0]}
+- add: plane
+`), ".")
+	if err != nil {
+		t.Fatalf("ParseScene() error = %v", err)
+	}
+
+	frame := scene.Frame(5)
+	if frame.Animation != nil {
+		t.Errorf("Frame(5).Animation = %v, want nil", frame.Animation)
+	}
+
+	// the camera keeps looking at the point it was given as it moves
+	wantView := ViewTransform(NewPoint(0, 5, -5), NewPoint(0, 0, 0), NewVector(0, 1, 0))
+	if c := frame.Camera; !cmp.Equal(c.Transform, wantView, float64Comparer) || c.Fov != 1.5 {
+		t.Errorf("Frame(5) camera = %v, fov %v, want %v, fov 1.5", c.Transform, c.Fov, wantView)
+	}
+	if want := NewCamera(10, 10, 1.5); frame.Camera.PixelSize != want.PixelSize {
+		t.Errorf("Frame(5) camera pixel size = %v, want %v", frame.Camera.PixelSize, want.PixelSize)
+	}
+	if got := frame.Light.Intensity; !got.Equals(&Color{0.125, 0.125, 0.125}) {
+		t.Errorf("Frame(5) light intensity = %v, want 0.125", got)
+	}
+
+	sphere := frame.Objects[0]
+	want := NewTranslation(5, 0, 0).Multiply(RotationY(math.Pi / 2)).Multiply(Scaling(2, 2, 2))
+	if !cmp.Equal(sphere.GetTransform(), want, float64Comparer) {
+		t.Errorf("Frame(5) sphere transform = %v, want %v", sphere.GetTransform(), want)
+	}
+	if m := sphere.GetMaterial(); !m.Color.Equals(&Color{1, 0.5, 0.5}) || m.Reflectivity != 0.5 {
+		t.Errorf("Frame(5) sphere color = %v, reflective %v, want [1, 0.5, 0.5], 0.5", m.Color, m.Reflectivity)
+	}
+	if frame.Objects[1] != scene.Objects[1] {
+		t.Errorf("Frame(5) copied the plane, which doesn't move")
+	}
+
+	// the scene itself is left as it was
+	if got := scene.Objects[0].GetTransform(); !cmp.Equal(got, Scaling(2, 2, 2), float64Comparer) {
+		t.Errorf("scene sphere transform = %v, want it unchanged", got)
+	}
+	if got := scene.Camera.Fov; got != 1 {
+		t.Errorf("scene camera fov = %v, want 1", got)
+	}
+}
+
+func TestParseScene_AnimationFrames(t *testing.T) {
+	scene, err := ParseScene([]byte(`
+- add: sphere
+  animate:
+    scale:
+      - {frame: 0, value: [1, 1, 1]}
+      - {frame: 36.5, value: [2, 2, 2]}
+`), ".")
+	if err != nil {
+		t.Fatalf("ParseScene() error = %v", err)
+	}
+	if a := scene.Animation; a == nil || a.Frames != 37 || a.FPS != DefaultFPS {
+		t.Errorf("Animation = %+v, want 37 frames at %v a second", a, DefaultFPS)
+	}
+
+	still, err := ParseScene([]byte("- add: sphere\n"), ".")
+	if err != nil {
+		t.Fatalf("ParseScene() error = %v", err)
+	}
+	if still.Animation != nil {
+		t.Errorf("Animation = %+v, want nil for a still", still.Animation)
+	}
+	if frame := still.Frame(3); frame.Objects[0] != still.Objects[0] {
+		t.Errorf("Frame(3) of a still changed its objects")
+	}
+}
+
+func TestParseScene_AnimationErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		line int
+		key  string
+		want error
+	}{
+		{
+			name: "no frames",
+			yaml: "- add: animation\n  frames: 0\n",
+			line: 2, key: "frames",
+			want: ErrInvalidValue,
+		},
+		{
+			name: "a property that can't be animated",
+			yaml: "- add: sphere\n  animate:\n    shear:\n      - {frame: 0, value: 1}\n",
+			line: 4, key: "shear",
+			want: ErrUnknownKey,
+		},
+		{
+			name: "the wrong number of values",
+			yaml: "- add: light\n  at: [0, 0, 0]\n  intensity: [1, 1, 1]\n  animate:\n    at:\n      - {frame: 0, value: [1, 2]}\n",
+			line: 6, key: "at",
+			want: ErrInvalidValue,
+		},
+		{
+			name: "keyframes out of order",
+			yaml: "- add: sphere\n  animate:\n    rotate-x:\n      - {frame: 5, value: 0}\n      - {frame: 2, value: 1}\n",
+			line: 4, key: "rotate-x",
+			want: ErrInvalidValue,
+		},
+		{
+			name: "an unknown easing",
+			yaml: "- add: sphere\n  animate:\n    rotate-x:\n      - {frame: 0, value: 0, ease: bounce}\n",
+			line: 4, key: "ease",
+			want: ErrUnknownType,
+		},
+		{
+			name: "a keyframe without a frame",
+			yaml: "- add: sphere\n  animate:\n    rotate-x:\n      - {value: 0}\n",
+			line: 4, key: "frame",
+			want: ErrMissingKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseScene([]byte(tt.yaml), ".")
+			var se *SceneError
+			if !errors.As(err, &se) || !errors.Is(err, tt.want) {
+				t.Fatalf("ParseScene() error = %v, want a *SceneError for %v", err, tt.want)
+			}
+			if se.Index != 0 || se.Line != tt.line || se.Key != tt.key {
+				t.Errorf("ParseScene() error = %v, want entry 0, line %d, key %q", err, tt.line, tt.key)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package jtracer
+
+import "math"
+
+// Background gives the color seen along a ray direction that misses every object in the world
+type Background interface {
+	ColorAt(direction Tuple) Color
+}
+
+// SolidBackground is the same color in every direction
+type SolidBackground struct {
+	Color Color
+}
+
+func (b SolidBackground) ColorAt(_ Tuple) Color {
+	return b.Color
+}
+
+// GradientBackground blends vertically from Bottom, straight down, to Top, straight up
+type GradientBackground struct {
+	Bottom Color
+	Top    Color
+}
+
+func (b GradientBackground) ColorAt(direction Tuple) Color {
+	d := direction.Normalize()
+	return blend(b.Bottom, b.Top, (d.Y+1)/2)
+}
+
+// CubeMapBackground surrounds the world with six images, one for each face of a cube, indexed by CubeFace
+type CubeMapBackground struct {
+	Faces [6]*Canvas
+	Files [6]string // the image files the faces were loaded from, if any
+}
+
+func (b CubeMapBackground) ColorAt(direction Tuple) Color {
+	// scale the direction so that it touches the unit cube
+	scale := math.Max(math.Abs(direction.X), math.Max(math.Abs(direction.Y), math.Abs(direction.Z)))
+	p := *NewPoint(direction.X/scale, direction.Y/scale, direction.Z/scale)
+
+	face := FaceFromPoint(p)
+	u, v := CubeFaceUV(face, p)
+	return b.Faces[face].Sample(u, v)
+}
+
+// EquirectangularBackground wraps a single panorama around the world, with longitude along its width and latitude
+// along its height
+type EquirectangularBackground struct {
+	Image *Canvas
+	File  string // the image file the panorama was loaded from, if any
+}
+
+func (b EquirectangularBackground) ColorAt(direction Tuple) Color {
+	u, v := SphericalMap{}.Map(*direction.Normalize())
+	return b.Image.Sample(u, v)
+}
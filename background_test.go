@@ -0,0 +1,94 @@
+package jtracer
+
+import (
+	"testing"
+)
+
+func TestBackground_ColorAt(t *testing.T) {
+	green, blue := Color{0, 1, 0}, Color{0, 0, 1}
+
+	// a cube map with a differently colored 1x1 image on each face
+	faceColors := [6]Color{CubeLeft: Red, CubeFront: green, CubeRight: blue, CubeBack: White, CubeUp: {1, 1, 0}, CubeDown: Black}
+	var cube CubeMapBackground
+	for face, c := range faceColors {
+		cube.Faces[face] = NewCanvas(1, 1)
+		cube.Faces[face].Data[0][0] = c
+	}
+
+	// an equirectangular image with red in its left half and blue in its right half
+	panorama := NewCanvas(2, 1)
+	panorama.Data[0][0] = Red
+	panorama.Data[0][1] = blue
+
+	tests := []struct {
+		name       string
+		background Background
+		direction  Tuple
+		want       Color
+	}{
+		{
+			name:       "a solid background is the same in every direction",
+			background: SolidBackground{Color: blue},
+			direction:  *NewVector(0.3, -0.4, 1),
+			want:       blue,
+		},
+		{
+			name:       "a gradient background is the top color straight up",
+			background: GradientBackground{Bottom: Black, Top: White},
+			direction:  *NewVector(0, 5, 0),
+			want:       White,
+		},
+		{
+			name:       "a gradient background is the bottom color straight down",
+			background: GradientBackground{Bottom: Black, Top: White},
+			direction:  *NewVector(0, -1, 0),
+			want:       Black,
+		},
+		{
+			name:       "a gradient background is halfway at the horizon",
+			background: GradientBackground{Bottom: Black, Top: White},
+			direction:  *NewVector(1, 0, 1),
+			want:       Color{0.5, 0.5, 0.5},
+		},
+		{name: "a cube map looks up the left face", background: cube, direction: *NewVector(-2, 0.5, 1), want: Red},
+		{name: "a cube map looks up the front face", background: cube, direction: *NewVector(0.1, 0.2, 3), want: green},
+		{name: "a cube map looks up the right face", background: cube, direction: *NewVector(1, -0.3, 0), want: blue},
+		{name: "a cube map looks up the back face", background: cube, direction: *NewVector(0, 0, -0.5), want: White},
+		{name: "a cube map looks up the up face", background: cube, direction: *NewVector(0.2, 4, -1), want: Color{1, 1, 0}},
+		{name: "a cube map looks up the down face", background: cube, direction: *NewVector(0, -1, 0), want: Black},
+		{
+			name:       "an equirectangular background looks left of the seam",
+			background: EquirectangularBackground{Image: panorama},
+			direction:  *NewVector(1, 0, 0),
+			want:       Red,
+		},
+		{
+			name:       "an equirectangular background looks right of the seam",
+			background: EquirectangularBackground{Image: panorama},
+			direction:  *NewVector(-1, 0, 0),
+			want:       blue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.background.ColorAt(tt.direction); !got.Equals(&tt.want) {
+				t.Errorf("ColorAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorld_BackgroundColor(t *testing.T) {
+	blue := Color{0, 0, 1}
+	w := DefaultWorld()
+	r := NewRay(NewPoint(0, 0, -5), NewVector(0, 1, 0))
+
+	if got := w.ColorAt(r, MaxReflections); !got.Equals(&Black) {
+		t.Errorf("ColorAt() without a background = %v, want %v", got, Black)
+	}
+
+	w.Background = SolidBackground{Color: blue}
+	if got := w.ColorAt(r, MaxReflections); !got.Equals(&blue) {
+		t.Errorf("ColorAt() with a background = %v, want %v", got, blue)
+	}
+}
@@ -6,10 +6,14 @@ import (
 	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"image"
 	"jtracer"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 import _ "net/http/pprof"
@@ -186,6 +190,13 @@ func main() {
 
 	flag.Parse()
 
+	if flag.Arg(0) == "validate" {
+		os.Exit(validate(flag.Args()[1:]))
+	}
+	if flag.Arg(0) == "animate" {
+		os.Exit(animate(flag.Args()[1:]))
+	}
+
 	inputFileName := os.Args[len(os.Args)-1]
 
 	go func() {
@@ -202,14 +213,12 @@ func main() {
 
 	scene, err := jtracer.LoadSceneFile(inputFileName)
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
 	go func() {
-		canvas := scene.Camera.Render(jtracer.World{
-			Objects: scene.Objects,
-			Light:   scene.Light,
-		})
+		canvas := scene.Camera.Render(scene.World())
 		err = canvas.SavePNG(*outputFile)
 	}()
 
@@ -225,3 +234,99 @@ func main() {
 	}
 
 }
+
+// validate checks each scene file, printing any problems found, and returns the exit status: 1 when any scene has
+// errors, 0 when there are only warnings or none at all
+func validate(files []string) int {
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: jtracer validate scene.yaml|scene.json...")
+		return 2
+	}
+
+	status := 0
+	for _, file := range files {
+		problems, err := jtracer.ValidateSceneFile(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		if jtracer.HasErrors(problems) {
+			status = 1
+		}
+	}
+
+	return status
+}
+
+// animate renders every frame of a scene's animation to a numbered PNG, and to an animated GIF when asked for one,
+// and returns the exit status
+func animate(args []string) int {
+	flags := flag.NewFlagSet("animate", flag.ContinueOnError)
+	outputFile := flags.String("out", "frame.png", "Filename of the frames, which are numbered before the extension")
+	gifFile := flags.String("gif", "", "Filename of an animated GIF of the frames")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: jtracer animate [-out frame.png] [-gif animation.gif] scene.yaml|scene.json")
+		return 2
+	}
+
+	scene, err := jtracer.LoadSceneFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	frames, fps := 1, float64(jtracer.DefaultFPS)
+	if a := scene.Animation; a != nil {
+		frames, fps = a.Frames, a.FPS
+	}
+
+	// every frame's camera reports its progress on the scene camera's channel
+	go func() {
+		for range scene.Camera.Progress {
+		}
+	}()
+
+	var gifFrames []*image.Paletted
+	for n := 0; n < frames; n++ {
+		start := time.Now()
+		frame := scene.Frame(n)
+		canvas := frame.Camera.Render(frame.World())
+
+		file := frameFile(*outputFile, n, frames)
+		if err := canvas.SavePNG(file); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if *gifFile != "" {
+			gifFrames = append(gifFrames, canvas.ToPaletted())
+		}
+		fmt.Printf("frame %d of %d: %v in %v\n", n+1, frames, file, time.Since(start).Round(time.Millisecond))
+	}
+
+	if *gifFile != "" {
+		if err := jtracer.SaveGIF(*gifFile, gifFrames, fps); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println(*gifFile)
+	}
+	return 0
+}
+
+// frameFile numbers the file of frame n of frames, as frame-007.png for frame.png, with enough digits for the last
+func frameFile(name string, n, frames int) string {
+	digits := len(strconv.Itoa(frames - 1))
+	if digits < 3 {
+		digits = 3
+	}
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s-%0*d%s", strings.TrimSuffix(name, ext), digits, n, ext)
+}
@@ -41,7 +41,24 @@ func NewCamera(hsize, vsize, fov float64) Camera {
 	return c
 }
 
+// RayForPixel returns the ray from the camera through the center of a pixel, along with the ray differentials
+// through the pixels to its right and below it
 func (c *Camera) RayForPixel(px, py float64) Ray {
+	inverse := c.Transform.Inverse()
+	origin, direction := c.rayThrough(inverse, px, py)
+	_, dx := c.rayThrough(inverse, px+1, py)
+	_, dy := c.rayThrough(inverse, px, py+1)
+
+	return Ray{
+		Origin:        origin,
+		Direction:     direction,
+		Differentials: &RayDifferentials{XOrigin: *origin, XDirection: *dx, YOrigin: *origin, YDirection: *dy},
+	}
+}
+
+// rayThrough returns the origin and direction of the ray from the camera through the center of a pixel, given the
+// inverse of the camera's transform
+func (c *Camera) rayThrough(inverse Matrix, px, py float64) (origin, direction *Tuple) {
 	// the offset from the edge of the canvas to the pixel's center
 	xOffset := (px + 0.5) * c.PixelSize
 	yOffset := (py + 0.5) * c.PixelSize
@@ -54,11 +71,11 @@ func (c *Camera) RayForPixel(px, py float64) Ray {
 	// and then compute the ray's direction vector.
 	// (remember that the canvas is at z=-1)
 
-	pixel := c.Transform.Inverse().MultiplyByTuple(*NewPoint(worldX, worldY, -1))
-	origin := c.Transform.Inverse().MultiplyByTuple(*NewPoint(0, 0, 0))
-	direction := pixel.Subtract(origin).Normalize()
+	pixel := inverse.MultiplyByTuple(*NewPoint(worldX, worldY, -1))
+	origin = inverse.MultiplyByTuple(*NewPoint(0, 0, 0))
+	direction = pixel.Subtract(origin).Normalize()
 
-	return Ray{origin, direction}
+	return origin, direction
 }
 
 const RendererCount = 8
@@ -2,6 +2,7 @@ package jtracer
 
 import (
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"math"
 	"testing"
 )
@@ -173,13 +174,32 @@ func TestCamera_RayForPixel(t *testing.T) {
 				HalfHeight: tt.fields.HalfHeight,
 				PixelSize:  tt.fields.PixelSize,
 			}
-			if got := c.RayForPixel(tt.args.px, tt.args.py); !cmp.Equal(got, tt.want, float64Comparer) {
+			got := c.RayForPixel(tt.args.px, tt.args.py)
+			if !cmp.Equal(got, tt.want, float64Comparer, cmpopts.IgnoreFields(Ray{}, "Differentials")) {
 				t.Errorf("RayForPixel() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
+func TestCamera_RayForPixel_Differentials(t *testing.T) {
+	c := NewCamera(201, 101, math.Pi/2)
+	c.Transform = RotationY(math.Pi / 4).Multiply(NewTranslation(0, -2, 5))
+
+	r := c.RayForPixel(100, 50)
+	right, below := c.RayForPixel(101, 50), c.RayForPixel(100, 51)
+
+	if r.Differentials == nil {
+		t.Fatalf("RayForPixel() has no differentials")
+	}
+	if d := r.Differentials; !d.XOrigin.Equals(r.Origin) || !d.XDirection.Equals(right.Direction) {
+		t.Errorf("RayForPixel() x differential = %v %v, want %v %v", d.XOrigin, d.XDirection, r.Origin, right.Direction)
+	}
+	if d := r.Differentials; !d.YOrigin.Equals(r.Origin) || !d.YDirection.Equals(below.Direction) {
+		t.Errorf("RayForPixel() y differential = %v %v, want %v %v", d.YOrigin, d.YDirection, r.Origin, below.Direction)
+	}
+}
+
 func TestCamera_Render(t *testing.T) {
 	type fields struct {
 		Hsize      float64
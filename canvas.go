@@ -3,7 +3,11 @@ package jtracer
 import (
 	"image"
 	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
 	"image/png"
+	"math"
 	"os"
 )
 
@@ -55,3 +59,30 @@ func (c *Canvas) SavePNG(path string) error {
 	}
 	return nil
 }
+
+// ToPaletted returns the canvas as an image of the web-safe palette, dithered to hide the banding, as a frame of an
+// animated GIF
+func (c *Canvas) ToPaletted() *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, c.Width, c.Height), palette.WebSafe)
+	draw.FloydSteinberg.Draw(img, img.Bounds(), c.ToImage(), image.Point{})
+	return img
+}
+
+// SaveGIF writes frames as an animated GIF that plays at fps frames a second and loops forever
+func SaveGIF(path string, frames []*image.Paletted, fps float64) error {
+	delay := int(math.Max(1, math.Round(100/fps))) // in hundredths of a second
+	anim := gif.GIF{Image: frames, Delay: make([]int, len(frames))}
+	for i := range anim.Delay {
+		anim.Delay[i] = delay
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(f, &anim); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -1,6 +1,10 @@
 package jtracer
 
 import (
+	"image"
+	"image/gif"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -91,3 +95,38 @@ func TestCanvas_WritePixel(t *testing.T) {
 		})
 	}
 }
+
+func TestSaveGIF(t *testing.T) {
+	var frames []*image.Paletted
+	for _, c := range []Color{Red, Black, White} {
+		canvas := NewCanvas(4, 2)
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 4; x++ {
+				canvas.WritePixel(x, y, &c)
+			}
+		}
+		frames = append(frames, canvas.ToPaletted())
+	}
+
+	path := filepath.Join(t.TempDir(), "frames.gif")
+	if err := SaveGIF(path, frames, 25); err != nil {
+		t.Fatalf("SaveGIF() error = %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	anim, err := gif.DecodeAll(f)
+	if err != nil {
+		t.Fatalf("gif.DecodeAll() error = %v", err)
+	}
+
+	if len(anim.Image) != 3 || anim.Delay[0] != 4 {
+		t.Fatalf("saved %d frames %v hundredths apart, want 3 frames 4 apart", len(anim.Image), anim.Delay)
+	}
+	if r, g, b, _ := anim.Image[0].At(1, 1).RGBA(); r != 0xffff || g != 0 || b != 0 {
+		t.Errorf("first frame = %v, %v, %v, want red", r, g, b)
+	}
+}
@@ -52,6 +52,11 @@ func (c *Color) Equals(a *Color) bool {
 		floatEquals(c.Blue, a.Blue)
 }
 
+// Luminance returns the perceived brightness of a color, using the Rec. 709 weights
+func (c *Color) Luminance() float64 {
+	return 0.2126*c.Red + 0.7152*c.Green + 0.0722*c.Blue
+}
+
 func clamp(scaledValue int) int {
 	if scaledValue < 0 {
 		return 0
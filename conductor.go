@@ -0,0 +1,59 @@
+package jtracer
+
+// ComplexIOR is the complex index of refraction n + ik of a conductor, given separately for each color channel.
+// N is the real part and K the extinction coefficient.
+type ComplexIOR struct {
+	N Color
+	K Color
+}
+
+// ConductorPresets holds measured complex indices of refraction of common metals, sampled at roughly 650nm, 550nm
+// and 450nm for the red, green and blue channels
+var ConductorPresets = map[string]ComplexIOR{
+	"gold": {
+		N: Color{0.143, 0.374, 1.442},
+		K: Color{3.983, 2.385, 1.603},
+	},
+	"copper": {
+		N: Color{0.200, 0.924, 1.102},
+		K: Color{3.912, 2.452, 2.142},
+	},
+	"silver": {
+		N: Color{0.155, 0.117, 0.138},
+		K: Color{4.828, 3.122, 2.147},
+	},
+	"aluminium": {
+		N: Color{1.657, 0.880, 0.521},
+		K: Color{9.224, 6.270, 4.837},
+	},
+	"aluminum": {
+		N: Color{1.657, 0.880, 0.521},
+		K: Color{9.224, 6.270, 4.837},
+	},
+}
+
+// IsConductor reports whether the material reflects light like a metal
+func (m Material) IsConductor() bool {
+	return m.Conductor != ComplexIOR{}
+}
+
+// ConductorFresnel returns the share of unpolarized light reflected by a conductor for each color channel, where
+// cosTheta is the cosine of the angle between the incoming light and the surface normal
+func ConductorFresnel(ior ComplexIOR, cosTheta float64) Color {
+	return Color{
+		conductorReflectance(ior.N.Red, ior.K.Red, cosTheta),
+		conductorReflectance(ior.N.Green, ior.K.Green, cosTheta),
+		conductorReflectance(ior.N.Blue, ior.K.Blue, cosTheta),
+	}
+}
+
+// conductorReflectance averages the s and p polarized reflectance of a conductor for a single wavelength
+func conductorReflectance(n, k, cos float64) float64 {
+	cos2 := cos * cos
+	nk2 := n*n + k*k
+
+	rs := (nk2 - 2*n*cos + cos2) / (nk2 + 2*n*cos + cos2)
+	rp := (nk2*cos2 - 2*n*cos + 1) / (nk2*cos2 + 2*n*cos + 1)
+
+	return (rs + rp) / 2
+}
@@ -0,0 +1,82 @@
+package jtracer
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"math"
+	"testing"
+)
+
+func TestConductorFresnel(t *testing.T) {
+	gold := ConductorPresets["gold"]
+	normalIncidence := func(n, k float64) float64 {
+		return ((n-1)*(n-1) + k*k) / ((n+1)*(n+1) + k*k)
+	}
+
+	tests := []struct {
+		name     string
+		ior      ComplexIOR
+		cosTheta float64
+		want     Color
+	}{
+		{
+			name:     "reflectance of gold at normal incidence",
+			ior:      gold,
+			cosTheta: 1,
+			want: Color{
+				normalIncidence(gold.N.Red, gold.K.Red),
+				normalIncidence(gold.N.Green, gold.K.Green),
+				normalIncidence(gold.N.Blue, gold.K.Blue),
+			},
+		},
+		{
+			name:     "every metal is a perfect mirror at grazing incidence",
+			ior:      gold,
+			cosTheta: 0,
+			want:     White,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConductorFresnel(tt.ior, tt.cosTheta); !cmp.Equal(got, tt.want, float64Comparer) {
+				t.Errorf("ConductorFresnel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if f := ConductorFresnel(gold, 1); f.Red <= f.Blue {
+		t.Errorf("ConductorFresnel() of gold = %v, want it to reflect more red than blue", f)
+	}
+}
+
+func TestConductorPresets(t *testing.T) {
+	for _, name := range []string{"gold", "copper", "silver", "aluminium", "aluminum"} {
+		if m := (Material{Conductor: ConductorPresets[name]}); !m.IsConductor() {
+			t.Errorf("ConductorPresets[%q] is missing", name)
+		}
+	}
+}
+
+func TestWorld_ReflectedColorOfConductor(t *testing.T) {
+	sky := NewSphere()
+	sky.SetTransform(Scaling(100, 100, 100))
+	sky.Material = Material{Color: White, Ambient: 1}
+
+	floor := NewPlane()
+	floor.Material = NewMaterial()
+	floor.Material.Reflectivity = 1
+	floor.Material.Conductor = ConductorPresets["copper"]
+
+	w := World{
+		Objects: []Shape{sky, floor},
+		Light:   NewPointLight(*NewPoint(-10, 10, -10), White),
+	}
+
+	r := NewRay(NewPoint(0, 1, -1), NewVector(0, -math.Sqrt(2)/2, math.Sqrt(2)/2))
+	xs := w.Intersect(r)
+	comps := xs.Hit().PrepareComputations(r, xs)
+
+	want := ConductorFresnel(ConductorPresets["copper"], math.Sqrt(2)/2)
+	if got := w.ReflectedColor(comps, MaxReflections); !got.Equals(&want) {
+		t.Errorf("ReflectedColor() = %v, want %v", got, want)
+	}
+}
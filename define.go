@@ -0,0 +1,307 @@
+package jtracer
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A define entry gives a value a name, so that materials, patterns and transforms can be written once and referred
+// to wherever one is expected:
+//
+//	- define: white-material
+//	  value:
+//	    color: [1, 1, 1]
+//	- define: blue-material
+//	  extend: white-material
+//	  value:
+//	    color: [0.5, 0.8, 0.9]
+//	- define: standard-transform
+//	  value:
+//	    - [translate, 1, -1, 1]
+//	    - [scale, 0.5, 0.5, 0.5]
+//	- add: sphere
+//	  material: blue-material
+//	  transform:
+//	    - standard-transform
+//	    - [scale, 3.5, 3.5, 3.5]
+//
+// A define that extends another takes the other's keys, overriding those it gives itself, or for a list is appended to
+// the other's items. A name within a list of transforms is replaced by the transforms it names.
+
+// define is a value given a name by a define entry
+type define struct {
+	name      *yaml.Node // where the define is named, for reporting problems with it
+	value     *yaml.Node
+	extend    *yaml.Node // the name of the define this one extends, if any
+	entry     sceneEntry
+	order     int // the define's position among all the scene's entries, counting those included
+	used      bool
+	resolved  *yaml.Node // value with its extension merged in and references expanded, once resolved
+	resolving bool
+	numeric   bool    // whether the define has been found to be a number
+	number    float64 // its value, when it is
+}
+
+// defines holds the defines of a scene by name
+type defines map[string]*define
+
+// collectDefines looks through every entry for defines, so that entries can refer to defines that come after them.
+// It returns an error for each entry that isn't a map or is a malformed define.
+func collectDefines(entries []sceneEntry) (defines, []error) {
+	defs := make(defines)
+
+	var errs []error
+	for i, entry := range entries {
+		if err := expectMapping(entry.node, ""); err != nil {
+			errs = append(errs, entry.locate(err))
+			continue
+		}
+
+		if name := lookup(entry.node, "define"); name != nil {
+			if err := decodeDefine(entry, name, i, defs); err != nil {
+				errs = append(errs, entry.locate(err))
+			}
+		}
+	}
+
+	return defs, errs
+}
+
+// decodeDefine records the value of a define entry under its name
+func decodeDefine(entry sceneEntry, name *yaml.Node, order int, defs defines) error {
+	n, err := decodeString(name, "define")
+	if err != nil {
+		return err
+	}
+
+	value, err := require(entry.node, "value")
+	if err != nil {
+		return err
+	}
+
+	extend := lookup(entry.node, "extend")
+	if extend != nil {
+		if _, err := decodeString(extend, "extend"); err != nil {
+			return err
+		}
+	}
+
+	defs[n] = &define{name: name, value: value, extend: extend, entry: entry, order: order}
+	return nil
+}
+
+// resolve returns the value of the define named by ref, merged with any define it extends and expanded by expand. The
+// result is kept, so a define is only resolved once however often it is used, and each use is given a copy of it so
+// that expanding one use can't change the others.
+func (d defines) resolve(ref *yaml.Node, key string, expand func(*yaml.Node) (*yaml.Node, error)) (*yaml.Node, error) {
+	def, ok := d[ref.Value]
+	if !ok {
+		return nil, nodeError(ref, key, fmt.Errorf("%w %q", ErrUnknownDefine, ref.Value))
+	}
+
+	def.used = true
+	if def.resolved != nil {
+		return copyNode(def.resolved), nil
+	}
+	if def.resolving {
+		return nil, invalid(ref, key, "define %q refers to itself", ref.Value)
+	}
+	def.resolving = true
+	defer func() { def.resolving = false }()
+
+	value := def.value
+	if def.extend != nil {
+		parent, err := d.resolve(def.extend, "extend", expand)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = extendNode(parent, value); err != nil {
+			return nil, err
+		}
+	}
+
+	value, err := expand(value)
+	if err != nil {
+		return nil, err
+	}
+
+	def.resolved = value
+	return copyNode(value), nil
+}
+
+// extendNode returns a new node combining child with the parent it extends: a map takes the parent's keys, overridden
+// by the child's, and a list has the child's items appended to the parent's
+func extendNode(parent, child *yaml.Node) (*yaml.Node, error) {
+	child = resolveAlias(child)
+	if parent.Kind != child.Kind || (child.Kind != yaml.MappingNode && child.Kind != yaml.SequenceNode) {
+		return nil, invalid(child, "extend", "can only extend a map with a map or a list with a list")
+	}
+
+	extended := *child
+	if child.Kind == yaml.SequenceNode {
+		extended.Content = append(append([]*yaml.Node{}, parent.Content...), child.Content...)
+		return &extended, nil
+	}
+
+	extended.Content = nil
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if lookup(child, parent.Content[i].Value) == nil {
+			extended.Content = append(extended.Content, parent.Content[i], parent.Content[i+1])
+		}
+	}
+	extended.Content = append(extended.Content, child.Content...)
+	return &extended, nil
+}
+
+// expandValue replaces the value of key in the mapping node n, if present, with its expansion
+func expandValue(n *yaml.Node, key string, expand func(*yaml.Node) (*yaml.Node, error)) error {
+	n = resolveAlias(n)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			v, err := expand(resolveAlias(n.Content[i+1]))
+			if err != nil {
+				return keyed(err, key)
+			}
+			n.Content[i+1] = v
+		}
+	}
+	return nil
+}
+
+// expandMaterial replaces a material named by a define with its value, and expands the names within it
+func (d defines) expandMaterial(n *yaml.Node) (*yaml.Node, error) {
+	if n.Kind == yaml.ScalarNode {
+		return d.resolve(n, "material", d.expandMaterial)
+	}
+
+	return n, expandValue(n, "pattern", d.expandPattern)
+}
+
+// expandPattern replaces a pattern named by a define with its value, and expands the names of the patterns nested
+// within it and of its transforms
+func (d defines) expandPattern(n *yaml.Node) (*yaml.Node, error) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return d.resolve(n, "pattern", d.expandPattern)
+	case yaml.MappingNode:
+	default:
+		return n, nil
+	}
+
+	for _, key := range []string{"colors", "patterns"} {
+		if items := lookup(n, key); items != nil && items.Kind == yaml.SequenceNode {
+			for i, item := range items.Content {
+				v, err := d.expandPattern(resolveAlias(item))
+				if err != nil {
+					return nil, keyed(err, key)
+				}
+				items.Content[i] = v
+			}
+		}
+	}
+
+	return n, firstError(
+		expandValue(n, "pattern", d.expandPattern),
+		expandValue(n, "mask", d.expandPattern),
+		expandValue(n, "transform", d.expandTransforms),
+	)
+}
+
+// expandTransforms replaces a list of transforms named by a define with its value, and splices the transforms named
+// by any define within the list in place of the name
+func (d defines) expandTransforms(n *yaml.Node) (*yaml.Node, error) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return d.resolve(n, "transform", d.expandTransforms)
+	case yaml.SequenceNode:
+	default:
+		return n, nil
+	}
+
+	expanded := *n
+	expanded.Content = nil
+	for _, item := range n.Content {
+		item = resolveAlias(item)
+		if item.Kind != yaml.ScalarNode {
+			expanded.Content = append(expanded.Content, item)
+			continue
+		}
+
+		transforms, err := d.resolve(item, "transform", d.expandTransforms)
+		if err != nil {
+			return nil, err
+		}
+		if err := expectSequence(transforms, "transform"); err != nil {
+			return nil, err
+		}
+		expanded.Content = append(expanded.Content, transforms.Content...)
+	}
+	return &expanded, nil
+}
+
+// number returns the value of a define that is a number, working out the expression it may be written as. ok is
+// false for a define that isn't a number, such as a map or the name of another define that isn't a number.
+func (d defines) number(name token, n *yaml.Node, key string) (v float64, ok bool, err error) {
+	def, found := d[name.text]
+	if !found {
+		return 0, false, nil
+	}
+
+	value := resolveAlias(def.value)
+	if value.Kind != yaml.ScalarNode || def.extend != nil {
+		return 0, false, nil
+	}
+	if def.numeric {
+		def.used = true
+		return def.number, true, nil
+	}
+	if def.resolving {
+		return 0, true, invalid(n, key, "define %q refers to itself", name.text)
+	}
+	def.resolving = true
+	defer func() { def.resolving = false }()
+
+	if _, isDefine := d[value.Value]; isDefine && value.ShortTag() == "!!str" {
+		if v, ok, err = d.number(token{kind: tokenName, text: value.Value}, value, "value"); !ok || err != nil {
+			return 0, ok, err
+		}
+	} else if v, err = (scope{defs: d}).decodeFloat(value, "value"); err != nil {
+		return 0, true, err
+	}
+
+	def.used, def.numeric, def.number = true, true, v
+	return v, true, nil
+}
+
+// scope holds what the names in an entry's expressions refer to: the scene's numeric defines, and the counters of the
+// repeats that made the entry, which hide any defines of the same name
+type scope struct {
+	defs     defines
+	counters map[string]float64
+}
+
+// vars returns the values of the names in the expression n. Names that aren't numeric defines or counters are left
+// out, for EvalExpression to report.
+func (sc scope) vars(n *yaml.Node, key string) (map[string]float64, error) {
+	vars := make(map[string]float64)
+	for _, name := range expressionNames(n.Value) {
+		if v, ok := sc.counters[name.text]; ok {
+			vars[name.text] = v
+			continue
+		}
+
+		v, ok, err := sc.defs.number(name, n, key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			vars[name.text] = v
+		}
+	}
+	return vars, nil
+}
@@ -0,0 +1,167 @@
+package jtracer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseScene_Defines(t *testing.T) {
+	scene, err := ParseScene([]byte(`
+- define: white-material
+  value:
+    color: [1, 1, 1]
+    diffuse: 0.7
+    ambient: 0.1
+- define: blue-material
+  extend: white-material
+  value:
+    color: [0, 0, 1]
+- define: shiny-blue-material
+  extend: blue-material
+  value:
+    reflective: 0.5
+- define: standard-transform
+  value:
+    - [translate, 1, -1, 1]
+    - [scale, 0.5, 0.5, 0.5]
+- define: large-object
+  value:
+    - standard-transform
+    - [scale, 3.5, 3.5, 3.5]
+- define: stripes
+  value:
+    type: stripes
+    colors: [[1, 0, 0], [0, 0, 1]]
+    transform: standard-transform
+- add: sphere
+  material: shiny-blue-material
+  transform:
+    - large-object
+    - [translate, 8.5, 1.5, -0.5]
+- add: plane
+  material:
+    pattern: stripes
+  transform: standard-transform
+`), ".")
+	if err != nil {
+		t.Fatalf("ParseScene() error = %v", err)
+	}
+
+	standard := Scaling(0.5, 0.5, 0.5).Multiply(NewTranslation(1, -1, 1))
+	large := NewTranslation(8.5, 1.5, -0.5).Multiply(Scaling(3.5, 3.5, 3.5)).Multiply(standard)
+
+	sphere := scene.Objects[0]
+	want := NewMaterial()
+	want.Color, want.Diffuse, want.Ambient, want.Reflectivity = Color{0, 0, 1}, 0.7, 0.1, 0.5
+	if got := sphere.GetMaterial(); !cmp.Equal(got, want) {
+		t.Errorf("sphere material = %v, want %v", got, want)
+	}
+	if got := sphere.GetTransform(); !cmp.Equal(got, large, float64Comparer) {
+		t.Errorf("sphere transform = %v, want %v", got, large)
+	}
+
+	plane := scene.Objects[1]
+	if got := plane.GetTransform(); !cmp.Equal(got, standard, float64Comparer) {
+		t.Errorf("plane transform = %v, want %v", got, standard)
+	}
+	p, ok := plane.GetMaterial().Pattern.(*StripePattern)
+	if !ok {
+		t.Fatalf("plane pattern = %T, want *StripePattern", plane.GetMaterial().Pattern)
+	}
+	if got := p.GetTransform(); !cmp.Equal(got, standard, float64Comparer) {
+		t.Errorf("pattern transform = %v, want %v", got, standard)
+	}
+}
+
+func TestParseScene_DefineErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		line int
+		key  string
+		want error
+	}{
+		{
+			name: "a transform list naming a missing define",
+			yaml: "- add: sphere\n  transform:\n    - [scale, 1, 1, 1]\n    - huge\n",
+			line: 4, key: "transform",
+			want: ErrUnknownDefine,
+		},
+		{
+			name: "a pattern naming a missing define",
+			yaml: "- add: sphere\n  material:\n    pattern: plaid\n",
+			line: 3, key: "pattern",
+			want: ErrUnknownDefine,
+		},
+		{
+			name: "extending a missing define",
+			yaml: "- define: a\n  extend: b\n  value: {}\n- add: sphere\n  material: a\n",
+			line: 2, key: "extend",
+			want: ErrUnknownDefine,
+		},
+		{
+			name: "defines that extend each other",
+			yaml: "- define: a\n  extend: b\n  value: {}\n- define: b\n  extend: a\n  value: {}\n- add: sphere\n  material: a\n",
+			line: 5, key: "extend",
+			want: ErrInvalidValue,
+		},
+		{
+			name: "a map extending a list",
+			yaml: "- define: a\n  value: [[scale, 1, 1, 1]]\n- define: b\n  extend: a\n  value: {}\n- add: sphere\n  material: b\n",
+			line: 5, key: "extend",
+			want: ErrInvalidValue,
+		},
+		{
+			name: "a define name that isn't a list of transforms",
+			yaml: "- define: a\n  value: {color: [1, 0, 0]}\n- add: sphere\n  transform: [a]\n",
+			line: 2, key: "transform",
+			want: ErrInvalidValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseScene([]byte(tt.yaml), ".")
+
+			var se *SceneError
+			if !errors.As(err, &se) || !errors.Is(err, tt.want) {
+				t.Fatalf("ParseScene() error = %v, want %v", err, tt.want)
+			}
+			if se.Line != tt.line || se.Key != tt.key {
+				t.Errorf("ParseScene() error at line %d, key %q, want line %d, key %q", se.Line, se.Key, tt.line, tt.key)
+			}
+		})
+	}
+}
+
+func TestDefines_Resolve_Copies(t *testing.T) {
+	entries, err := sceneEntries([]byte("- define: stripes\n  value:\n    type: stripes\n    colors: [[1, 0, 0], [0, 0, 1]]\n"), "", ".", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, errs := collectDefines(entries)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	ref := &yaml.Node{Kind: yaml.ScalarNode, Value: "stripes"}
+	first, err := defs.resolve(ref, "pattern", defs.expandPattern)
+	if err != nil {
+		t.Fatalf("resolve() error = %v", err)
+	}
+	lookup(first, "type").Value = "rings"
+	lookup(first, "colors").Content = nil
+
+	second, err := defs.resolve(ref, "pattern", defs.expandPattern)
+	if err != nil {
+		t.Fatalf("resolve() error = %v", err)
+	}
+	if got := lookup(second, "type").Value; got != "stripes" {
+		t.Errorf("resolve() type = %q after changing an earlier use, want %q", got, "stripes")
+	}
+	if got := len(lookup(second, "colors").Content); got != 2 {
+		t.Errorf("resolve() has %d colors after changing an earlier use, want 2", got)
+	}
+}
@@ -0,0 +1,77 @@
+package jtracer
+
+import "math"
+
+// RayDifferentials are the rays through the neighbouring pixels, one to the right and one below, followed alongside
+// a camera ray so that the area of a surface covered by its pixel can be estimated wherever it lands
+type RayDifferentials struct {
+	XOrigin, XDirection Tuple
+	YOrigin, YDirection Tuple
+}
+
+// Transform returns the differentials moved by m
+func (d *RayDifferentials) Transform(m Matrix) *RayDifferentials {
+	if d == nil {
+		return nil
+	}
+
+	return &RayDifferentials{
+		XOrigin:    *m.MultiplyByTuple(d.XOrigin),
+		XDirection: *m.MultiplyByTuple(d.XDirection),
+		YOrigin:    *m.MultiplyByTuple(d.YOrigin),
+		YDirection: *m.MultiplyByTuple(d.YDirection),
+	}
+}
+
+// HasFootprint reports whether the computations know the area of the surface covered by the pixel
+func (c Computations) HasFootprint() bool {
+	return c.Differentials != nil
+}
+
+// ReflectedDifferentials follows the neighbouring rays as they reflect, treating the surface as flat across the
+// footprint of the pixel
+func (c Computations) ReflectedDifferentials() *RayDifferentials {
+	if !c.HasFootprint() {
+		return nil
+	}
+
+	return &RayDifferentials{
+		XOrigin:    *c.OverPoint.Add(&c.Dpdx),
+		XDirection: c.Differentials.XDirection.Reflect(c.Normalv),
+		YOrigin:    *c.OverPoint.Add(&c.Dpdy),
+		YDirection: c.Differentials.YDirection.Reflect(c.Normalv),
+	}
+}
+
+// RefractedDifferentials follows the neighbouring rays as they refract, treating the surface as flat across the
+// footprint of the pixel. A neighbouring ray that is totally internally reflected follows the refracted ray instead.
+func (c Computations) RefractedDifferentials(refracted Tuple) *RayDifferentials {
+	if !c.HasFootprint() {
+		return nil
+	}
+
+	bend := func(direction Tuple) Tuple {
+		if d, ok := refractDirection(*direction.Negate(), c.Normalv, c.N1/c.N2); ok {
+			return d
+		}
+		return refracted
+	}
+
+	return &RayDifferentials{
+		XOrigin:    *c.UnderPoint.Add(&c.Dpdx),
+		XDirection: bend(c.Differentials.XDirection),
+		YOrigin:    *c.UnderPoint.Add(&c.Dpdy),
+		YDirection: bend(c.Differentials.YDirection),
+	}
+}
+
+// tangentPlaneOffset returns the offset from p to where a neighbouring ray crosses the plane through p with normal n
+func tangentPlaneOffset(p, n, origin, direction Tuple) Tuple {
+	denominator := n.Dot(&direction)
+	if math.Abs(denominator) < epsilon {
+		return *NewVector(0, 0, 0)
+	}
+
+	t := (n.Dot(&p) - n.Dot(&origin)) / denominator
+	return *origin.Add(direction.Multiply(t)).Subtract(&p)
+}
@@ -0,0 +1,76 @@
+package jtracer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPrepareComputations_Differentials(t *testing.T) {
+	floor := NewPlane()
+	r := NewRay(NewPoint(0, 1, 0), NewVector(0, -1, 0))
+	r.Differentials = &RayDifferentials{
+		XOrigin: *NewPoint(0, 1, 0), XDirection: *NewVector(0.1, -1, 0).Normalize(),
+		YOrigin: *NewPoint(0, 1, 0), YDirection: *NewVector(0, -1, -0.2).Normalize(),
+	}
+	i := Intersection{T: 1, Object: floor}
+	comps := i.PrepareComputations(r, Intersections{i})
+
+	if want := NewVector(0.1, 0, 0); !comps.Dpdx.Equals(want) {
+		t.Errorf("PrepareComputations() Dpdx = %v, want %v", comps.Dpdx, want)
+	}
+	if want := NewVector(0, 0, -0.2); !comps.Dpdy.Equals(want) {
+		t.Errorf("PrepareComputations() Dpdy = %v, want %v", comps.Dpdy, want)
+	}
+
+	t.Run("reflected differentials leave from the neighbouring points", func(t *testing.T) {
+		d := comps.ReflectedDifferentials()
+		if want := comps.OverPoint.Add(NewVector(0.1, 0, 0)); !d.XOrigin.Equals(want) {
+			t.Errorf("ReflectedDifferentials() XOrigin = %v, want %v", d.XOrigin, want)
+		}
+		if want := NewVector(0.1, 1, 0).Normalize(); !d.XDirection.Equals(want) {
+			t.Errorf("ReflectedDifferentials() XDirection = %v, want %v", d.XDirection, want)
+		}
+	})
+
+	t.Run("refracted differentials bend by the same ratio", func(t *testing.T) {
+		c := comps
+		c.N1, c.N2 = 1, 1
+		d := c.RefractedDifferentials(*NewVector(0, -1, 0))
+		if want := NewVector(0, -1, -0.2).Normalize(); !d.YDirection.Equals(want) {
+			t.Errorf("RefractedDifferentials() YDirection = %v, want %v", d.YDirection, want)
+		}
+		if want := comps.UnderPoint.Add(NewVector(0, 0, -0.2)); !d.YOrigin.Equals(want) {
+			t.Errorf("RefractedDifferentials() YOrigin = %v, want %v", d.YOrigin, want)
+		}
+	})
+
+	t.Run("rays without differentials have no footprint", func(t *testing.T) {
+		plain := i.PrepareComputations(NewRay(NewPoint(0, 1, 0), NewVector(0, -1, 0)), Intersections{i})
+		if plain.HasFootprint() || plain.ReflectedDifferentials() != nil {
+			t.Errorf("PrepareComputations() has a footprint without ray differentials")
+		}
+	})
+}
+
+func TestRayDifferentials_Transform(t *testing.T) {
+	d := &RayDifferentials{
+		XOrigin: *NewPoint(1, 2, 3), XDirection: *NewVector(0, 1, 0),
+		YOrigin: *NewPoint(1, 2, 3), YDirection: *NewVector(1, 0, 0),
+	}
+	got := d.Transform(NewTranslation(3, 4, 5).Multiply(RotationZ(math.Pi / 2)))
+
+	if want := NewPoint(1, 5, 8); !got.XOrigin.Equals(want) {
+		t.Errorf("Transform() XOrigin = %v, want %v", got.XOrigin, want)
+	}
+	if want := NewVector(-1, 0, 0); !got.XDirection.Equals(want) {
+		t.Errorf("Transform() XDirection = %v, want %v", got.XDirection, want)
+	}
+	if want := NewVector(0, 1, 0); !got.YDirection.Equals(want) {
+		t.Errorf("Transform() YDirection = %v, want %v", got.YDirection, want)
+	}
+
+	var none *RayDifferentials
+	if none.Transform(IdentityMatrix) != nil {
+		t.Errorf("Transform() of no differentials is not nil")
+	}
+}
@@ -0,0 +1,126 @@
+package jtracer
+
+import "math"
+
+// DispersionModel selects the formula used to derive a wavelength dependent index of refraction
+type DispersionModel int
+
+const (
+	NoDispersion DispersionModel = iota
+	CauchyDispersion
+	SellmeierDispersion
+)
+
+// Dispersion describes how a material's index of refraction varies with wavelength.
+//
+// For the Cauchy model B holds the A, B and C terms of n(λ) = A + B/λ² + C/λ⁴.
+// For the Sellmeier model B holds B1..B3 and C holds C1..C3 of n²(λ) = 1 + Σ Bi·λ²/(λ² - Ci).
+// In both cases λ is measured in micrometres.
+type Dispersion struct {
+	Model DispersionModel
+	B     [3]float64
+	C     [3]float64
+}
+
+// IndexAt returns the index of refraction at a wavelength given in nanometres
+func (d Dispersion) IndexAt(wavelength float64) float64 {
+	l := wavelength / 1000
+	l2 := l * l
+
+	switch d.Model {
+	case CauchyDispersion:
+		return d.B[0] + d.B[1]/l2 + d.B[2]/(l2*l2)
+	case SellmeierDispersion:
+		n2 := 1.0
+		for i := range d.B {
+			n2 += d.B[i] * l2 / (l2 - d.C[i])
+		}
+		return math.Sqrt(n2)
+	}
+
+	return 0
+}
+
+// RefractiveIndexAt returns the index of refraction of the material for light of the given wavelength in nanometres.
+// A wavelength of zero stands for white light and always yields RefractiveIndex.
+func (m Material) RefractiveIndexAt(wavelength float64) float64 {
+	if wavelength == 0 || m.Dispersion.Model == NoDispersion {
+		return m.RefractiveIndex
+	}
+	return m.Dispersion.IndexAt(wavelength)
+}
+
+// SpectralSample is a single wavelength traced through a dispersive material. Weight is the share of the
+// sample's color contributed to the final pixel; the weights of a sample set add up to White.
+type SpectralSample struct {
+	Wavelength float64
+	Weight     Color
+}
+
+// RGBSamples splits white light into one red, one green and one blue wavelength
+var RGBSamples = []SpectralSample{
+	{Wavelength: 610, Weight: Color{1, 0, 0}},
+	{Wavelength: 550, Weight: Color{0, 1, 0}},
+	{Wavelength: 465, Weight: Color{0, 0, 1}},
+}
+
+// SpectralSamples is the set of wavelengths RefractedColor traces when light enters a dispersive material
+var SpectralSamples = RGBSamples
+
+// NewSpectralSamples returns n wavelengths evenly spaced across the visible spectrum, weighted so that they
+// recombine to white.
+func NewSpectralSamples(n int) []SpectralSample {
+	if n < 3 {
+		return RGBSamples
+	}
+
+	samples := make([]SpectralSample, n)
+	var total Color
+	for i := range samples {
+		wavelength := 400 + (300 * (float64(i) + 0.5) / float64(n))
+		samples[i] = SpectralSample{Wavelength: wavelength, Weight: WavelengthToColor(wavelength)}
+		total = *total.Add(&samples[i].Weight)
+	}
+
+	for i := range samples {
+		samples[i].Weight = Color{
+			samples[i].Weight.Red / total.Red,
+			samples[i].Weight.Green / total.Green,
+			samples[i].Weight.Blue / total.Blue,
+		}
+	}
+
+	return samples
+}
+
+// WavelengthToColor approximates the color of monochromatic light with a wavelength between 380 and 780 nanometres
+func WavelengthToColor(wavelength float64) Color {
+	var c Color
+	switch {
+	case wavelength < 380 || wavelength > 780:
+		return Black
+	case wavelength < 440:
+		c = Color{(440 - wavelength) / (440 - 380), 0, 1}
+	case wavelength < 490:
+		c = Color{0, (wavelength - 440) / (490 - 440), 1}
+	case wavelength < 510:
+		c = Color{0, 1, (510 - wavelength) / (510 - 490)}
+	case wavelength < 580:
+		c = Color{(wavelength - 510) / (580 - 510), 1, 0}
+	case wavelength < 645:
+		c = Color{1, (645 - wavelength) / (645 - 580), 0}
+	default:
+		c = Color{1, 0, 0}
+	}
+
+	// intensity falls off near the limits of vision
+	factor := 1.0
+	switch {
+	case wavelength < 420:
+		factor = 0.3 + 0.7*(wavelength-380)/(420-380)
+	case wavelength > 700:
+		factor = 0.3 + 0.7*(780-wavelength)/(780-700)
+	}
+
+	return *c.MultiplyByScalar(factor)
+}
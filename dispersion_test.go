@@ -0,0 +1,104 @@
+package jtracer
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"math"
+	"testing"
+)
+
+func TestDispersion_IndexAt(t *testing.T) {
+	tests := []struct {
+		name       string
+		dispersion Dispersion
+		wavelength float64
+		want       float64
+	}{
+		{
+			name:       "cauchy equation for a crown glass",
+			dispersion: Dispersion{Model: CauchyDispersion, B: [3]float64{1.5046, 0.00420}},
+			wavelength: 500,
+			want:       1.5214,
+		},
+		{
+			name: "sellmeier equation for BK7 at the sodium d-line",
+			dispersion: Dispersion{
+				Model: SellmeierDispersion,
+				B:     [3]float64{1.03961212, 0.231792344, 1.01046945},
+				C:     [3]float64{0.00600069867, 0.0200179144, 103.560653},
+			},
+			wavelength: 587.6,
+			want:       1.51680,
+		},
+		{
+			name:       "no dispersion",
+			dispersion: Dispersion{},
+			wavelength: 500,
+			want:       0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dispersion.IndexAt(tt.wavelength); !cmp.Equal(got, tt.want, float64Comparer) {
+				t.Errorf("IndexAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaterial_RefractiveIndexAt(t *testing.T) {
+	m := NewMaterial()
+	m.RefractiveIndex = 1.5
+	m.Dispersion = Dispersion{Model: CauchyDispersion, B: [3]float64{1.5046, 0.00420}}
+
+	if got := m.RefractiveIndexAt(0); got != 1.5 {
+		t.Errorf("RefractiveIndexAt(0) = %v, want 1.5", got)
+	}
+
+	if red, blue := m.RefractiveIndexAt(650), m.RefractiveIndexAt(450); blue <= red {
+		t.Errorf("RefractiveIndexAt() blue = %v, red = %v, want blue to bend more than red", blue, red)
+	}
+}
+
+func TestNewSpectralSamples(t *testing.T) {
+	for _, n := range []int{3, 8, 16} {
+		var total Color
+		for _, s := range NewSpectralSamples(n) {
+			total = *total.Add(&s.Weight)
+		}
+		if !total.Equals(&White) {
+			t.Errorf("NewSpectralSamples(%v) weights add up to %v, want %v", n, total, White)
+		}
+	}
+}
+
+func TestWorld_RefractedColorWithDispersion(t *testing.T) {
+	newWorld := func(d Dispersion) World {
+		w := DefaultWorld()
+		glass := NewGlassSphere()
+		glass.Material.Dispersion = d
+		wall := NewPlane()
+		wall.SetTransform(NewTranslation(0, 0, 5).Multiply(RotationX(math.Pi / 2)))
+		wall.Material.Pattern = NewTestPattern()
+		wall.Material.HasPattern = true
+		w.Objects = []Shape{glass, wall}
+		return w
+	}
+
+	r := NewRay(NewPoint(0, 0.5, -5), NewVector(0, -0.2, 1).Normalize())
+	colorOf := func(w World) Color {
+		xs := w.Intersect(r)
+		hit := xs.Hit()
+		return w.RefractedColor(hit.PrepareComputations(r, xs), 5)
+	}
+
+	plain := colorOf(newWorld(Dispersion{}))
+	constant := colorOf(newWorld(Dispersion{Model: CauchyDispersion, B: [3]float64{1.5}}))
+	if !constant.Equals(&plain) {
+		t.Errorf("RefractedColor() with constant dispersion = %v, want %v", constant, plain)
+	}
+
+	dispersed := colorOf(newWorld(Dispersion{Model: CauchyDispersion, B: [3]float64{1.5, 0.05}}))
+	if dispersed.Equals(&plain) {
+		t.Errorf("RefractedColor() with dispersion = %v, want it to differ from %v", dispersed, plain)
+	}
+}
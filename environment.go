@@ -0,0 +1,181 @@
+package jtracer
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// EnvironmentSamples is the number of directions an environment light is sampled in at each point seen by the camera
+const EnvironmentSamples = 16
+
+// EnvironmentLight lights the world from every direction with an equirectangular image, typically a high dynamic
+// range photograph read by ReadHDR. Directions are importance sampled by the brightness of the image, so small bright
+// features such as the softboxes of a studio are rarely missed. Build one with NewEnvironmentLight.
+type EnvironmentLight struct {
+	Map       *Canvas
+	File      string  // the image file the map was loaded from, if any
+	Intensity float64 // scales the radiance of the map
+	Rotation  float64 // turns the map about the y axis, in radians
+	Samples   int     // EnvironmentSamples when zero
+
+	marginal    []float64   // cumulative probability of sampling each row
+	conditional [][]float64 // cumulative probability of sampling each column within its row
+}
+
+func NewEnvironmentLight(m *Canvas) *EnvironmentLight {
+	e := &EnvironmentLight{Map: m, Intensity: 1}
+
+	// weight each pixel by its brightness and the solid angle it covers, which shrinks towards the poles
+	weights := make([][]float64, m.Height)
+	rowWeights := make([]float64, m.Height)
+	for y := range weights {
+		sinPhi := math.Sin(math.Pi * (float64(y) + 0.5) / float64(m.Height))
+		weights[y] = make([]float64, m.Width)
+		for x := range weights[y] {
+			weights[y][x] = m.Data[y][x].Luminance() * sinPhi
+			rowWeights[y] += weights[y][x]
+		}
+
+		// a black row is never chosen, but is still given a distribution of columns
+		if rowWeights[y] <= 0 {
+			for x := range weights[y] {
+				weights[y][x] = 1
+			}
+		}
+		e.conditional = append(e.conditional, cumulative(weights[y]))
+	}
+
+	var total float64
+	for _, w := range rowWeights {
+		total += w
+	}
+
+	// an entirely black map is sampled uniformly over the sphere
+	if total <= 0 {
+		for y := range rowWeights {
+			rowWeights[y] = math.Sin(math.Pi * (float64(y) + 0.5) / float64(m.Height))
+		}
+	}
+	e.marginal = cumulative(rowWeights)
+
+	return e
+}
+
+// ColorAt returns the radiance arriving from direction, which lets an environment light double as the background
+func (e *EnvironmentLight) ColorAt(direction Tuple) Color {
+	return e.Radiance(direction)
+}
+
+// Radiance returns the light arriving from direction
+func (e *EnvironmentLight) Radiance(direction Tuple) Color {
+	u, v := SphericalMap{}.Map(rotateY(direction, -e.Rotation))
+	c := e.Map.Sample(u, v)
+	return *c.MultiplyByScalar(e.Intensity)
+}
+
+// Sample turns two uniform random numbers in [0, 1) into a direction towards the environment, chosen in proportion
+// to the brightness of the map, and the probability density of choosing it per unit solid angle
+func (e *EnvironmentLight) Sample(u1, u2 float64) (direction Tuple, pdf float64) {
+	row, rowP, dv := sampleCumulative(e.marginal, u1)
+	col, colP, du := sampleCumulative(e.conditional[row], u2)
+
+	// invert the spherical mapping at the chosen point within the pixel
+	u := (float64(col) + du) / float64(e.Map.Width)
+	phi := math.Pi * (float64(row) + dv) / float64(e.Map.Height)
+	theta := 2 * math.Pi * (0.5 - u)
+
+	sinPhi := math.Sin(phi)
+	if sinPhi == 0 {
+		return *NewVector(0, math.Cos(phi), 0), 0
+	}
+
+	direction = rotateY(*NewVector(sinPhi*math.Sin(theta), math.Cos(phi), sinPhi*math.Cos(theta)), e.Rotation)
+	pdf = rowP * colP * float64(e.Map.Width*e.Map.Height) / (2 * math.Pi * math.Pi * sinPhi)
+	return direction, pdf
+}
+
+// EnvironmentLighting estimates the light the environment contributes to the surface in comps, excluding anything
+// it reaches only by reflection or refraction. The material's own shading model is evaluated for each sampled
+// direction, so diffuse, glossy and microfacet surfaces all respond to the environment.
+func (w World) EnvironmentLighting(comps Computations, remaining int) Color {
+	e := w.Environment
+	if e == nil {
+		return Black
+	}
+
+	m := comps.SurfaceMaterial()
+	m.Ambient = 0
+
+	samples := e.Samples
+	if samples <= 0 {
+		samples = EnvironmentSamples
+	}
+	samples = scaledSampleCount(samples, remaining)
+
+	var total Color
+	for i := 0; i < samples; i++ {
+		direction, pdf := e.Sample(rand.Float64(), rand.Float64())
+		if pdf == 0 || direction.Dot(&comps.Normalv) <= 0 {
+			continue
+		}
+		if w.isOccluded(comps.OverPoint, direction, math.Inf(1)) {
+			continue
+		}
+
+		// a point light is only ever lit with the cosine of its angle, so dividing by π as well as the pdf turns
+		// the shading model's answer into a Monte Carlo estimate of the light reflected towards the eye
+		radiance := e.Radiance(direction)
+		light := NewPointLight(*comps.OverPoint.Add(&direction), *radiance.MultiplyByScalar(1 / (math.Pi * pdf)))
+		c := m.Lighting(comps.Object, light, comps.OverPoint, comps.Eyev, comps.Normalv, false)
+		total = *total.Add(&c)
+	}
+
+	return *total.MultiplyByScalar(1 / float64(samples))
+}
+
+// cumulative returns the running totals of weights, normalized so that the last is 1
+func cumulative(weights []float64) []float64 {
+	cdf := make([]float64, len(weights))
+
+	var sum float64
+	for i, w := range weights {
+		sum += w
+		cdf[i] = sum
+	}
+	for i := range cdf {
+		cdf[i] /= sum
+	}
+
+	return cdf
+}
+
+// sampleCumulative picks the index whose interval of cdf contains u, returning its probability and how far through
+// the interval u lies
+func sampleCumulative(cdf []float64, u float64) (index int, p, offset float64) {
+	index = sort.Search(len(cdf), func(i int) bool { return cdf[i] > u })
+	if index == len(cdf) {
+		index--
+	}
+
+	var lo float64
+	if index > 0 {
+		lo = cdf[index-1]
+	}
+	p = cdf[index] - lo
+	if p <= 0 {
+		return index, 0, 0
+	}
+
+	return index, p, math.Min((u-lo)/p, 1)
+}
+
+// rotateY turns a direction about the y axis
+func rotateY(d Tuple, angle float64) Tuple {
+	if angle == 0 {
+		return d
+	}
+
+	sin, cos := math.Sin(angle), math.Cos(angle)
+	return *NewVector(d.X*cos+d.Z*sin, d.Y, -d.X*sin+d.Z*cos)
+}
@@ -0,0 +1,128 @@
+package jtracer
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvironmentLight_Radiance(t *testing.T) {
+	// a panorama with red in its left half and blue in its right half
+	m := NewCanvas(2, 1)
+	m.Data[0][0] = Red
+	m.Data[0][1] = Color{0, 0, 1}
+
+	tests := []struct {
+		name      string
+		intensity float64
+		rotation  float64
+		direction Tuple
+		want      Color
+	}{
+		{name: "looking left of the seam", intensity: 1, direction: *NewVector(1, 0, 0), want: Red},
+		{name: "looking right of the seam", intensity: 1, direction: *NewVector(-1, 0, 0), want: Color{0, 0, 1}},
+		{name: "intensity scales the map", intensity: 2, direction: *NewVector(1, 0, 0), want: Color{2, 0, 0}},
+		{name: "rotation turns the map about the y axis", intensity: 1, rotation: math.Pi, direction: *NewVector(1, 0, 0), want: Color{0, 0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEnvironmentLight(m)
+			e.Intensity = tt.intensity
+			e.Rotation = tt.rotation
+			if got := e.Radiance(tt.direction); !got.Equals(&tt.want) {
+				t.Errorf("Radiance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvironmentLight_Sample(t *testing.T) {
+	t.Run("samples are drawn towards the bright parts of the map", func(t *testing.T) {
+		m := NewCanvas(4, 2)
+		m.Data[0][1] = Color{10, 10, 10}
+		e := NewEnvironmentLight(m)
+
+		for _, u := range [][2]float64{{0.01, 0.01}, {0.3, 0.7}, {0.99, 0.5}, {0.5, 0.01}} {
+			direction, pdf := e.Sample(u[0], u[1])
+			if pdf <= 0 {
+				t.Errorf("Sample(%v) pdf = %v, want a positive density", u, pdf)
+			}
+			if mu, mv := (SphericalMap{}).Map(direction); mu < 0.25 || mu > 0.5 || mv < 0.5 {
+				t.Errorf("Sample(%v) maps to %v, %v, want the top row's second pixel", u, mu, mv)
+			}
+		}
+	})
+
+	t.Run("a uniform map is sampled evenly over the sphere", func(t *testing.T) {
+		m := NewCanvas(16, 8)
+		for y := range m.Data {
+			for x := range m.Data[y] {
+				m.Data[y][x] = White
+			}
+		}
+		e := NewEnvironmentLight(m)
+
+		_, pdf := e.Sample(0.5, 0.5)
+		if want := 1 / (4 * math.Pi); math.Abs(pdf-want) > 0.05*want {
+			t.Errorf("Sample() pdf = %v, want %v", pdf, want)
+		}
+	})
+}
+
+func TestWorld_EnvironmentLighting(t *testing.T) {
+	white := NewCanvas(16, 8)
+	for y := range white.Data {
+		for x := range white.Data[y] {
+			white.Data[y][x] = White
+		}
+	}
+
+	floor := NewPlane()
+	floor.Material = Material{Color: White, Diffuse: 1}
+
+	e := NewEnvironmentLight(white)
+	e.Samples = 4096
+	w := World{Objects: []Shape{floor}, Environment: e}
+	r := NewRay(NewPoint(0, 1, 0), NewVector(0, -1, 0))
+
+	t.Run("a white diffuse surface under a uniform white sky is white", func(t *testing.T) {
+		if got := w.ColorAt(r, MaxReflections); math.Abs(got.Red-1) > 0.1 || math.Abs(got.Green-1) > 0.1 {
+			t.Errorf("ColorAt() = %v, want about %v", got, White)
+		}
+	})
+
+	t.Run("rays that miss see the environment", func(t *testing.T) {
+		if got := w.ColorAt(NewRay(NewPoint(0, 1, 0), NewVector(0, 1, 0)), MaxReflections); !got.Equals(&White) {
+			t.Errorf("ColorAt() = %v, want %v", got, White)
+		}
+	})
+
+	t.Run("a surface covered by another object is unlit", func(t *testing.T) {
+		roof := NewPlane()
+		roof.SetTransform(NewTranslation(0, 2, 0))
+		covered := World{Objects: []Shape{floor, roof}, Environment: e}
+		if got := covered.ColorAt(r, MaxReflections); !got.Equals(&Black) {
+			t.Errorf("ColorAt() = %v, want %v", got, Black)
+		}
+	})
+}
+
+func TestParseScene_EmptyEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "empty.ppm"), []byte("P3\n0 0\n255\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ParseScene([]byte("- add: environment\n  file: empty.ppm\n"), dir)
+	var se *SceneError
+	if !errors.As(err, &se) || se.Line != 2 || se.Key != "file" {
+		t.Errorf("ParseScene() error = %v, want a *SceneError for the file at line 2", err)
+	}
+
+	problems := LintScene(&Scene{Camera: NewCamera(10, 10, 1), Environment: NewEnvironmentLight(NewCanvas(0, 0))})
+	if len(problems) != 1 || !errors.Is(problems[0], ErrInvalidValue) {
+		t.Errorf("LintScene() = %v, want the environment map without pixels", problems)
+	}
+}
@@ -0,0 +1,301 @@
+package jtracer
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Numbers in a scene file may be written as arithmetic, so that angles can be given as pi/4 or deg(45) rather than
+// 0.785398. Expressions have + - * / and parentheses, the constants below, the functions below, and the names of
+// numeric defines:
+//
+//	- define: column-height
+//	  value: 3
+//	- add: sphere
+//	  transform:
+//	    - [rotate-y, deg(30)]
+//	    - [translate, 0, column-height / 2 + 0.5, 0]
+//
+// As a name may contain hyphens, subtraction needs spaces around it where it follows a name.
+
+var expressionConstants = map[string]float64{
+	"pi":  math.Pi,
+	"tau": 2 * math.Pi,
+	"e":   math.E,
+}
+
+var expressionFunctions = map[string]struct {
+	args int // -1 for any number above zero
+	fn   func(args []float64) float64
+}{
+	"deg":   {1, func(a []float64) float64 { return a[0] * math.Pi / 180 }},
+	"sqrt":  {1, func(a []float64) float64 { return math.Sqrt(a[0]) }},
+	"sin":   {1, func(a []float64) float64 { return math.Sin(a[0]) }},
+	"cos":   {1, func(a []float64) float64 { return math.Cos(a[0]) }},
+	"tan":   {1, func(a []float64) float64 { return math.Tan(a[0]) }},
+	"abs":   {1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"floor": {1, func(a []float64) float64 { return math.Floor(a[0]) }},
+	"pow":   {2, func(a []float64) float64 { return math.Pow(a[0], a[1]) }},
+	"min":   {-1, func(a []float64) float64 { return fold(a, math.Min) }},
+	"max":   {-1, func(a []float64) float64 { return fold(a, math.Max) }},
+}
+
+func fold(values []float64, f func(a, b float64) float64) float64 {
+	result := values[0]
+	for _, v := range values[1:] {
+		result = f(result, v)
+	}
+	return result
+}
+
+// EvalExpression works out the value of an arithmetic expression, looking up names other than the built in
+// constants in vars. Its errors describe what is wrong with the expression for a *SceneError to report.
+func EvalExpression(expr string, vars map[string]float64) (float64, error) {
+	tokens, err := lexExpression(expr)
+	if err != nil {
+		return 0, err
+	}
+
+	p := exprParser{tokens: tokens, vars: vars}
+	v, err := p.sum()
+	if err != nil {
+		return 0, err
+	}
+	if t := p.peek(); t.kind != tokenEnd {
+		return 0, fmt.Errorf("unexpected %q at %d", t.text, t.pos+1)
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("%s is not a number", expr)
+	}
+	return v, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenNumber
+	tokenName
+	tokenSymbol
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	pos   int // byte offset of the token in the expression
+	value float64
+}
+
+// lexExpression splits an expression into numbers, names and the symbols + - * / ( ) and comma
+func lexExpression(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c >= '0' && c <= '9' || c == '.':
+			start := i
+			for i < len(expr) && (isDigit(expr[i]) || expr[i] == '.') {
+				i++
+			}
+			if i < len(expr) && (expr[i] == 'e' || expr[i] == 'E') {
+				j := i + 1
+				if j < len(expr) && (expr[j] == '+' || expr[j] == '-') {
+					j++
+				}
+				if j < len(expr) && isDigit(expr[j]) {
+					for i = j; i < len(expr) && isDigit(expr[i]); i++ {
+					}
+				}
+			}
+			v, err := strconv.ParseFloat(expr[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad number %q at %d", expr[start:i], start+1)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: expr[start:i], pos: start, value: v})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(expr) && isNameByte(expr, i) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenName, text: expr[start:i], pos: start})
+		case strings.ContainsRune("+-*/(),", c):
+			tokens = append(tokens, token{kind: tokenSymbol, text: string(c), pos: i})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected %q at %d", c, i+1)
+		}
+	}
+	return append(tokens, token{kind: tokenEnd, text: "end", pos: len(expr)}), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isNameByte reports whether expr[i] continues a name. A hyphen does when a letter or digit follows, as in the names
+// of defines, so x-1 is a name and x - 1 a subtraction.
+func isNameByte(expr string, i int) bool {
+	c := expr[i]
+	if c == '-' {
+		return i+1 < len(expr) && (unicode.IsLetter(rune(expr[i+1])) || isDigit(expr[i+1]))
+	}
+	return unicode.IsLetter(rune(c)) || isDigit(c) || c == '_'
+}
+
+// exprParser evaluates tokens by recursive descent as it parses them
+type exprParser struct {
+	tokens []token
+	next   int
+	vars   map[string]float64
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *exprParser) take() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEnd {
+		p.next++
+	}
+	return t
+}
+
+func (p *exprParser) expect(symbol string) error {
+	if t := p.take(); t.kind != tokenSymbol || t.text != symbol {
+		return fmt.Errorf("expected %q at %d, got %q", symbol, t.pos+1, t.text)
+	}
+	return nil
+}
+
+// sum parses terms added or subtracted
+func (p *exprParser) sum() (float64, error) {
+	v, err := p.product()
+	for err == nil {
+		t := p.peek()
+		if t.kind != tokenSymbol || (t.text != "+" && t.text != "-") {
+			break
+		}
+		p.take()
+
+		var rhs float64
+		if rhs, err = p.product(); t.text == "+" {
+			v += rhs
+		} else {
+			v -= rhs
+		}
+	}
+	return v, err
+}
+
+// product parses factors multiplied or divided
+func (p *exprParser) product() (float64, error) {
+	v, err := p.unary()
+	for err == nil {
+		t := p.peek()
+		if t.kind != tokenSymbol || (t.text != "*" && t.text != "/") {
+			break
+		}
+		p.take()
+
+		var rhs float64
+		if rhs, err = p.unary(); t.text == "*" {
+			v *= rhs
+		} else {
+			v /= rhs
+		}
+	}
+	return v, err
+}
+
+func (p *exprParser) unary() (float64, error) {
+	if t := p.peek(); t.kind == tokenSymbol && (t.text == "-" || t.text == "+") {
+		p.take()
+		v, err := p.unary()
+		if t.text == "-" {
+			v = -v
+		}
+		return v, err
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (float64, error) {
+	t := p.take()
+	switch {
+	case t.kind == tokenNumber:
+		return t.value, nil
+	case t.kind == tokenSymbol && t.text == "(":
+		v, err := p.sum()
+		if err != nil {
+			return 0, err
+		}
+		return v, p.expect(")")
+	case t.kind == tokenName:
+		if next := p.peek(); next.kind == tokenSymbol && next.text == "(" {
+			return p.call(t)
+		}
+		if v, ok := p.vars[t.text]; ok {
+			return v, nil
+		}
+		if v, ok := expressionConstants[t.text]; ok {
+			return v, nil
+		}
+		return 0, fmt.Errorf("unknown name %q", t.text)
+	}
+
+	return 0, fmt.Errorf("unexpected %q at %d", t.text, t.pos+1)
+}
+
+// call parses the arguments of a function and applies it to them
+func (p *exprParser) call(name token) (float64, error) {
+	f, ok := expressionFunctions[name.text]
+	if !ok {
+		return 0, fmt.Errorf("unknown function %q", name.text)
+	}
+
+	p.take() // the opening parenthesis
+	var args []float64
+	for {
+		v, err := p.sum()
+		if err != nil {
+			return 0, err
+		}
+		args = append(args, v)
+
+		if t := p.peek(); t.kind == tokenSymbol && t.text == "," {
+			p.take()
+			continue
+		}
+		if err := p.expect(")"); err != nil {
+			return 0, err
+		}
+		break
+	}
+
+	if f.args >= 0 && len(args) != f.args {
+		return 0, fmt.Errorf("%s takes %d arguments, got %d", name.text, f.args, len(args))
+	}
+	return f.fn(args), nil
+}
+
+// expressionNames returns the names an expression refers to other than functions, or nil if it can't be lexed
+func expressionNames(expr string) []token {
+	tokens, err := lexExpression(expr)
+	if err != nil {
+		return nil
+	}
+
+	var names []token
+	for i, t := range tokens {
+		if t.kind == tokenName && !(tokens[i+1].kind == tokenSymbol && tokens[i+1].text == "(") {
+			names = append(names, t)
+		}
+	}
+	return names
+}
@@ -0,0 +1,217 @@
+package jtracer
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEvalExpression(t *testing.T) {
+	tests := []struct {
+		expr string
+		vars map[string]float64
+		want float64
+	}{
+		{expr: "1.5", want: 1.5},
+		{expr: "2e-3", want: 0.002},
+		{expr: "pi/4", want: math.Pi / 4},
+		{expr: "deg(45)", want: math.Pi / 4},
+		{expr: "1 + 2 * 3", want: 7},
+		{expr: "(1 + 2) * 3", want: 9},
+		{expr: "8 / 2 / 2", want: 2},
+		{expr: "5 - 3 - 1", want: 1},
+		{expr: "-pi", want: -math.Pi},
+		{expr: "- -2", want: 2},
+		{expr: "tau - 2*pi", want: 0},
+		{expr: "sqrt(2) * sqrt(2)", want: 2},
+		{expr: "max(1, 3, 2) + min(4, -1)", want: 2},
+		{expr: "pow(2, 10)", want: 1024},
+		{expr: "column-height / 2", vars: map[string]float64{"column-height": 3}, want: 1.5},
+		{expr: "column-height - 1", vars: map[string]float64{"column-height": 3}, want: 2},
+		{expr: "pi", vars: map[string]float64{"pi": 3}, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := EvalExpression(tt.expr, tt.vars)
+			if err != nil {
+				t.Fatalf("EvalExpression() error = %v", err)
+			}
+			if math.Abs(got-tt.want) > epsilon {
+				t.Errorf("EvalExpression() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalExpression_Errors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{expr: "", want: `unexpected "end" at 1`},
+		{expr: "pi/", want: `unexpected "end" at 4`},
+		{expr: "2 3", want: `unexpected "3" at 3`},
+		{expr: "(1 + 2", want: `expected ")" at 7, got "end"`},
+		{expr: "1 ^ 2", want: `unexpected '^' at 3`},
+		{expr: "radius * 2", want: `unknown name "radius"`},
+		{expr: "x-1", want: `unknown name "x-1"`},
+		{expr: "cbrt(8)", want: `unknown function "cbrt"`},
+		{expr: "pow(2)", want: "pow takes 2 arguments, got 1"},
+		{expr: "1/0", want: "1/0 is not a number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := EvalExpression(tt.expr, nil)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("EvalExpression() error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseScene_Expressions(t *testing.T) {
+	scene, err := ParseScene([]byte(`
+- define: column-height
+  value: 3
+- define: half-height
+  value: column-height / 2
+- define: column
+  value:
+    - [scale, 0.5, half-height, 0.5]
+- define: tilted
+  value:
+    pattern:
+      type: stripes
+      colors: [[1, 1, 1], [0, 0, 0]]
+      transform: [[rotate-z, deg(90)]]
+    reflective: 1 / column-height
+- add: camera
+  width: 50 * 2
+  height: "max(25, 50)"
+  field-of-view: pi/3
+  from: [0, column-height, -5]
+  to: [0, half-height, 0]
+  up: [0, 1, 0]
+- add: sphere
+  material: tilted
+  transform:
+    - column
+    - [rotate-y, deg(45)]
+    - [translate, 0, half-height + 0.5, 0]
+`), ".")
+	if err != nil {
+		t.Fatalf("ParseScene() error = %v", err)
+	}
+
+	if c := scene.Camera; c.Hsize != 100 || c.Vsize != 50 || math.Abs(c.Fov-math.Pi/3) > epsilon {
+		t.Errorf("camera = %v x %v, fov %v, want 100 x 50, fov pi/3", c.Hsize, c.Vsize, c.Fov)
+	}
+	wantView := ViewTransform(NewPoint(0, 3, -5), NewPoint(0, 1.5, 0), NewVector(0, 1, 0))
+	if !cmp.Equal(scene.Camera.Transform, wantView, float64Comparer) {
+		t.Errorf("camera transform = %v, want %v", scene.Camera.Transform, wantView)
+	}
+
+	sphere := scene.Objects[0]
+	want := NewTranslation(0, 2, 0).Multiply(RotationY(math.Pi / 4)).Multiply(Scaling(0.5, 1.5, 0.5))
+	if got := sphere.GetTransform(); !cmp.Equal(got, want, float64Comparer) {
+		t.Errorf("sphere transform = %v, want %v", got, want)
+	}
+	m := sphere.GetMaterial()
+	if math.Abs(m.Reflectivity-1.0/3) > epsilon {
+		t.Errorf("reflective = %v, want 1/3", m.Reflectivity)
+	}
+	if got := m.Pattern.GetTransform(); !cmp.Equal(got, RotationZ(math.Pi/2), float64Comparer) {
+		t.Errorf("pattern transform = %v, want %v", got, RotationZ(math.Pi/2))
+	}
+}
+
+func TestParseScene_ExpressionsLeaveStrings(t *testing.T) {
+	scene, err := ParseScene([]byte(`
+- define: columns
+  value: 6
+- define: toon
+  value: 4
+- add: description
+  title: columns
+  description: Six columns
+- add: sphere
+  material:
+    shading:
+      type: toon
+      bands: toon
+    pattern:
+      type: stripes
+      colors: [[1, 1, 1], [0, 0, 0]]
+      transform: [[scale, columns, 1, 1]]
+`), ".")
+	if err != nil {
+		t.Fatalf("ParseScene() error = %v", err)
+	}
+
+	if d := scene.Description; d.Title != "columns" || d.Description != "Six columns" {
+		t.Errorf("description = %q, %q, want %q, %q", d.Title, d.Description, "columns", "Six columns")
+	}
+	m := scene.Objects[0].GetMaterial()
+	if want := (ToonShading{Bands: 4}); m.Shading != want {
+		t.Errorf("shading = %v, want %v", m.Shading, want)
+	}
+	if got := m.Pattern.GetTransform(); !cmp.Equal(got, Scaling(6, 1, 1), float64Comparer) {
+		t.Errorf("pattern transform = %v, want %v", got, Scaling(6, 1, 1))
+	}
+}
+
+func TestParseScene_ExpressionErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		line    int
+		key     string
+		message string
+	}{
+		{
+			name: "an unknown name",
+			yaml: "- add: camera\n  width: 10\n  height: 10\n  field-of-view: fov\n",
+			line: 4, key: "field-of-view",
+			message: `unknown name "fov"`,
+		},
+		{
+			name: "a malformed expression",
+			yaml: "- add: sphere\n  transform:\n    - [rotate-x, pi/]\n",
+			line: 3, key: "transform",
+			message: `unexpected "end" at 4`,
+		},
+		{
+			name: "a width that isn't whole",
+			yaml: "- add: camera\n  width: 10 / 4\n  height: 10\n  field-of-view: 1\n",
+			line: 2, key: "width",
+			message: "which is 2.5",
+		},
+		{
+			name: "a bad expression in a define",
+			yaml: "- define: size\n  value: 2 *\n- add: sphere\n  transform:\n    - [scale, size, size, size]\n",
+			line: 2, key: "value",
+			message: `unexpected "end" at 4`,
+		},
+		{
+			name: "defines that refer to each other",
+			yaml: "- define: a\n  value: b + 1\n- define: b\n  value: a + 1\n- add: sphere\n  transform:\n    - [scale, a, 1, 1]\n",
+			line: 4, key: "value",
+			message: `define "a" refers to itself`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseScene([]byte(tt.yaml), ".")
+			var se *SceneError
+			if !errors.As(err, &se) || !errors.Is(err, ErrInvalidValue) {
+				t.Fatalf("ParseScene() error = %v, want a *SceneError for an invalid value", err)
+			}
+			if se.Line != tt.line || se.Key != tt.key || !strings.Contains(se.Err.Error(), tt.message) {
+				t.Errorf("ParseScene() error = %v, want line %d, key %q and %q", err, tt.line, tt.key, tt.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package jtracer
+
+import "math"
+
+// FilteredPattern is implemented by patterns that can average their color over the footprint of a pixel, given by
+// the offsets dx and dy, in pattern space, to the points seen through the neighbouring pixels
+type FilteredPattern interface {
+	Pattern
+	ColorAtFootprint(point, dx, dy Tuple) Color
+}
+
+// FilteredPatternAtShape returns the color of a pattern averaged over the footprint of a pixel, given by the world
+// space offsets dpdx and dpdy
+func FilteredPatternAtShape(p FilteredPattern, shape Shape, worldPoint, dpdx, dpdy Tuple) Color {
+	toPattern := p.GetInverse().Multiply(shape.GetInverse())
+
+	point := toPattern.MultiplyByTuple(worldPoint)
+	dx := toPattern.MultiplyByTuple(dpdx)
+	dy := toPattern.MultiplyByTuple(dpdy)
+
+	return p.ColorAtFootprint(*point, *dx, *dy)
+}
+
+// SurfaceMaterial returns the material of the object hit, with a filtered pattern replaced by its color averaged over
+// the footprint of the pixel when the footprint is known. Like Lighting, it looks at the pattern at the over point, so
+// that a hit just below a surface the pattern changes at doesn't take the color from the other side.
+func (c Computations) SurfaceMaterial() Material {
+	m := c.Object.GetMaterial()
+	if !m.HasPattern || !c.HasFootprint() {
+		return m
+	}
+
+	fp, ok := m.Pattern.(FilteredPattern)
+	if !ok {
+		return m
+	}
+
+	m.Color = FilteredPatternAtShape(fp, c.Object, c.OverPoint, c.Dpdx, c.Dpdy)
+	m.Pattern, m.HasPattern = nil, false
+	return m
+}
+
+// ColorAtFootprint box filters the checkers analytically. The checkers are the product of a square wave along each
+// axis, so their average over a box is the product of each wave's average along that side of the box.
+func (s *CheckersPattern) ColorAtFootprint(p, dx, dy Tuple) Color {
+	wave := filteredSquareWave(p.X, math.Max(math.Abs(dx.X), math.Abs(dy.X))) *
+		filteredSquareWave(p.Y, math.Max(math.Abs(dx.Y), math.Abs(dy.Y))) *
+		filteredSquareWave(p.Z, math.Max(math.Abs(dx.Z), math.Abs(dy.Z)))
+
+	return blend(nestedColor(s.A, s.PatternA, p), nestedColor(s.B, s.PatternB, p), (1-wave)/2)
+}
+
+// filteredSquareWave averages a wave that is 1 where floor(x) is even and -1 where it is odd over a box of the given
+// width centered on x
+func filteredSquareWave(x, width float64) float64 {
+	if width < epsilon {
+		if math.Mod(math.Floor(x), 2) == 0 {
+			return 1
+		}
+		return -1
+	}
+
+	// the integral of the square wave is a triangle wave
+	integral := func(x float64) float64 {
+		return 1 - math.Abs(x-2*math.Floor(x/2)-1)
+	}
+
+	return (integral(x+width/2) - integral(x-width/2)) / width
+}
+
+// ColorAtFootprint samples the mipmap level whose texels best match the size of the footprint, blending between the
+// two nearest levels
+func (s *TextureMapPattern) ColorAtFootprint(p, dx, dy Tuple) Color {
+	if len(s.Mipmaps) == 0 {
+		return s.ColorAt(p)
+	}
+
+	u, v := s.Mapper.Map(p)
+	ux, vx := s.Mapper.Map(*p.Add(&dx))
+	uy, vy := s.Mapper.Map(*p.Add(&dy))
+
+	width, height := float64(s.Image.Width), float64(s.Image.Height)
+	texels := math.Max(
+		math.Hypot(wrapDelta(ux-u)*width, wrapDelta(vx-v)*height),
+		math.Hypot(wrapDelta(uy-u)*width, wrapDelta(vy-v)*height),
+	)
+
+	return SampleMipmaps(s.Mipmaps, u, v, texels)
+}
+
+// BuildMipmaps returns a chain of canvases starting with c, each half the size of the one before and averaging its
+// texels in blocks of two by two, down to a single texel
+func BuildMipmaps(c *Canvas) []*Canvas {
+	levels := []*Canvas{c}
+
+	for c.Width > 1 || c.Height > 1 {
+		next := NewCanvas(int(math.Max(1, float64(c.Width/2))), int(math.Max(1, float64(c.Height/2))))
+		for y := 0; y < next.Height; y++ {
+			for x := 0; x < next.Width; x++ {
+				var sum Color
+				for _, offset := range [][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+					sx := int(math.Min(float64(2*x+offset[0]), float64(c.Width-1)))
+					sy := int(math.Min(float64(2*y+offset[1]), float64(c.Height-1)))
+					sum = *sum.Add(&c.Data[sy][sx])
+				}
+				next.Data[y][x] = *sum.MultiplyByScalar(0.25)
+			}
+		}
+
+		levels = append(levels, next)
+		c = next
+	}
+
+	return levels
+}
+
+// SampleMipmaps samples a chain of mipmaps at texture coordinates u and v for a footprint spanning the given number
+// of texels of the largest level, interpolating between the two levels nearest in size
+func SampleMipmaps(levels []*Canvas, u, v, texels float64) Color {
+	if texels <= 1 {
+		return levels[0].Sample(u, v)
+	}
+
+	level := math.Log2(texels)
+	if level >= float64(len(levels)-1) {
+		return levels[len(levels)-1].Sample(u, v)
+	}
+
+	lo := int(level)
+	return blend(levels[lo].Sample(u, v), levels[lo+1].Sample(u, v), level-float64(lo))
+}
+
+// wrapDelta returns the difference between two texture coordinates, taking the short way around when they wrap
+func wrapDelta(d float64) float64 {
+	return d - math.Round(d)
+}
@@ -0,0 +1,182 @@
+package jtracer
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestFilteredSquareWave(t *testing.T) {
+	tests := []struct {
+		name  string
+		x     float64
+		width float64
+		want  float64
+	}{
+		{name: "an even cell without filtering", x: 0.5, want: 1},
+		{name: "an odd cell without filtering", x: 1.5, want: -1},
+		{name: "a negative odd cell without filtering", x: -0.5, want: -1},
+		{name: "a box within an even cell", x: 0.5, width: 1, want: 1},
+		{name: "a box straddling two cells equally", x: 1, width: 1, want: 0},
+		{name: "a box mostly within an even cell", x: 0.75, width: 1, want: 0.5},
+		{name: "a box spanning a whole period", x: 0.3, width: 2, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filteredSquareWave(tt.x, tt.width); !cmp.Equal(got, tt.want, float64Comparer) {
+				t.Errorf("filteredSquareWave() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckersPattern_ColorAtFootprint(t *testing.T) {
+	p := NewCheckersPattern(White, Black)
+
+	tests := []struct {
+		name   string
+		point  Tuple
+		dx, dy Tuple
+		want   Color
+	}{
+		{
+			name:  "a tiny footprint sees a single checker",
+			point: *NewPoint(0.5, 0, 1.5),
+			dx:    *NewVector(0.01, 0, 0),
+			dy:    *NewVector(0, 0, 0.01),
+			want:  Black,
+		},
+		{
+			name:  "a footprint covering many checkers averages them",
+			point: *NewPoint(0.5, 0, 0.5),
+			dx:    *NewVector(4, 0, 0),
+			dy:    *NewVector(0, 0, 4),
+			want:  Color{0.5, 0.5, 0.5},
+		},
+		{
+			name:  "a footprint straddling an edge blends its two sides",
+			point: *NewPoint(1, 0, 0.5),
+			dx:    *NewVector(0.5, 0, 0),
+			dy:    *NewVector(0, 0, 0.1),
+			want:  Color{0.5, 0.5, 0.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.ColorAtFootprint(tt.point, tt.dx, tt.dy); !got.Equals(&tt.want) {
+				t.Errorf("ColorAtFootprint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildMipmaps(t *testing.T) {
+	c := NewCanvas(4, 2)
+	c.Data[0][0] = White
+	c.Data[1][3] = Color{0, 0, 4}
+
+	levels := BuildMipmaps(c)
+	if len(levels) != 3 {
+		t.Fatalf("BuildMipmaps() = %d levels, want 3", len(levels))
+	}
+
+	tests := []struct {
+		level, x, y int
+		want        Color
+	}{
+		{1, 0, 0, Color{0.25, 0.25, 0.25}},
+		{1, 1, 0, Color{0, 0, 1}},
+		{2, 0, 0, Color{0.125, 0.125, 0.625}},
+	}
+	for _, tt := range tests {
+		if got := levels[tt.level].PixelAt(tt.x, tt.y); !got.Equals(&tt.want) {
+			t.Errorf("BuildMipmaps() level %d pixel %d, %d = %v, want %v", tt.level, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestTextureMapPattern_ColorAtFootprint(t *testing.T) {
+	// a 4x4 checkerboard of single texels
+	img := NewCanvas(4, 4)
+	for y := range img.Data {
+		for x := range img.Data[y] {
+			if (x+y)%2 == 0 {
+				img.Data[y][x] = White
+			}
+		}
+	}
+	p := NewTextureMapPattern(PlanarMap{}, img)
+
+	tests := []struct {
+		name   string
+		dx, dy Tuple
+		want   Color
+	}{
+		{
+			name: "a footprint smaller than a texel samples the full size image",
+			dx:   *NewVector(0.1, 0, 0),
+			dy:   *NewVector(0, 0, 0.1),
+			want: White,
+		},
+		{
+			name: "a footprint spanning several texels samples a smaller level",
+			dx:   *NewVector(0.5, 0, 0),
+			dy:   *NewVector(0, 0, 0.5),
+			want: Color{0.5, 0.5, 0.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.ColorAtFootprint(*NewPoint(0.125, 0, 0.875), tt.dx, tt.dy); !got.Equals(&tt.want) {
+				t.Errorf("ColorAtFootprint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputations_SurfaceMaterial(t *testing.T) {
+	floor := NewPlane()
+	checkers := NewCheckersPattern(White, Black)
+	floor.Material.Pattern = &checkers
+	floor.Material.HasPattern = true
+
+	origin, direction := NewPoint(0.5, 1, 0.5), NewVector(0, -1, 0)
+	i := Intersection{T: 1, Object: floor}
+
+	t.Run("without ray differentials the pattern is kept", func(t *testing.T) {
+		comps := i.PrepareComputations(NewRay(origin, direction), Intersections{i})
+		if m := comps.SurfaceMaterial(); !m.HasPattern {
+			t.Errorf("SurfaceMaterial() = %v, want the pattern kept", m)
+		}
+	})
+
+	t.Run("with ray differentials the pattern is filtered over the footprint", func(t *testing.T) {
+		r := NewRay(origin, direction)
+		r.Differentials = &RayDifferentials{
+			XOrigin: *NewPoint(4.5, 1, 0.5), XDirection: *direction,
+			YOrigin: *NewPoint(0.5, 1, 4.5), YDirection: *direction,
+		}
+		comps := i.PrepareComputations(r, Intersections{i})
+
+		m := comps.SurfaceMaterial()
+		if want := (Color{0.5, 0.5, 0.5}); m.HasPattern || !m.Color.Equals(&want) {
+			t.Errorf("SurfaceMaterial() = %v, want the color %v", m, want)
+		}
+	})
+	t.Run("a hit just below the surface takes the color above it", func(t *testing.T) {
+		// the floor is white from y = 0 up, black below; a footprint flat in y picks the side of the point exactly
+		point := *NewPoint(0.5, -2.2e-16, 0.5)
+		comps := Computations{
+			Object:        floor,
+			Point:         point,
+			OverPoint:     *point.Add(NewVector(0, epsilon, 0)),
+			Differentials: &RayDifferentials{},
+			Dpdx:          *NewVector(0.1, 0, 0),
+			Dpdy:          *NewVector(0, 0, 0.1),
+		}
+
+		want := PatternAtShape(&checkers, floor, comps.OverPoint)
+		if m := comps.SurfaceMaterial(); !m.Color.Equals(&want) {
+			t.Errorf("SurfaceMaterial() color = %v, want %v", m.Color, want)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package jtracer
+
+import "math/rand"
+
+// GlossySamples is the number of rays averaged for a rough reflection or refraction seen directly by the camera
+const GlossySamples = 16
+
+// glossySampleCount returns how many rays to trace through the lobe of a rough material
+func glossySampleCount(m Material, remaining int) int {
+	samples := m.GlossySamples
+	if samples <= 0 {
+		samples = GlossySamples
+	}
+
+	return scaledSampleCount(samples, remaining)
+}
+
+// scaledSampleCount reduces the samples taken at a point seen directly by the camera for a point reached after some
+// bounces. Every bounce already taken quarters the count so that nested sampling doesn't multiply the work beyond
+// MaxReflections allows.
+func scaledSampleCount(samples int, remaining int) int {
+	depth := MaxReflections - remaining
+	if depth > 0 {
+		samples >>= 2 * depth
+	}
+
+	if samples < 1 {
+		return 1
+	}
+	return samples
+}
+
+// randomInUnitSphere returns a random vector no longer than 1
+func randomInUnitSphere() Tuple {
+	for {
+		v := NewVector(rand.Float64()*2-1, rand.Float64()*2-1, rand.Float64()*2-1)
+		if v.Dot(v) <= 1 {
+			return *v
+		}
+	}
+}
+
+// jitterDirection perturbs direction within a lobe whose width grows with roughness. The result stays on the side
+// of the surface given by side: 1 for reflections leaving along the normal and -1 for refractions passing through.
+func jitterDirection(direction, normal Tuple, roughness, side float64) Tuple {
+	for i := 0; i < 8; i++ {
+		offset := randomInUnitSphere()
+		jittered := direction.Add(offset.Multiply(roughness)).Normalize()
+		if jittered.Dot(&normal)*side > 0 {
+			return *jittered
+		}
+	}
+
+	// grazing directions may keep falling through the surface; fall back to the perfect direction
+	return direction
+}
+
+// traceLobe averages the color seen along several jittered copies of a ray leaving a rough surface
+func (w World) traceLobe(origin, direction, normal Tuple, side float64, m Material, remaining int, wavelength float64) Color {
+	samples := glossySampleCount(m, remaining)
+
+	var total Color
+	for i := 0; i < samples; i++ {
+		d := jitterDirection(direction, normal, m.Roughness, side)
+		r := Ray{Origin: &origin, Direction: &d, Wavelength: wavelength}
+		total = *total.Add(w.ColorAt(r, remaining-1))
+	}
+
+	return *total.MultiplyByScalar(1 / float64(samples))
+}
@@ -0,0 +1,92 @@
+package jtracer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGlossySampleCount(t *testing.T) {
+	tests := []struct {
+		name      string
+		material  Material
+		remaining int
+		want      int
+	}{
+		{
+			name:      "a glossy surface seen by the camera uses the default sample count",
+			material:  Material{Roughness: 0.3},
+			remaining: MaxReflections,
+			want:      GlossySamples,
+		},
+		{
+			name:      "a glossy surface seen in a reflection uses fewer samples",
+			material:  Material{Roughness: 0.3},
+			remaining: MaxReflections - 1,
+			want:      GlossySamples / 4,
+		},
+		{
+			name:      "deeply nested glossy surfaces trace a single ray",
+			material:  Material{Roughness: 0.3},
+			remaining: 1,
+			want:      1,
+		},
+		{
+			name:      "the material can ask for more samples",
+			material:  Material{Roughness: 0.3, GlossySamples: 64},
+			remaining: MaxReflections,
+			want:      64,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := glossySampleCount(tt.material, tt.remaining); got != tt.want {
+				t.Errorf("glossySampleCount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJitterDirection(t *testing.T) {
+	direction := *NewVector(0, math.Sqrt(2)/2, math.Sqrt(2)/2)
+	normal := *NewVector(0, 1, 0)
+
+	if got := jitterDirection(direction, normal, 0, 1); !got.Equals(&direction) {
+		t.Errorf("jitterDirection() with no roughness = %v, want %v", got, direction)
+	}
+
+	for i := 0; i < 100; i++ {
+		got := jitterDirection(direction, normal, 0.5, 1)
+		if got.Dot(&normal) <= 0 {
+			t.Errorf("jitterDirection() = %v, want it to stay above the surface", got)
+		}
+		if got.Dot(&direction) < math.Cos(math.Pi/6)-epsilon {
+			t.Errorf("jitterDirection() = %v, want it within 30 degrees of %v", got, direction)
+		}
+	}
+}
+
+func TestWorld_ReflectedColorWithRoughness(t *testing.T) {
+	// a rough mirror inside a uniformly glowing sphere sees the same color along every jittered ray
+	sky := NewSphere()
+	sky.SetTransform(Scaling(100, 100, 100))
+	sky.Material = Material{Color: White, Ambient: 1}
+
+	floor := NewPlane()
+	floor.Material = NewMaterial()
+	floor.Material.Reflectivity = 0.5
+	floor.Material.Roughness = 0.4
+
+	w := World{
+		Objects: []Shape{sky, floor},
+		Light:   NewPointLight(*NewPoint(-10, 10, -10), White),
+	}
+
+	r := NewRay(NewPoint(0, 1, -3), NewVector(0, -math.Sqrt(2)/2, math.Sqrt(2)/2))
+	xs := w.Intersect(r)
+	comps := xs.Hit().PrepareComputations(r, xs)
+
+	want := Color{0.5, 0.5, 0.5}
+	if got := w.ReflectedColor(comps, MaxReflections); !got.Equals(&want) {
+		t.Errorf("ReflectedColor() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package jtracer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// maxHDRPixels limits the size of the images ReadHDR reads, so that a corrupt header fails rather than eating memory
+const maxHDRPixels = 1 << 27
+
+// ReadHDR reads a Radiance RGBE image, keeping the full dynamic range of its colors. Both flat and run-length
+// encoded scanlines are understood; only the standard "-Y height +X width" orientation is supported.
+func ReadHDR(r io.Reader) (*Canvas, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("hdr: unexpected end of file")
+	}
+	if magic = strings.TrimSpace(magic); magic != "#?RADIANCE" && magic != "#?RGBE" {
+		return nil, fmt.Errorf("hdr: unsupported format %q", magic)
+	}
+
+	// header variables run until a blank line
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("hdr: unexpected end of file")
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "FORMAT=") && line != "FORMAT=32-bit_rle_rgbe" {
+			return nil, fmt.Errorf("hdr: unsupported pixel format %q", strings.TrimPrefix(line, "FORMAT="))
+		}
+	}
+
+	resolution, err := br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("hdr: unexpected end of file")
+	}
+	var width, height int
+	if _, err := fmt.Sscanf(resolution, "-Y %d +X %d", &height, &width); err != nil {
+		return nil, fmt.Errorf("hdr: unsupported resolution %q", strings.TrimSpace(resolution))
+	}
+	if width <= 0 || height <= 0 || width > maxHDRPixels/height {
+		return nil, fmt.Errorf("hdr: invalid size %dx%d", width, height)
+	}
+
+	c := NewCanvas(width, height)
+	scanline := make([][4]byte, width)
+	for y := 0; y < height; y++ {
+		if err := readHDRScanline(br, scanline); err != nil {
+			return nil, err
+		}
+		for x, rgbe := range scanline {
+			c.Data[y][x] = rgbeToColor(rgbe)
+		}
+	}
+
+	return c, nil
+}
+
+// readHDRScanline fills scanline with one row of RGBE pixels, decoding run-length encoding when the row uses it
+func readHDRScanline(br *bufio.Reader, scanline [][4]byte) error {
+	width := len(scanline)
+
+	var first [4]byte
+	if _, err := io.ReadFull(br, first[:]); err != nil {
+		return fmt.Errorf("hdr: unexpected end of file")
+	}
+
+	// run-length encoded rows start with 2, 2 and the row width; anything else is a flat row of pixels
+	if width < 8 || width > 0x7fff || first[0] != 2 || first[1] != 2 || first[2]&0x80 != 0 {
+		scanline[0] = first
+		for x := 1; x < width; x++ {
+			if _, err := io.ReadFull(br, scanline[x][:]); err != nil {
+				return fmt.Errorf("hdr: unexpected end of file")
+			}
+		}
+		return nil
+	}
+
+	if int(first[2])<<8|int(first[3]) != width {
+		return fmt.Errorf("hdr: scanline width mismatch")
+	}
+
+	// each channel is encoded separately as a sequence of runs and literal spans
+	for channel := 0; channel < 4; channel++ {
+		for x := 0; x < width; {
+			count, err := br.ReadByte()
+			if err != nil {
+				return fmt.Errorf("hdr: unexpected end of file")
+			}
+
+			if count > 128 {
+				n := int(count) - 128
+				value, err := br.ReadByte()
+				if err != nil {
+					return fmt.Errorf("hdr: unexpected end of file")
+				}
+				if x+n > width {
+					return fmt.Errorf("hdr: run overflows scanline")
+				}
+				for ; n > 0; n-- {
+					scanline[x][channel] = value
+					x++
+				}
+				continue
+			}
+
+			n := int(count)
+			if n == 0 || x+n > width {
+				return fmt.Errorf("hdr: invalid run length")
+			}
+			for ; n > 0; n-- {
+				value, err := br.ReadByte()
+				if err != nil {
+					return fmt.Errorf("hdr: unexpected end of file")
+				}
+				scanline[x][channel] = value
+				x++
+			}
+		}
+	}
+
+	return nil
+}
+
+// rgbeToColor decodes a pixel whose three mantissas share the exponent in its fourth byte
+func rgbeToColor(rgbe [4]byte) Color {
+	if rgbe[3] == 0 {
+		return Black
+	}
+
+	f := math.Ldexp(1, int(rgbe[3])-(128+8))
+	return Color{float64(rgbe[0]) * f, float64(rgbe[1]) * f, float64(rgbe[2]) * f}
+}
@@ -0,0 +1,105 @@
+package jtracer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadHDR(t *testing.T) {
+	header := "#?RADIANCE\n# made by hand\nFORMAT=32-bit_rle_rgbe\nEXPOSURE=1.0\n\n"
+
+	tests := []struct {
+		name    string
+		hdr     string
+		x       int
+		want    Color
+		wantErr bool
+	}{
+		{
+			name: "reading a flat scanline",
+			hdr:  header + "-Y 1 +X 2\n" + "\x80\x40\x00\x81" + "\x00\x00\x00\x00",
+			want: Color{1, 0.5, 0},
+		},
+		{
+			name: "colors brighter than white keep their range",
+			hdr:  header + "-Y 1 +X 1\n" + "\x80\x40\x00\x88",
+			want: Color{128, 64, 0},
+		},
+		{
+			name: "reading a run length encoded scanline",
+			hdr: header + "-Y 1 +X 8\n" + "\x02\x02\x00\x08" +
+				"\x88\x80" + // red: a run of eight 128s
+				"\x08\x40\x40\x40\x40\x40\x40\x40\x40" + // green: eight literal 64s
+				"\x88\x00" + // blue: a run of eight zeros
+				"\x88\x82", // exponent: a run of eight 130s
+			x:    7,
+			want: Color{2, 1, 0},
+		},
+		{
+			name:    "reading a file that is not an hdr",
+			hdr:     "P3\n1 1\n255\n0 0 0\n",
+			wantErr: true,
+		},
+		{
+			name:    "reading an hdr with an unsupported orientation",
+			hdr:     header + "+Y 1 +X 1\n" + "\x80\x40\x00\x81",
+			wantErr: true,
+		},
+		{
+			name:    "reading an hdr with no pixels",
+			hdr:     header + "-Y 1 +X 0\n",
+			wantErr: true,
+		},
+		{
+			name:    "reading an hdr with a negative size",
+			hdr:     header + "-Y -1 +X 1\n" + "\x80\x40\x00\x81",
+			wantErr: true,
+		},
+		{
+			name:    "reading an hdr too large to hold",
+			hdr:     header + "-Y 100000 +X 100000\n",
+			wantErr: true,
+		},
+		{
+			name:    "reading a truncated hdr",
+			hdr:     header + "-Y 1 +X 2\n" + "\x80\x40\x00\x81",
+			wantErr: true,
+		},
+		{
+			name:    "reading a run that overflows its scanline",
+			hdr:     header + "-Y 1 +X 8\n" + "\x02\x02\x00\x08" + "\x89\x80",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ReadHDR(strings.NewReader(tt.hdr))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadHDR() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := c.PixelAt(tt.x, 0); !got.Equals(&tt.want) {
+				t.Errorf("ReadHDR() pixel = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadImage_HDR(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "studio.hdr")
+	if err := os.WriteFile(path, []byte("#?RGBE\n\n-Y 1 +X 1\n\x80\x80\x80\x83"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadImage(path)
+	if err != nil {
+		t.Fatalf("LoadImage() error = %v", err)
+	}
+	if want := (Color{4, 4, 4}); !got.PixelAt(0, 0).Equals(&want) {
+		t.Errorf("LoadImage() pixel = %v, want %v", got.PixelAt(0, 0), want)
+	}
+}
@@ -0,0 +1,131 @@
+package jtracer
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// An include entry pulls the entries of another scene file into a scene in its place, so that defines and objects can
+// be shared between scenes:
+//
+//	- include: props/table.yaml
+//	- include: std:materials.yaml
+//
+// Paths are relative to the including file. Those starting with LibraryPrefix name the files of the standard library
+// bundled with jtracer, found in the library directory.
+
+// LibraryPrefix starts the name of an included file from the standard library
+const LibraryPrefix = "std:"
+
+//go:embed library/*.yaml
+var library embed.FS
+
+// sceneEntry is an entry of a scene along with where it came from, which for an included entry is another file
+type sceneEntry struct {
+	node  *yaml.Node
+	file  string // the file holding the entry, "" when the scene wasn't read from a file
+	index int    // the entry's position within its file
+
+	counters map[string]float64 // the counters of the repeats that made the entry, by name
+}
+
+// locate records where an error was found, unless it happened within another file the entry includes
+func (e sceneEntry) locate(err error) error {
+	var se *SceneError
+	if errors.As(err, &se) && se.File != "" && se.File != e.file {
+		return err
+	}
+	return inEntry(err, e.index, e.file)
+}
+
+// sceneEntries parses YAML read from file into a scene's entries, replacing each include with the entries of the file
+// it names. Relative paths are resolved against dir, and including lists the files already being read, outermost
+// first, to catch files that include themselves.
+func sceneEntries(data []byte, file, dir string, including []string) ([]sceneEntry, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, &SceneError{File: file, Index: -1, Err: err}
+	}
+
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	root := doc.Content[0]
+	if err := expectSequence(root, ""); err != nil {
+		return nil, inEntry(err, -1, file)
+	}
+
+	// files referenced by the scene are relative to the scene file itself
+	resolvePaths(root, dir)
+
+	var entries []sceneEntry
+	for i, n := range root.Content {
+		entry := sceneEntry{node: n, file: file, index: i}
+
+		name := lookup(n, "include")
+		if name == nil {
+			entries = append(entries, entry)
+			continue
+		}
+
+		included, err := includeFile(name, dir, including)
+		if err != nil {
+			return nil, entry.locate(err)
+		}
+		entries = append(entries, included...)
+	}
+
+	return entries, nil
+}
+
+// includeFile reads the entries of the file named by an include
+func includeFile(name *yaml.Node, dir string, including []string) ([]sceneEntry, error) {
+	target, err := decodeString(name, "include")
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	var file, fileDir string
+	if strings.HasPrefix(target, LibraryPrefix) {
+		file, fileDir = target, ""
+		data, err = library.ReadFile(path.Join("library", strings.TrimPrefix(target, LibraryPrefix)))
+	} else {
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(dir, target)
+		}
+		file, fileDir = filepath.Clean(target), filepath.Dir(target)
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, nodeError(name, "include", err)
+	}
+
+	for i, f := range including {
+		if sameFile(f, file) {
+			chain := strings.Join(append(append([]string{}, including[i:]...), file), " -> ")
+			return nil, nodeError(name, "include", fmt.Errorf("%w: %s", ErrIncludeCycle, chain))
+		}
+	}
+
+	return sceneEntries(data, file, fileDir, append(including[:len(including):len(including)], file))
+}
+
+// sameFile reports whether two included paths name the same file
+func sameFile(a, b string) bool {
+	if a == b {
+		return true
+	}
+
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
@@ -0,0 +1,161 @@
+package jtracer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes each of files, given by path relative to dir, creating directories as needed
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadSceneFile_Include(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"scene.yaml":       "- include: props/table.yaml\n- add: sphere\n  material: red\n",
+		"props/table.yaml": "- include: ../materials.yaml\n- add: plane\n  material: red\n",
+		"materials.yaml":   "- define: red\n  value:\n    color: [1, 0, 0]\n    pattern:\n      type: map\n      file: textures/red.ppm\n",
+		"textures/red.ppm": "P3 1 1 255 255 0 0\n",
+	})
+
+	scene, err := LoadSceneFile(filepath.Join(dir, "scene.yaml"))
+	if err != nil {
+		t.Fatalf("LoadSceneFile() error = %v", err)
+	}
+
+	if len(scene.Objects) != 2 {
+		t.Fatalf("LoadSceneFile() = %d objects, want 2", len(scene.Objects))
+	}
+	if _, ok := scene.Objects[0].(*Plane); !ok {
+		t.Errorf("LoadSceneFile() first object = %T, want the included *Plane", scene.Objects[0])
+	}
+	for _, o := range scene.Objects {
+		if m := o.GetMaterial(); !m.Color.Equals(&Red) || !m.HasPattern {
+			t.Errorf("LoadSceneFile() material = %v, want the included red material", m)
+		}
+	}
+}
+
+func TestLoadSceneFile_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		file  string // where the error should be reported
+		index int
+		line  int
+		want  error
+	}{
+		{
+			name:  "a missing file",
+			files: map[string]string{"scene.yaml": "- add: sphere\n- include: missing.yaml\n"},
+			file:  "scene.yaml", index: 1, line: 2,
+			want: os.ErrNotExist,
+		},
+		{
+			name:  "a file that includes itself",
+			files: map[string]string{"scene.yaml": "- include: scene.yaml\n"},
+			file:  "scene.yaml", index: 0, line: 1,
+			want: ErrIncludeCycle,
+		},
+		{
+			name: "files that include each other",
+			files: map[string]string{
+				"scene.yaml": "- include: a.yaml\n",
+				"a.yaml":     "- add: sphere\n- include: sub/b.yaml\n",
+				"sub/b.yaml": "- include: ../a.yaml\n",
+			},
+			file: "sub/b.yaml", index: 0, line: 1,
+			want: ErrIncludeCycle,
+		},
+		{
+			name: "a problem within an included file",
+			files: map[string]string{
+				"scene.yaml": "- add: sphere\n- include: props.yaml\n",
+				"props.yaml": "- add: sphere\n- add: sphere\n  transform:\n    - [twist, 1]\n",
+			},
+			file: "props.yaml", index: 1, line: 4,
+			want: ErrUnknownTransform,
+		},
+		{
+			name: "an included file that isn't a list",
+			files: map[string]string{
+				"scene.yaml": "- include: props.yaml\n",
+				"props.yaml": "add: sphere\n",
+			},
+			file: "props.yaml", index: -1, line: 1,
+			want: ErrInvalidValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+
+			_, err := LoadSceneFile(filepath.Join(dir, "scene.yaml"))
+
+			var se *SceneError
+			if !errors.As(err, &se) || !errors.Is(err, tt.want) {
+				t.Fatalf("LoadSceneFile() error = %v, want %v", err, tt.want)
+			}
+			if se.File != filepath.Join(dir, tt.file) || se.Index != tt.index || se.Line != tt.line {
+				t.Errorf("LoadSceneFile() error = %v, want it in %v entry %d line %d", err, tt.file, tt.index, tt.line)
+			}
+		})
+	}
+}
+
+func TestLibrary_Materials(t *testing.T) {
+	data, err := library.ReadFile("library/materials.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := sceneEntries(data, "std:materials.yaml", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, errs := collectDefines(entries)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	// every material in the library can be used
+	var scene strings.Builder
+	scene.WriteString("- include: std:materials.yaml\n")
+	for name := range defs {
+		fmt.Fprintf(&scene, "- add: sphere\n  material: %s\n", name)
+	}
+
+	s, err := ParseScene([]byte(scene.String()), ".")
+	if err != nil {
+		t.Fatalf("ParseScene() error = %v", err)
+	}
+	if len(s.Objects) != len(defs) {
+		t.Errorf("ParseScene() = %d objects, want %d", len(s.Objects), len(defs))
+	}
+
+	glass, _ := ParseScene([]byte("- include: std:materials.yaml\n- add: sphere\n  material: diamond\n"), ".")
+	if m := glass.Objects[0].GetMaterial(); m.RefractiveIndex != 2.417 || m.Transparency != 0.9 {
+		t.Errorf("diamond = %v, want glass with a refractive index of 2.417", m)
+	}
+}
+
+func TestValidateScene_IncludedDefines(t *testing.T) {
+	problems := ValidateScene([]byte(validCameraAndLight+"- include: std:materials.yaml\n- add: sphere\n  material: gold\n"), ".")
+	if len(problems) != 0 {
+		t.Errorf("ValidateScene() = %v, want no warnings about the library's unused defines", problems)
+	}
+}
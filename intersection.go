@@ -51,6 +51,13 @@ type Computations struct {
 	Reflectv   Tuple
 	N1         float64 // n1 is the refractive index belonging to the material being exited
 	N2         float64 // n2 is the refractive index belonging to the material being entered
+	Exited     Shape   // the object whose material is being exited, nil when leaving empty space
+	Entered    Shape   // the object whose material is being entered, nil when entering empty space
+	Wavelength float64 // wavelength of the incoming ray in nanometres, zero for white light
+
+	Differentials *RayDifferentials // of the incoming ray, nil when it carries none
+	Dpdx          Tuple             // offset to the point seen through the pixel to the right, when Differentials is set
+	Dpdy          Tuple             // offset to the point seen through the pixel below, when Differentials is set
 }
 
 type container []Shape
@@ -82,9 +89,10 @@ func (i Intersection) Equal(i2 Intersection) bool {
 
 func (i Intersection) PrepareComputations(r Ray, xs Intersections) Computations {
 	comps := Computations{
-		T:      i.T,
-		Object: i.Object,
-		Inside: false,
+		T:          i.T,
+		Object:     i.Object,
+		Inside:     false,
+		Wavelength: r.Wavelength,
 	}
 
 	// containers will record which objects have been entered but not yet exited
@@ -96,7 +104,8 @@ func (i Intersection) PrepareComputations(r Ray, xs Intersections) Computations
 			if len(containers) == 0 {
 				comps.N1 = 1.0
 			} else {
-				comps.N1 = containers[len(containers)-1].GetMaterial().RefractiveIndex
+				comps.Exited = containers[len(containers)-1]
+				comps.N1 = comps.Exited.GetMaterial().RefractiveIndexAt(r.Wavelength)
 			}
 		}
 
@@ -110,7 +119,8 @@ func (i Intersection) PrepareComputations(r Ray, xs Intersections) Computations
 			if len(containers) == 0 {
 				comps.N2 = 1.0
 			} else {
-				comps.N2 = containers[len(containers)-1].GetMaterial().RefractiveIndex
+				comps.Entered = containers[len(containers)-1]
+				comps.N2 = comps.Entered.GetMaterial().RefractiveIndexAt(r.Wavelength)
 			}
 		}
 	}
@@ -118,6 +128,12 @@ func (i Intersection) PrepareComputations(r Ray, xs Intersections) Computations
 	comps.Point = *r.Position(comps.T)
 	comps.Eyev = *r.Direction.Negate()
 	comps.Normalv = NormalAt(comps.Object, comps.Point)
+	if m := comps.Object.GetMaterial(); m.NormalMap != nil || m.BumpMap != nil {
+		comps.Normalv = m.MappedNormalAt(comps.Object, comps.Point)
+	}
+	if m := comps.Object.GetMaterial(); m.Bump != 0 {
+		comps.Normalv = m.PerturbNormal(comps.Object, comps.Point, comps.Normalv)
+	}
 
 	if comps.Normalv.Dot(&comps.Eyev) < 0 {
 		comps.Inside = true
@@ -129,9 +145,33 @@ func (i Intersection) PrepareComputations(r Ray, xs Intersections) Computations
 
 	comps.Reflectv = r.Direction.Reflect(comps.Normalv)
 
+	if d := r.Differentials; d != nil {
+		comps.Differentials = d
+		comps.Dpdx = tangentPlaneOffset(comps.Point, comps.Normalv, d.XOrigin, d.XDirection)
+		comps.Dpdy = tangentPlaneOffset(comps.Point, comps.Normalv, d.YOrigin, d.YDirection)
+	}
+
 	return comps
 }
 
+// IndicesAt returns the refractive indices on either side of the intersection for light of the given wavelength
+func (c Computations) IndicesAt(wavelength float64) (n1, n2 float64) {
+	n1, n2 = 1.0, 1.0
+	if c.Exited != nil {
+		n1 = c.Exited.GetMaterial().RefractiveIndexAt(wavelength)
+	}
+	if c.Entered != nil {
+		n2 = c.Entered.GetMaterial().RefractiveIndexAt(wavelength)
+	}
+	return n1, n2
+}
+
+// dispersive reports whether either side of the intersection splits white light into its wavelengths
+func (c Computations) dispersive() bool {
+	return (c.Exited != nil && c.Exited.GetMaterial().Dispersion.Model != NoDispersion) ||
+		(c.Entered != nil && c.Entered.GetMaterial().Dispersion.Model != NoDispersion)
+}
+
 func Schlick(comps Computations) float64 {
 	// find the cosine of the angle between the eye and normal vectors
 	cos := comps.Eyev.Dot(&comps.Normalv)
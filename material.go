@@ -1,9 +1,5 @@
 package jtracer
 
-import (
-	"math"
-)
-
 type Material struct {
 	Color           Color
 	Ambient         float64
@@ -15,6 +11,16 @@ type Material struct {
 	Reflectivity    float64
 	Transparency    float64
 	RefractiveIndex float64
+	Dispersion      Dispersion
+	Shading         ShadingModel // the illumination model, Phong when nil
+	Metallic        float64      // 0 for dielectrics, 1 for metals
+	Roughness       float64      // microfacet roughness from 0 (polished) to 1 (matte), also blurs reflections and refractions
+	GlossySamples   int          // rays averaged for blurred reflections and refractions, GlossySamples when zero
+	Conductor       ComplexIOR
+	Bump            float64 // strength of the noise perturbing the surface normal
+	BumpFrequency   float64 // scale of the bump noise in object space, 1 when zero
+	NormalMap       *NormalMap
+	BumpMap         *BumpMap
 }
 
 func NewMaterial() Material {
@@ -37,49 +43,29 @@ func (m Material) Lighting(object Shape, light Light, point, eyev, normalv Tuple
 		color = m.Color
 	}
 
-	// combine the surface color with the light's color/intensity
-	effectiveColor := color.Multiply(&light.Intensity)
-
-	// find the direction to the light source
-	lightv := light.Position.Subtract(&point).Normalize()
-
-	// compute the ambient contribution
-	ambient := effectiveColor.MultiplyByScalar(m.Ambient)
+	shading := m.Shading
+	if shading == nil {
+		shading = PhongShading{}
+	}
 
-	// light_dot_normal represents the cosine of the angle between the
-	// light vector and the normal vector. A negative number means the
-	// light is on the other side of the surface.
+	return shading.Shade(m, color, light, point, eyev, normalv, inShadow)
+}
 
-	var diffuse, specular Color
-	lightDotNormal := lightv.Dot(&normalv)
-	if lightDotNormal < 0 || inShadow {
-		diffuse = Black
-		specular = Black
-	} else {
-		diffuse = *effectiveColor.MultiplyByScalar(m.Diffuse)
-		diffuse = *diffuse.MultiplyByScalar(lightDotNormal)
+// PerturbNormal tilts a world space normal by the gradient of noise sampled at the object space point, giving the
+// surface a bumpy look without changing its geometry
+func (m Material) PerturbNormal(object Shape, worldPoint, normalv Tuple) Tuple {
+	frequency := m.BumpFrequency
+	if frequency == 0 {
+		frequency = 1
+	}
 
-		//  reflect_dot_eye represents the cosine of the angle between the
-		//  reflection vector and the eye vector. A negative number means the
-		//  light reflects away from the eye.
-		reflectV := lightv.Multiply(-1).Reflect(normalv)
-		reflectDotEye := reflectV.Dot(&eyev)
+	objectPoint := object.GetInverse().MultiplyByTuple(worldPoint)
+	gradient := DefaultNoise.Gradient(*objectPoint.Multiply(frequency), 1)
+	gradient = *object.GetInverseTranspose().MultiplyByTuple(gradient)
+	gradient.W = 0
 
-		if reflectDotEye <= 0 {
-			specular = Black
-		} else {
-			// compute the specular contribution
-			factor := math.Pow(reflectDotEye, m.Shininess)
-			specular = light.Intensity
-			specular = *specular.MultiplyByScalar(m.Specular)
-			specular = *specular.MultiplyByScalar(factor)
-		}
-	}
-	//
-	//spew.Dump("diffuse", diffuse)
-	//spew.Dump("spec", specular)
+	// only the part of the gradient along the surface tilts the normal
+	tangential := gradient.Subtract(normalv.Multiply(gradient.Dot(&normalv)))
 
-	c := ambient.Add(&diffuse)
-	c = c.Add(&specular)
-	return *c
+	return *normalv.Subtract(tangential.Multiply(m.Bump)).Normalize()
 }
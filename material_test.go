@@ -231,3 +231,22 @@ func TestMaterial_Lighting(t *testing.T) {
 		})
 	}
 }
+
+func TestMaterial_PerturbNormal(t *testing.T) {
+	s := NewSphere()
+	s.Material.Bump = 0.5
+
+	point := *NewPoint(0.3, 0.4, -math.Sqrt(0.75))
+	normal := NormalAt(s, point)
+	got := s.Material.PerturbNormal(s, point, normal)
+
+	if !floatEquals(got.Magnitude(), 1) {
+		t.Errorf("PerturbNormal() = %v, want a unit vector", got)
+	}
+	if got.Equals(&normal) {
+		t.Errorf("PerturbNormal() = %v, want it to differ from %v", got, normal)
+	}
+	if got.Dot(&normal) <= 0 {
+		t.Errorf("PerturbNormal() = %v, want it on the same side of the surface as %v", got, normal)
+	}
+}
@@ -0,0 +1,79 @@
+package jtracer
+
+import "math"
+
+// minRoughness keeps the GGX distribution finite for perfectly smooth surfaces
+const minRoughness = 0.04
+
+// dielectricF0 is the reflectance at normal incidence shared by most non-metals
+const dielectricF0 = 0.04
+
+// MicrofacetShading is a physically based model: the Cook-Torrance BRDF using the GGX normal distribution, the
+// Smith geometry term and Fresnel-Schlick. The surface color is used as the base color; the material's Metallic
+// blends between a dielectric and a conductor and its Roughness widens the specular lobe.
+type MicrofacetShading struct{}
+
+func (MicrofacetShading) Shade(m Material, baseColor Color, light Light, point, eyev, normalv Tuple, inShadow bool) Color {
+	ambient := baseColor.Multiply(&light.Intensity).MultiplyByScalar(m.Ambient)
+
+	lightv := light.Position.Subtract(&point).Normalize()
+	nDotL := normalv.Dot(lightv)
+	nDotV := normalv.Dot(&eyev)
+	if nDotL <= 0 || nDotV <= 0 || inShadow {
+		return *ambient
+	}
+
+	halfv := lightv.Add(&eyev).Normalize()
+	nDotH := math.Max(normalv.Dot(halfv), 0)
+	vDotH := math.Max(eyev.Dot(halfv), 0)
+
+	roughness := math.Max(m.Roughness, minRoughness)
+
+	// reflectance at normal incidence: dielectrics reflect a little white light, metals reflect their own color
+	f0 := Color{dielectricF0, dielectricF0, dielectricF0}
+	f0 = *f0.MultiplyByScalar(1 - m.Metallic).Add(baseColor.MultiplyByScalar(m.Metallic))
+	fresnel := FresnelSchlick(f0, vDotH)
+
+	specularFactor := GGXDistribution(nDotH, roughness) * SmithGeometry(nDotV, nDotL, roughness) / (4 * nDotV * nDotL)
+	specular := fresnel.MultiplyByScalar(specularFactor)
+
+	// light that is not reflected at the surface is scattered diffusely, unless the surface is a metal
+	kd := White.Subtract(&fresnel).MultiplyByScalar(1 - m.Metallic)
+	diffuse := kd.Multiply(&baseColor).MultiplyByScalar(1 / math.Pi)
+
+	// a point light of intensity 1 delivers an irradiance of π, so that a white lambertian surface facing the light
+	// has a brightness of 1 just as it does under the Phong model
+	radiance := light.Intensity.MultiplyByScalar(math.Pi * nDotL)
+	direct := diffuse.Add(specular).Multiply(radiance)
+
+	return *ambient.Add(direct)
+}
+
+// GGXDistribution is the Trowbridge-Reitz normal distribution function: the share of microfacets oriented along
+// the half vector
+func GGXDistribution(nDotH, roughness float64) float64 {
+	a := roughness * roughness
+	a2 := a * a
+	d := nDotH*nDotH*(a2-1) + 1
+	return a2 / (math.Pi * d * d)
+}
+
+// SmithGeometry approximates the share of microfacets that are neither shadowed nor masked, using the
+// Schlick-GGX form of the Smith function for both the view and light directions
+func SmithGeometry(nDotV, nDotL, roughness float64) float64 {
+	k := (roughness + 1) * (roughness + 1) / 8
+	g1 := func(nDotX float64) float64 {
+		return nDotX / (nDotX*(1-k) + k)
+	}
+	return g1(nDotV) * g1(nDotL)
+}
+
+// FresnelSchlick approximates the reflectance of a surface with the reflectance f0 at normal incidence
+func FresnelSchlick(f0 Color, cosTheta float64) Color {
+	factor := math.Pow(1-cosTheta, 5)
+	return Color{
+		f0.Red + (1-f0.Red)*factor,
+		f0.Green + (1-f0.Green)*factor,
+		f0.Blue + (1-f0.Blue)*factor,
+	}
+}
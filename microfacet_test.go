@@ -0,0 +1,116 @@
+package jtracer
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"math"
+	"testing"
+)
+
+func TestGGXDistribution(t *testing.T) {
+	tests := []struct {
+		name      string
+		nDotH     float64
+		roughness float64
+		want      float64
+	}{
+		{
+			name:      "a fully rough surface spreads microfacets evenly",
+			nDotH:     1,
+			roughness: 1,
+			want:      1 / math.Pi,
+		},
+		{
+			name:      "a fully rough surface spreads microfacets evenly at an angle",
+			nDotH:     0.5,
+			roughness: 1,
+			want:      1 / math.Pi,
+		},
+		{
+			name:      "a smooth surface concentrates microfacets along the normal",
+			nDotH:     1,
+			roughness: 0.5,
+			want:      16 / math.Pi,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GGXDistribution(tt.nDotH, tt.roughness); !cmp.Equal(got, tt.want, float64Comparer) {
+				t.Errorf("GGXDistribution() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSmithGeometry(t *testing.T) {
+	if got := SmithGeometry(1, 1, 0.5); !cmp.Equal(got, 1.0, float64Comparer) {
+		t.Errorf("SmithGeometry() = %v, want 1", got)
+	}
+
+	if grazing, head := SmithGeometry(0.1, 1, 0.5), SmithGeometry(0.9, 1, 0.5); grazing >= head {
+		t.Errorf("SmithGeometry() grazing = %v, head on = %v, want more masking at grazing angles", grazing, head)
+	}
+}
+
+func TestFresnelSchlick(t *testing.T) {
+	f0 := Color{0.04, 0.04, 0.04}
+
+	if got := FresnelSchlick(f0, 1); !got.Equals(&f0) {
+		t.Errorf("FresnelSchlick() at normal incidence = %v, want %v", got, f0)
+	}
+
+	if got := FresnelSchlick(f0, 0); !got.Equals(&White) {
+		t.Errorf("FresnelSchlick() at grazing incidence = %v, want %v", got, White)
+	}
+}
+
+func TestMaterial_MicrofacetLighting(t *testing.T) {
+	light := NewPointLight(*NewPoint(0, 0, -10), White)
+
+	tests := []struct {
+		name     string
+		material Material
+		inShadow bool
+		want     Color
+	}{
+		{
+			name: "a rough dielectric lit head on",
+			material: Material{
+				Color:     White,
+				Ambient:   0.1,
+				Shading:   MicrofacetShading{},
+				Roughness: 1,
+			},
+			want: Color{1.07, 1.07, 1.07},
+		},
+		{
+			name: "a rough metal only reflects its own color",
+			material: Material{
+				Color:     Red,
+				Ambient:   0.1,
+				Shading:   MicrofacetShading{},
+				Metallic:  1,
+				Roughness: 1,
+			},
+			want: Color{0.35, 0, 0},
+		},
+		{
+			name: "a surface in shadow only receives ambient light",
+			material: Material{
+				Color:     White,
+				Ambient:   0.1,
+				Shading:   MicrofacetShading{},
+				Roughness: 1,
+			},
+			inShadow: true,
+			want:     Color{0.1, 0.1, 0.1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.material.Lighting(NewSphere(), light, *NewPoint(0, 0, 0), *NewVector(0, 0, -1), *NewVector(0, 0, -1), tt.inShadow)
+			if !got.Equals(&tt.want) {
+				t.Errorf("Lighting() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
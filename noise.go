@@ -0,0 +1,187 @@
+package jtracer
+
+import (
+	"math"
+	"math/rand"
+)
+
+// referencePermutation is the permutation table from Ken Perlin's reference implementation of improved noise
+var referencePermutation = [256]int{
+	151, 160, 137, 91, 90, 15, 131, 13, 201, 95, 96, 53, 194, 233, 7, 225,
+	140, 36, 103, 30, 69, 142, 8, 99, 37, 240, 21, 10, 23, 190, 6, 148,
+	247, 120, 234, 75, 0, 26, 197, 62, 94, 252, 219, 203, 117, 35, 11, 32,
+	57, 177, 33, 88, 237, 149, 56, 87, 174, 20, 125, 136, 171, 168, 68, 175,
+	74, 165, 71, 134, 139, 48, 27, 166, 77, 146, 158, 231, 83, 111, 229, 122,
+	60, 211, 133, 230, 220, 105, 92, 41, 55, 46, 245, 40, 244, 102, 143, 54,
+	65, 25, 63, 161, 1, 216, 80, 73, 209, 76, 132, 187, 208, 89, 18, 169,
+	200, 196, 135, 130, 116, 188, 159, 86, 164, 100, 109, 198, 173, 186, 3, 64,
+	52, 217, 226, 250, 124, 123, 5, 202, 38, 147, 118, 126, 255, 82, 85, 212,
+	207, 206, 59, 227, 47, 16, 58, 17, 182, 189, 28, 42, 223, 183, 170, 213,
+	119, 248, 152, 2, 44, 154, 163, 70, 221, 153, 101, 155, 167, 43, 172, 9,
+	129, 22, 39, 253, 19, 98, 108, 110, 79, 113, 224, 232, 178, 185, 112, 104,
+	218, 246, 97, 228, 251, 34, 242, 193, 238, 210, 144, 12, 191, 179, 162, 241,
+	81, 51, 145, 235, 249, 14, 239, 107, 49, 192, 214, 31, 181, 199, 106, 157,
+	184, 84, 204, 176, 115, 121, 50, 45, 127, 4, 150, 254, 138, 236, 205, 93,
+	222, 114, 67, 29, 24, 72, 243, 141, 128, 195, 78, 66, 215, 61, 156, 180,
+}
+
+// Perlin generates Ken Perlin's improved gradient noise
+type Perlin struct {
+	Seed int64 // the seed the permutation was shuffled with
+	perm [512]int
+}
+
+// NewPerlin returns a noise generator. A seed of zero uses the reference permutation; any other seed shuffles it,
+// giving a different but repeatable noise field.
+func NewPerlin(seed int64) *Perlin {
+	p := &Perlin{Seed: seed}
+
+	table := referencePermutation
+	if seed != 0 {
+		r := rand.New(rand.NewSource(seed))
+		r.Shuffle(len(table), func(i, j int) {
+			table[i], table[j] = table[j], table[i]
+		})
+	}
+
+	for i := 0; i < 512; i++ {
+		p.perm[i] = table[i%256]
+	}
+
+	return p
+}
+
+// DefaultNoise is the noise field shared by patterns and materials that don't ask for a seed of their own
+var DefaultNoise = NewPerlin(0)
+
+// Noise returns the gradient noise of DefaultNoise at a point, in the range [-1, 1]
+func Noise(x, y, z float64) float64 {
+	return DefaultNoise.Noise(x, y, z)
+}
+
+// Noise returns the gradient noise at a point, in the range [-1, 1]. It is zero at every integer lattice point.
+func (p *Perlin) Noise(x, y, z float64) float64 {
+	// find the unit cube that contains the point
+	xi := int(math.Floor(x)) & 255
+	yi := int(math.Floor(y)) & 255
+	zi := int(math.Floor(z)) & 255
+
+	// find the relative position of the point in the cube
+	x -= math.Floor(x)
+	y -= math.Floor(y)
+	z -= math.Floor(z)
+
+	u := fade(x)
+	v := fade(y)
+	w := fade(z)
+
+	// hash the coordinates of the 8 cube corners
+	a := p.perm[xi] + yi
+	aa := p.perm[a] + zi
+	ab := p.perm[a+1] + zi
+	b := p.perm[xi+1] + yi
+	ba := p.perm[b] + zi
+	bb := p.perm[b+1] + zi
+
+	// and blend the gradients from the corners
+	return lerp(w,
+		lerp(v,
+			lerp(u, grad(p.perm[aa], x, y, z), grad(p.perm[ba], x-1, y, z)),
+			lerp(u, grad(p.perm[ab], x, y-1, z), grad(p.perm[bb], x-1, y-1, z))),
+		lerp(v,
+			lerp(u, grad(p.perm[aa+1], x, y, z-1), grad(p.perm[ba+1], x-1, y, z-1)),
+			lerp(u, grad(p.perm[ab+1], x, y-1, z-1), grad(p.perm[bb+1], x-1, y-1, z-1))))
+}
+
+// Fractal sums octaves of noise, each at twice the frequency and half the amplitude of the one before. The result
+// is normalized back into the range [-1, 1].
+func (p *Perlin) Fractal(point Tuple, octaves int) float64 {
+	if octaves < 1 {
+		octaves = 1
+	}
+
+	var total, norm float64
+	frequency, amplitude := 1.0, 1.0
+	for i := 0; i < octaves; i++ {
+		total += p.Noise(point.X*frequency, point.Y*frequency, point.Z*frequency) * amplitude
+		norm += amplitude
+		frequency *= 2
+		amplitude /= 2
+	}
+
+	return total / norm
+}
+
+// Turbulence is like Fractal but sums the absolute value of each octave, giving sharp creases where the noise
+// crosses zero. The result lies in the range [0, 1].
+func (p *Perlin) Turbulence(point Tuple, octaves int) float64 {
+	if octaves < 1 {
+		octaves = 1
+	}
+
+	var total, norm float64
+	frequency, amplitude := 1.0, 1.0
+	for i := 0; i < octaves; i++ {
+		total += math.Abs(p.Noise(point.X*frequency, point.Y*frequency, point.Z*frequency)) * amplitude
+		norm += amplitude
+		frequency *= 2
+		amplitude /= 2
+	}
+
+	return total / norm
+}
+
+// Vector returns a vector of three decorrelated fractal noise values, useful for displacing points and normals
+func (p *Perlin) Vector(point Tuple, octaves int) Tuple {
+	return *NewVector(
+		p.Fractal(point, octaves),
+		p.Fractal(*point.Add(NewVector(31.41, 17.23, 5.97)), octaves),
+		p.Fractal(*point.Add(NewVector(-11.73, 47.13, 23.57)), octaves),
+	)
+}
+
+// Gradient estimates the gradient of the fractal noise at a point with central differences
+func (p *Perlin) Gradient(point Tuple, octaves int) Tuple {
+	const h = 0.001
+	dx := p.Fractal(*point.Add(NewVector(h, 0, 0)), octaves) - p.Fractal(*point.Subtract(NewVector(h, 0, 0)), octaves)
+	dy := p.Fractal(*point.Add(NewVector(0, h, 0)), octaves) - p.Fractal(*point.Subtract(NewVector(0, h, 0)), octaves)
+	dz := p.Fractal(*point.Add(NewVector(0, 0, h)), octaves) - p.Fractal(*point.Subtract(NewVector(0, 0, h)), octaves)
+	return *NewVector(dx/(2*h), dy/(2*h), dz/(2*h))
+}
+
+// fade smooths the interpolation weights so that the noise has continuous second derivatives: 6t^5 - 15t^4 + 10t^3
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+func lerp(t, a, b float64) float64 {
+	return a + t*(b-a)
+}
+
+// grad converts the low 4 bits of the hash into one of 12 gradient directions and returns its dot product with
+// the distance vector
+func grad(hash int, x, y, z float64) float64 {
+	h := hash & 15
+	u := y
+	if h < 8 {
+		u = x
+	}
+
+	var v float64
+	switch {
+	case h < 4:
+		v = y
+	case h == 12 || h == 14:
+		v = x
+	default:
+		v = z
+	}
+
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+	return u + v
+}
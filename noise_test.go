@@ -0,0 +1,77 @@
+package jtracer
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestPerlin_Noise(t *testing.T) {
+	tests := []struct {
+		name  string
+		point Tuple
+		want  float64
+	}{
+		{
+			name:  "noise is zero at the origin",
+			point: *NewPoint(0, 0, 0),
+			want:  0,
+		},
+		{
+			name:  "noise is zero at every lattice point",
+			point: *NewPoint(3, -7, 12),
+			want:  0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Noise(tt.point.X, tt.point.Y, tt.point.Z); !floatEquals(got, tt.want) {
+				t.Errorf("Noise() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerlin_NoiseRange(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	seeded := NewPerlin(42)
+
+	var differs bool
+	for i := 0; i < 1000; i++ {
+		p := *NewPoint(r.Float64()*100-50, r.Float64()*100-50, r.Float64()*100-50)
+
+		n := DefaultNoise.Noise(p.X, p.Y, p.Z)
+		if n < -1 || n > 1 {
+			t.Fatalf("Noise(%v) = %v, want a value in [-1, 1]", p, n)
+		}
+		if n != DefaultNoise.Noise(p.X, p.Y, p.Z) {
+			t.Fatalf("Noise(%v) is not repeatable", p)
+		}
+		if n != seeded.Noise(p.X, p.Y, p.Z) {
+			differs = true
+		}
+
+		if f := DefaultNoise.Fractal(p, 4); f < -1 || f > 1 {
+			t.Fatalf("Fractal(%v) = %v, want a value in [-1, 1]", p, f)
+		}
+		if turbulence := DefaultNoise.Turbulence(p, 4); turbulence < 0 || turbulence > 1 {
+			t.Fatalf("Turbulence(%v) = %v, want a value in [0, 1]", p, turbulence)
+		}
+	}
+
+	if !differs {
+		t.Errorf("NewPerlin(42) produced the same noise as the reference permutation")
+	}
+}
+
+func TestPerlin_Gradient(t *testing.T) {
+	// along a line the change in noise should match the gradient
+	p := *NewPoint(0.3, 1.7, -2.2)
+	step := *NewVector(0.0001, 0, 0)
+	g := DefaultNoise.Gradient(p, 1)
+
+	want := (DefaultNoise.Fractal(*p.Add(&step), 1) - DefaultNoise.Fractal(p, 1)) / step.X
+	if math.Abs(g.X-want) > 0.01 {
+		t.Errorf("Gradient().X = %v, want %v", g.X, want)
+	}
+}
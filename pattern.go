@@ -37,8 +37,10 @@ func (s *AbstractPattern) GetInverseTranspose() Matrix {
 }
 
 type StripePattern struct {
-	A Color
-	B Color
+	A        Color
+	B        Color
+	PatternA Pattern // when set, used in place of A
+	PatternB Pattern // when set, used in place of B
 	AbstractPattern
 }
 
@@ -46,6 +48,12 @@ func PatternAtShape(patterny Pattern, shape Shape, worldPoint Tuple) Color {
 	objectPoint := shape.GetInverse().MultiplyByTuple(worldPoint)
 	patternPoint := patterny.GetInverse().MultiplyByTuple(*objectPoint)
 
+	if sp, ok := patterny.(SurfacePattern); ok {
+		patternNormal := patterny.GetInverseTranspose().MultiplyByTuple(shape.LocalNormalAt(*objectPoint))
+		patternNormal.W = 0
+		return sp.ColorAtSurface(*patternPoint, *patternNormal.Normalize())
+	}
+
 	return patterny.ColorAt(*patternPoint)
 }
 
@@ -78,15 +86,17 @@ func NewStripePattern(a Color, b Color) *StripePattern {
 
 func (s *StripePattern) ColorAt(p Tuple) Color {
 	if int(math.Floor(p.X))%2 == 0 {
-		return s.A
+		return nestedColor(s.A, s.PatternA, p)
 	}
 
-	return s.B
+	return nestedColor(s.B, s.PatternB, p)
 }
 
 type CheckersPattern struct {
-	A Color
-	B Color
+	A        Color
+	B        Color
+	PatternA Pattern // when set, used in place of A
+	PatternB Pattern // when set, used in place of B
 	AbstractPattern
 }
 
@@ -98,8 +108,166 @@ func NewCheckersPattern(a Color, b Color) CheckersPattern {
 
 func (s *CheckersPattern) ColorAt(p Tuple) Color {
 	if (int(math.Floor(p.X))+int(math.Floor(p.Y))+int(math.Floor(p.Z)))%2 == 0 {
-		return s.A
+		return nestedColor(s.A, s.PatternA, p)
+	}
+
+	return nestedColor(s.B, s.PatternB, p)
+}
+
+// GradientPattern blends linearly from A to B along x, repeating every unit
+type GradientPattern struct {
+	A        Color
+	B        Color
+	PatternA Pattern // when set, used in place of A
+	PatternB Pattern // when set, used in place of B
+	AbstractPattern
+}
+
+func NewGradientPattern(a Color, b Color) *GradientPattern {
+	p := &GradientPattern{A: a, B: b}
+	p.SetTransform(IdentityMatrix)
+	return p
+}
+
+func (s *GradientPattern) ColorAt(p Tuple) Color {
+	return blend(nestedColor(s.A, s.PatternA, p), nestedColor(s.B, s.PatternB, p), p.X-math.Floor(p.X))
+}
+
+// RingPattern alternates between A and B in concentric rings around the y axis
+type RingPattern struct {
+	A        Color
+	B        Color
+	PatternA Pattern // when set, used in place of A
+	PatternB Pattern // when set, used in place of B
+	AbstractPattern
+}
+
+func NewRingPattern(a Color, b Color) *RingPattern {
+	p := &RingPattern{A: a, B: b}
+	p.SetTransform(IdentityMatrix)
+	return p
+}
+
+func (s *RingPattern) ColorAt(p Tuple) Color {
+	if int(math.Floor(math.Sqrt(p.X*p.X+p.Z*p.Z)))%2 == 0 {
+		return nestedColor(s.A, s.PatternA, p)
+	}
+
+	return nestedColor(s.B, s.PatternB, p)
+}
+
+// RadialGradientPattern blends from A to B with the distance from the y axis, repeating every unit
+type RadialGradientPattern struct {
+	A        Color
+	B        Color
+	PatternA Pattern // when set, used in place of A
+	PatternB Pattern // when set, used in place of B
+	AbstractPattern
+}
+
+func NewRadialGradientPattern(a Color, b Color) *RadialGradientPattern {
+	p := &RadialGradientPattern{A: a, B: b}
+	p.SetTransform(IdentityMatrix)
+	return p
+}
+
+func (s *RadialGradientPattern) ColorAt(p Tuple) Color {
+	distance := math.Sqrt(p.X*p.X + p.Z*p.Z)
+	return blend(nestedColor(s.A, s.PatternA, p), nestedColor(s.B, s.PatternB, p), distance-math.Floor(distance))
+}
+
+// SolidPattern is a single color everywhere in space
+type SolidPattern struct {
+	Color Color
+	AbstractPattern
+}
+
+func NewSolidPattern(c Color) *SolidPattern {
+	p := &SolidPattern{Color: c}
+	p.SetTransform(IdentityMatrix)
+	return p
+}
+
+func (s *SolidPattern) ColorAt(_ Tuple) Color {
+	return s.Color
+}
+
+// blend returns the color a fraction t of the way from a to b
+func blend(a, b Color, t float64) Color {
+	distance := b.Subtract(&a)
+	return *a.Add(distance.MultiplyByScalar(t))
+}
+
+// PatternAt returns the color of a pattern nested inside another, where point is in the space of the outer pattern
+func PatternAt(p Pattern, point Tuple) Color {
+	return p.ColorAt(*p.GetInverse().MultiplyByTuple(point))
+}
+
+// nestedColor returns the color of a nested pattern at a point in the space of the pattern containing it, or c when
+// there is no nested pattern
+func nestedColor(c Color, nested Pattern, point Tuple) Color {
+	if nested == nil {
+		return c
 	}
+	return PatternAt(nested, point)
+}
+
+// BlendedPattern averages two patterns. Weight is the share of B, so 0 shows only A and 1 only B.
+type BlendedPattern struct {
+	A      Pattern
+	B      Pattern
+	Weight float64
+	AbstractPattern
+}
+
+func NewBlendedPattern(a, b Pattern) *BlendedPattern {
+	p := &BlendedPattern{A: a, B: b, Weight: 0.5}
+	p.SetTransform(IdentityMatrix)
+	return p
+}
+
+func (s *BlendedPattern) ColorAt(p Tuple) Color {
+	return blend(PatternAt(s.A, p), PatternAt(s.B, p), s.Weight)
+}
+
+// MaskPattern mixes two patterns using the brightness of a third: where Mask is black only A shows, where it is
+// white only B.
+type MaskPattern struct {
+	A    Pattern
+	B    Pattern
+	Mask Pattern
+	AbstractPattern
+}
+
+func NewMaskPattern(a, b, mask Pattern) *MaskPattern {
+	p := &MaskPattern{A: a, B: b, Mask: mask}
+	p.SetTransform(IdentityMatrix)
+	return p
+}
+
+func (s *MaskPattern) ColorAt(p Tuple) Color {
+	mask := PatternAt(s.Mask, p)
+	t := mask.Luminance()
+	return blend(PatternAt(s.A, p), PatternAt(s.B, p), math.Min(math.Max(t, 0), 1))
+}
+
+// PerturbedPattern jitters the points passed to another pattern with gradient noise, breaking up its regularity
+type PerturbedPattern struct {
+	Pattern   Pattern
+	Scale     float64 // how far points are displaced
+	Frequency float64 // how quickly the displacement changes, 1 when zero
+	Octaves   int     // octaves of noise summed, 1 when zero
+	Noise     *Perlin // DefaultNoise when nil
+	AbstractPattern
+}
+
+func NewPerturbedPattern(p Pattern, scale float64) *PerturbedPattern {
+	pp := &PerturbedPattern{Pattern: p, Scale: scale, Frequency: 1, Octaves: 1}
+	pp.SetTransform(IdentityMatrix)
+	return pp
+}
 
-	return s.B
+func (p *PerturbedPattern) ColorAt(point Tuple) Color {
+	offset := noiseOrDefault(p.Noise).Vector(*point.Multiply(nonZero(p.Frequency, 1)), p.Octaves)
+	return PatternAt(p.Pattern, *point.Add(offset.Multiply(p.Scale)))
 }
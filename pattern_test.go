@@ -342,3 +342,259 @@ func TestPatternAtShape(t *testing.T) {
 		})
 	}
 }
+
+func TestPerturbedPattern_ColorAt(t *testing.T) {
+	stripes := NewStripePattern(White, Black)
+
+	unperturbed := NewPerturbedPattern(stripes, 0)
+	perturbed := NewPerturbedPattern(stripes, 0.5)
+
+	var differs bool
+	for x := 0.05; x < 4; x += 0.1 {
+		p := *NewPoint(x, 0.37, 0.71)
+		if got, want := unperturbed.ColorAt(p), stripes.ColorAt(p); !reflect.DeepEqual(got, want) {
+			t.Errorf("ColorAt(%v) with no perturbation = %v, want %v", p, got, want)
+		}
+		if !reflect.DeepEqual(perturbed.ColorAt(p), stripes.ColorAt(p)) {
+			differs = true
+		}
+	}
+
+	if !differs {
+		t.Errorf("ColorAt() with a perturbation never moved a stripe boundary")
+	}
+}
+
+func TestPatternAt(t *testing.T) {
+	p := NewTestPatternWithTransform(Scaling(2, 2, 2))
+	want := Color{1, 1.5, 2}
+	if got := PatternAt(p, Tuple{2, 3, 4, 1}); !reflect.DeepEqual(got, want) {
+		t.Errorf("PatternAt() = %v, want %v", got, want)
+	}
+}
+
+func TestGradientPattern_ColorAt(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Tuple
+		want Color
+	}{
+		{
+			name: "a gradient linearly interpolates between colors",
+			p:    *NewPoint(0, 0, 0),
+			want: White,
+		},
+		{
+			name: "a gradient linearly interpolates between colors",
+			p:    *NewPoint(0.25, 0, 0),
+			want: Color{0.75, 0.75, 0.75},
+		},
+		{
+			name: "a gradient linearly interpolates between colors",
+			p:    *NewPoint(0.5, 0, 0),
+			want: Color{0.5, 0.5, 0.5},
+		},
+		{
+			name: "a gradient linearly interpolates between colors",
+			p:    *NewPoint(0.75, 0, 0),
+			want: Color{0.25, 0.25, 0.25},
+		},
+		{
+			name: "a gradient repeats every unit",
+			p:    *NewPoint(1.25, 0, 0),
+			want: Color{0.75, 0.75, 0.75},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewGradientPattern(White, Black)
+			if got := s.ColorAt(tt.p); !got.Equals(&tt.want) {
+				t.Errorf("ColorAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRingPattern_ColorAt(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Tuple
+		want Color
+	}{
+		{
+			name: "a ring should extend in both x and z",
+			p:    *NewPoint(0, 0, 0),
+			want: White,
+		},
+		{
+			name: "a ring should extend in both x and z",
+			p:    *NewPoint(1, 0, 0),
+			want: Black,
+		},
+		{
+			name: "a ring should extend in both x and z",
+			p:    *NewPoint(0, 0, 1),
+			want: Black,
+		},
+		{
+			name: "a ring should extend in both x and z",
+			p:    *NewPoint(0.708, 0, 0.708),
+			want: Black,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewRingPattern(White, Black)
+			if got := s.ColorAt(tt.p); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ColorAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRadialGradientPattern_ColorAt(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Tuple
+		want Color
+	}{
+		{
+			name: "a radial gradient starts at the y axis",
+			p:    *NewPoint(0, 5, 0),
+			want: White,
+		},
+		{
+			name: "a radial gradient blends with the distance from the y axis",
+			p:    *NewPoint(0.3, 0, 0.4),
+			want: Color{0.5, 0.5, 0.5},
+		},
+		{
+			name: "a radial gradient repeats every unit",
+			p:    *NewPoint(0, 0, -1.25),
+			want: Color{0.75, 0.75, 0.75},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewRadialGradientPattern(White, Black)
+			if got := s.ColorAt(tt.p); !got.Equals(&tt.want) {
+				t.Errorf("ColorAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSolidPattern_ColorAt(t *testing.T) {
+	s := NewSolidPattern(Red)
+	for _, p := range []Tuple{*NewPoint(0, 0, 0), *NewPoint(-3.5, 2, 100)} {
+		if got := s.ColorAt(p); !reflect.DeepEqual(got, Red) {
+			t.Errorf("ColorAt(%v) = %v, want %v", p, got, Red)
+		}
+	}
+}
+
+func TestNestedPatterns(t *testing.T) {
+	// checkers whose first squares hold narrow stripes
+	stripes := NewStripePattern(White, Black)
+	stripes.SetTransform(Scaling(0.25, 1, 1))
+	checkers := NewCheckersPattern(Red, Red)
+	checkers.PatternA = stripes
+
+	tests := []struct {
+		name string
+		p    Tuple
+		want Color
+	}{
+		{
+			name: "a nested pattern fills the first color slot",
+			p:    *NewPoint(0.1, 0, 0.5),
+			want: White,
+		},
+		{
+			name: "a nested pattern has its own transform",
+			p:    *NewPoint(0.3, 0, 0.5),
+			want: Black,
+		},
+		{
+			name: "the other color slot is unaffected",
+			p:    *NewPoint(1.1, 0, 0.5),
+			want: Red,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkers.ColorAt(tt.p); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ColorAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlendedPattern_ColorAt(t *testing.T) {
+	a := NewStripePattern(White, Black)
+	b := NewStripePattern(White, Black)
+	b.SetTransform(Scaling(2, 1, 1))
+
+	tests := []struct {
+		name   string
+		weight float64
+		p      Tuple
+		want   Color
+	}{
+		{
+			name:   "both patterns agree",
+			weight: 0.5,
+			p:      *NewPoint(0.5, 0, 0),
+			want:   White,
+		},
+		{
+			name:   "the patterns are averaged",
+			weight: 0.5,
+			p:      *NewPoint(1.5, 0, 0),
+			want:   Color{0.5, 0.5, 0.5},
+		},
+		{
+			name:   "the weight favours the second pattern",
+			weight: 0.75,
+			p:      *NewPoint(1.5, 0, 0),
+			want:   Color{0.75, 0.75, 0.75},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewBlendedPattern(a, b)
+			p.Weight = tt.weight
+			if got := p.ColorAt(tt.p); !got.Equals(&tt.want) {
+				t.Errorf("ColorAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskPattern_ColorAt(t *testing.T) {
+	p := NewMaskPattern(NewSolidPattern(Red), NewSolidPattern(Color{0, 0, 1}), NewGradientPattern(Black, White))
+
+	tests := []struct {
+		name string
+		p    Tuple
+		want Color
+	}{
+		{
+			name: "a black mask shows the first pattern",
+			p:    *NewPoint(0, 0, 0),
+			want: Red,
+		},
+		{
+			name: "a grey mask mixes the patterns",
+			p:    *NewPoint(0.5, 0, 0),
+			want: Color{0.5, 0, 0.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.ColorAt(tt.p); !got.Equals(&tt.want) {
+				t.Errorf("ColorAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
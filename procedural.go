@@ -0,0 +1,150 @@
+package jtracer
+
+import (
+	"math"
+)
+
+// MarblePattern bands A and B along x with a sine wave whose phase is disturbed by turbulence, giving veins
+type MarblePattern struct {
+	A          Color
+	B          Color
+	PatternA   Pattern // when set, used in place of A
+	PatternB   Pattern // when set, used in place of B
+	Frequency  float64 // veins per unit, 1 when zero
+	Turbulence float64 // how far the veins wander
+	Octaves    int     // octaves of turbulence summed, 1 when zero
+	Noise      *Perlin // DefaultNoise when nil
+	AbstractPattern
+}
+
+func NewMarblePattern(a Color, b Color) *MarblePattern {
+	p := &MarblePattern{A: a, B: b, Frequency: 1, Turbulence: 5, Octaves: 4}
+	p.SetTransform(IdentityMatrix)
+	return p
+}
+
+func (s *MarblePattern) ColorAt(p Tuple) Color {
+	frequency := nonZero(s.Frequency, 1)
+	turbulence := noiseOrDefault(s.Noise).Turbulence(*p.Multiply(frequency), s.Octaves)
+
+	t := 0.5 + 0.5*math.Sin((p.X*frequency+s.Turbulence*turbulence)*math.Pi)
+	return blend(nestedColor(s.A, s.PatternA, p), nestedColor(s.B, s.PatternB, p), t)
+}
+
+// WoodPattern forms growth rings around the y axis, distorted by noise so they aren't perfect circles
+type WoodPattern struct {
+	A          Color
+	B          Color
+	PatternA   Pattern // when set, used in place of A
+	PatternB   Pattern // when set, used in place of B
+	Frequency  float64 // rings per unit, 1 when zero
+	Turbulence float64 // how far the rings wander
+	Octaves    int     // octaves of noise summed, 1 when zero
+	Noise      *Perlin // DefaultNoise when nil
+	AbstractPattern
+}
+
+func NewWoodPattern(a Color, b Color) *WoodPattern {
+	p := &WoodPattern{A: a, B: b, Frequency: 4, Turbulence: 0.1, Octaves: 2}
+	p.SetTransform(IdentityMatrix)
+	return p
+}
+
+func (s *WoodPattern) ColorAt(p Tuple) Color {
+	frequency := nonZero(s.Frequency, 1)
+	distortion := noiseOrDefault(s.Noise).Fractal(p, s.Octaves)
+
+	rings := (math.Sqrt(p.X*p.X+p.Z*p.Z) + s.Turbulence*distortion) * frequency
+	t := rings - math.Floor(rings)
+	return blend(nestedColor(s.A, s.PatternA, p), nestedColor(s.B, s.PatternB, p), t)
+}
+
+// WorleyPattern is Steven Worley's cellular noise: space is split into cells around randomly scattered feature
+// points. The color blends from A at a feature point to B one cell away. With Edges set the blend follows the
+// distance to the border between neighbouring cells instead, outlining each cell in A.
+type WorleyPattern struct {
+	A         Color
+	B         Color
+	PatternA  Pattern // when set, used in place of A
+	PatternB  Pattern // when set, used in place of B
+	Frequency float64 // cells per unit, 1 when zero
+	Seed      int64
+	Edges     bool
+	AbstractPattern
+}
+
+func NewWorleyPattern(a Color, b Color) *WorleyPattern {
+	p := &WorleyPattern{A: a, B: b, Frequency: 1}
+	p.SetTransform(IdentityMatrix)
+	return p
+}
+
+func (s *WorleyPattern) ColorAt(p Tuple) Color {
+	f1, f2 := WorleyDistances(*p.Multiply(nonZero(s.Frequency, 1)), s.Seed)
+
+	t := f1
+	if s.Edges {
+		t = f2 - f1
+	}
+
+	return blend(nestedColor(s.A, s.PatternA, p), nestedColor(s.B, s.PatternB, p), math.Min(t, 1))
+}
+
+// WorleyDistances returns the distances from a point to the nearest and second nearest feature points, where each
+// unit cell holds a single feature point placed by hashing the cell's coordinates with the seed
+func WorleyDistances(p Tuple, seed int64) (f1, f2 float64) {
+	f1, f2 = math.MaxFloat64, math.MaxFloat64
+
+	cx, cy, cz := math.Floor(p.X), math.Floor(p.Y), math.Floor(p.Z)
+	for x := cx - 1; x <= cx+1; x++ {
+		for y := cy - 1; y <= cy+1; y++ {
+			for z := cz - 1; z <= cz+1; z++ {
+				h := cellHash(int64(x), int64(y), int64(z), seed)
+
+				// offset from the point to the cell's feature point; only x, y and z matter here
+				dx := x + hashToUnit(h) - p.X
+				dy := y + hashToUnit(h>>21) - p.Y
+				dz := z + hashToUnit(h>>42) - p.Z
+
+				d := math.Sqrt(dx*dx + dy*dy + dz*dz)
+				if d < f1 {
+					f1, f2 = d, f1
+				} else if d < f2 {
+					f2 = d
+				}
+			}
+		}
+	}
+
+	return f1, f2
+}
+
+// cellHash mixes integer cell coordinates and a seed into 64 well distributed bits
+func cellHash(x, y, z, seed int64) uint64 {
+	h := uint64(seed)*0x9E3779B97F4A7C15 ^ uint64(x)*0xBF58476D1CE4E5B9 ^ uint64(y)*0x94D049BB133111EB ^ uint64(z)*0xD6E8FEB86659FD93
+	h ^= h >> 30
+	h *= 0xBF58476D1CE4E5B9
+	h ^= h >> 27
+	h *= 0x94D049BB133111EB
+	h ^= h >> 31
+	return h
+}
+
+// hashToUnit maps the low 21 bits of a hash onto [0, 1)
+func hashToUnit(h uint64) float64 {
+	return float64(h&0x1FFFFF) / float64(0x200000)
+}
+
+func noiseOrDefault(p *Perlin) *Perlin {
+	if p == nil {
+		return DefaultNoise
+	}
+	return p
+}
+
+func nonZero(v, fallback float64) float64 {
+	if v == 0 {
+		return fallback
+	}
+	return v
+}
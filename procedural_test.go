@@ -0,0 +1,121 @@
+package jtracer
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestMarblePattern_ColorAt(t *testing.T) {
+	tests := []struct {
+		name       string
+		turbulence float64
+		p          Tuple
+		want       Color
+	}{
+		{
+			name: "without turbulence marble is a sine wave along x",
+			p:    *NewPoint(0, 0, 0),
+			want: Color{0.5, 0.5, 0.5},
+		},
+		{
+			name: "without turbulence marble is a sine wave along x",
+			p:    *NewPoint(0.5, 3, -2),
+			want: Black,
+		},
+		{
+			name: "without turbulence marble is a sine wave along x",
+			p:    *NewPoint(1.5, 0, 0),
+			want: White,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMarblePattern(White, Black)
+			s.Turbulence = tt.turbulence
+			if got := s.ColorAt(tt.p); !got.Equals(&tt.want) {
+				t.Errorf("ColorAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWoodPattern_ColorAt(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Tuple
+		want Color
+	}{
+		{
+			name: "without turbulence wood rings blend outwards from the y axis",
+			p:    *NewPoint(0, 7, 0),
+			want: White,
+		},
+		{
+			name: "without turbulence wood rings blend outwards from the y axis",
+			p:    *NewPoint(0.15, 0, 0.2),
+			want: Color{0.75, 0.75, 0.75},
+		},
+		{
+			name: "without turbulence wood rings blend outwards from the y axis",
+			p:    *NewPoint(0, -1, 1.5),
+			want: Color{0.5, 0.5, 0.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewWoodPattern(White, Black)
+			s.Frequency = 1
+			s.Turbulence = 0
+			if got := s.ColorAt(tt.p); !got.Equals(&tt.want) {
+				t.Errorf("ColorAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorleyDistances(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	var differs bool
+	for i := 0; i < 500; i++ {
+		p := *NewPoint(r.Float64()*20-10, r.Float64()*20-10, r.Float64()*20-10)
+		f1, f2 := WorleyDistances(p, 0)
+		if f1 > f2 {
+			t.Fatalf("WorleyDistances(%v) = %v, %v, want the nearest distance first", p, f1, f2)
+		}
+		if f1 > math.Sqrt(3) {
+			t.Fatalf("WorleyDistances(%v) = %v, want the nearest feature point within a cell", p, f1)
+		}
+		if g1, _ := WorleyDistances(p, 7); g1 != f1 {
+			differs = true
+		}
+	}
+
+	if !differs {
+		t.Errorf("WorleyDistances() gave the same cells for different seeds")
+	}
+}
+
+func TestWorleyPattern_ColorAt(t *testing.T) {
+	// the feature point of the cell at the origin
+	h := cellHash(0, 0, 0, 3)
+	feature := *NewPoint(hashToUnit(h), hashToUnit(h>>21), hashToUnit(h>>42))
+
+	s := NewWorleyPattern(White, Black)
+	s.Seed = 3
+	if got := s.ColorAt(feature); !got.Equals(&White) {
+		t.Errorf("ColorAt() at a feature point = %v, want %v", got, White)
+	}
+
+	s.Frequency = 2
+	if got := s.ColorAt(*feature.Multiply(0.5)); !got.Equals(&White) {
+		t.Errorf("ColorAt() at a feature point with a frequency of 2 = %v, want %v", got, White)
+	}
+
+	s.Frequency = 1
+	s.Edges = true
+	if got := s.ColorAt(feature); got.Equals(&White) {
+		t.Errorf("ColorAt() with edges at a feature point = %v, want it away from the cell border", got)
+	}
+}
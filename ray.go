@@ -2,10 +2,12 @@ package jtracer
 
 type Ray struct {
 	Origin, Direction *Tuple
+	Wavelength        float64           // wavelength in nanometres carried through dispersive materials, zero for white light
+	Differentials     *RayDifferentials // rays through the neighbouring pixels, nil when not followed
 }
 
 func NewRay(origin, direction *Tuple) Ray {
-	return Ray{origin, direction}
+	return Ray{Origin: origin, Direction: direction}
 }
 
 func (r *Ray) Position(t float64) *Tuple {
@@ -14,7 +16,9 @@ func (r *Ray) Position(t float64) *Tuple {
 
 func (r *Ray) Transform(m Matrix) Ray {
 	return Ray{
-		m.MultiplyByTuple(*r.Origin),
-		m.MultiplyByTuple(*r.Direction),
+		Origin:        m.MultiplyByTuple(*r.Origin),
+		Direction:     m.MultiplyByTuple(*r.Direction),
+		Wavelength:    r.Wavelength,
+		Differentials: r.Differentials.Transform(m),
 	}
 }
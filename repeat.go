@@ -0,0 +1,172 @@
+package jtracer
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A repeat entry adds the entries it holds a number of times over, with a name counting up from 0 that expressions
+// within them can use. Repeats nest, so a grid of spheres is:
+//
+//	- repeat: 4
+//	  as: row
+//	  entries:
+//	    - repeat: 4
+//	      as: column
+//	      entries:
+//	        - add: sphere
+//	          transform:
+//	            - [scale, 0.4, 0.4, 0.4]
+//	            - [translate, column - 1.5, 0.4, row * 1.5]
+//	          material:
+//	            color: [row / 3, 0.5, column / 3]
+//
+// The name defaults to i. The count may be an expression, and refer to numeric defines and the names of the repeats
+// it is within. Defines can't be made within a repeat.
+
+// maxRepeated limits how many entries repeats can make, so that a mistyped count fails rather than eating memory
+const maxRepeated = 100_000
+
+// expandRepeats replaces each repeat entry with a copy of its entries for every time round. It returns an error for
+// each malformed repeat.
+func expandRepeats(entries []sceneEntry, defs defines) ([]sceneEntry, []error) {
+	var expanded []sceneEntry
+	var errs []error
+	for _, entry := range entries {
+		if lookup(entry.node, "repeat") == nil {
+			expanded = append(expanded, entry)
+			continue
+		}
+
+		var made int
+		repeated, err := defs.repeat(entry, &made)
+		if err != nil {
+			errs = append(errs, entry.locate(err))
+			continue
+		}
+		expanded = append(expanded, repeated...)
+	}
+	return expanded, errs
+}
+
+// repeat returns the entries a repeat entry makes, once any repeats within it are expanded in turn, each knowing the
+// counters of the repeats it was made by. made counts the entries made so far, to hold them to maxRepeated, and a
+// time round that makes none counts as one, so that repeats of nothing can't go round without end.
+func (d defines) repeat(e sceneEntry, made *int) ([]sceneEntry, error) {
+	n := e.node
+	if err := checkRepeatKeys(n); err != nil {
+		return nil, err
+	}
+
+	countNode := lookup(n, "repeat")
+	count, err := scope{defs: d, counters: e.counters}.decodeInt(countNode, "repeat")
+	if err != nil {
+		return nil, err
+	}
+	if count < 0 {
+		return nil, invalid(countNode, "repeat", "expected a count of 0 or more, got %d", count)
+	}
+
+	name := repeatName(n)
+	if v := lookup(n, "as"); v != nil {
+		if _, err = decodeString(v, "as"); err != nil {
+			return nil, err
+		}
+		if tokens := expressionNames(name); len(tokens) != 1 || tokens[0].text != name {
+			return nil, invalid(v, "as", "expected a name, got %q", name)
+		}
+	}
+
+	body, err := require(n, "entries")
+	if err != nil {
+		return nil, err
+	}
+	if err := expectSequence(body, "entries"); err != nil {
+		return nil, err
+	}
+	for _, item := range body.Content {
+		if err := expectMapping(item, "entries"); err != nil {
+			return nil, err
+		}
+		if v := lookup(item, "define"); v != nil {
+			return nil, invalid(v, "define", "defines can't be made within a repeat")
+		}
+		if v := lookup(item, "include"); v != nil {
+			return nil, invalid(v, "include", "files can't be included within a repeat")
+		}
+	}
+
+	size := len(body.Content)
+	if size == 0 {
+		size = 1
+	}
+	if count > maxRepeated || *made+count*size > maxRepeated {
+		return nil, invalid(countNode, "repeat", "repeats make more than %d entries", maxRepeated)
+	}
+
+	var repeated []sceneEntry
+	for i := 0; i < count; i++ {
+		start := *made
+		counters := map[string]float64{name: float64(i)}
+		for outer, v := range e.counters {
+			if outer != name {
+				counters[outer] = v
+			}
+		}
+
+		for _, item := range body.Content {
+			entry := sceneEntry{node: copyNode(item), file: e.file, index: e.index, counters: counters}
+			if lookup(entry.node, "repeat") == nil {
+				repeated = append(repeated, entry)
+				*made++
+			} else {
+				inner, err := d.repeat(entry, made)
+				if err != nil {
+					return nil, err
+				}
+				repeated = append(repeated, inner...)
+			}
+
+			if *made > maxRepeated {
+				return nil, invalid(countNode, "repeat", "repeats make more than %d entries", maxRepeated)
+			}
+		}
+		if *made == start {
+			*made++
+		}
+	}
+	return repeated, nil
+}
+
+// copyNode returns a deep copy of n, with aliases replaced by copies of what they refer to
+func copyNode(n *yaml.Node) *yaml.Node {
+	n = resolveAlias(n)
+	c := *n
+	c.Anchor = ""
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = copyNode(child)
+	}
+	return &c
+}
+
+// repeatName returns the name a repeat entry counts with
+func repeatName(n *yaml.Node) string {
+	if v := lookup(n, "as"); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return "i"
+}
+
+// checkRepeatKeys reports keys of a repeat entry other than those it takes
+func checkRepeatKeys(n *yaml.Node) error {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		switch key := n.Content[i]; key.Value {
+		case "repeat", "as", "entries":
+		default:
+			return nodeError(key, key.Value, fmt.Errorf("%w %q", ErrUnknownKey, key.Value))
+		}
+	}
+	return nil
+}
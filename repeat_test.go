@@ -0,0 +1,191 @@
+package jtracer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseScene_Repeat(t *testing.T) {
+	scene, err := ParseScene([]byte(`
+- define: count
+  value: 3
+- define: spacing
+  value: 2
+- repeat: count
+  entries:
+    - add: sphere
+      transform:
+        - [translate, i * spacing, 0, 0]
+      material:
+        reflective: i / 4
+- repeat: 2
+  as: row
+  entries:
+    - repeat: row + 1
+      as: column
+      entries:
+        - add: plane
+          transform:
+            - [translate, column, 0, row]
+    - repeat: 1
+      entries:
+        - add: sphere
+          transform: [[translate, i, row, 0]]
+`), ".")
+	if err != nil {
+		t.Fatalf("ParseScene() error = %v", err)
+	}
+
+	want := []Matrix{
+		NewTranslation(0, 0, 0),
+		NewTranslation(2, 0, 0),
+		NewTranslation(4, 0, 0),
+		NewTranslation(0, 0, 0), // row 0 has a single column
+		NewTranslation(0, 0, 0), // an inner repeat counts with its own i
+		NewTranslation(0, 0, 1),
+		NewTranslation(1, 0, 1),
+		NewTranslation(0, 1, 0),
+	}
+	if len(scene.Objects) != len(want) {
+		t.Fatalf("len(Objects) = %d, want %d", len(scene.Objects), len(want))
+	}
+	for i, s := range scene.Objects {
+		if !cmp.Equal(s.GetTransform(), want[i], float64Comparer) {
+			t.Errorf("Objects[%d] transform = %v, want %v", i, s.GetTransform(), want[i])
+		}
+	}
+	if got := scene.Objects[2].GetMaterial().Reflectivity; got != 0.5 {
+		t.Errorf("Objects[2] reflective = %v, want 0.5", got)
+	}
+}
+
+func TestLoadSceneFile_RepeatLeavesStrings(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "i")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewCanvas(1, 1).SavePNG(filepath.Join(dir, "i.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "scene.yaml")
+	scene := `
+- repeat: 2
+  entries:
+    - add: description
+      title: Pillar i of the set
+    - add: sphere
+      transform: [[translate, i, 0, 0]]
+      material:
+        pattern:
+          type: map
+          file: i.png
+`
+	if err := os.WriteFile(path, []byte(scene), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadSceneFile(path)
+	if err != nil {
+		t.Fatalf("LoadSceneFile() error = %v", err)
+	}
+	if want := "Pillar i of the set"; s.Description.Title != want {
+		t.Errorf("title = %q, want %q", s.Description.Title, want)
+	}
+	for i, o := range s.Objects {
+		if want := NewTranslation(float64(i), 0, 0); !cmp.Equal(o.GetTransform(), want, float64Comparer) {
+			t.Errorf("Objects[%d] transform = %v, want %v", i, o.GetTransform(), want)
+		}
+		p, ok := o.GetMaterial().Pattern.(*TextureMapPattern)
+		if want := filepath.Join(dir, "i.png"); !ok || p.File != want {
+			t.Errorf("Objects[%d] pattern = %v, want a texture from %v", i, o.GetMaterial().Pattern, want)
+		}
+	}
+}
+
+func TestParseScene_RepeatErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		line int
+		key  string
+		want error
+	}{
+		{
+			name: "a count that isn't whole",
+			yaml: "- repeat: 2.5\n  entries: []\n",
+			line: 1, key: "repeat",
+			want: ErrInvalidValue,
+		},
+		{
+			name: "a negative count",
+			yaml: "- repeat: -1\n  entries: []\n",
+			line: 1, key: "repeat",
+			want: ErrInvalidValue,
+		},
+		{
+			name: "no entries",
+			yaml: "- repeat: 2\n",
+			line: 1, key: "entries",
+			want: ErrMissingKey,
+		},
+		{
+			name: "an unknown key",
+			yaml: "- repeat: 2\n  times: 3\n  entries: []\n",
+			line: 2, key: "times",
+			want: ErrUnknownKey,
+		},
+		{
+			name: "a define within a repeat",
+			yaml: "- repeat: 2\n  entries:\n    - define: x\n      value: 1\n",
+			line: 3, key: "define",
+			want: ErrInvalidValue,
+		},
+		{
+			name: "a name that isn't one",
+			yaml: "- repeat: 2\n  as: 2x\n  entries: []\n",
+			line: 2, key: "as",
+			want: ErrInvalidValue,
+		},
+		{
+			name: "too many entries",
+			yaml: "- repeat: 1000\n  entries:\n    - repeat: 101\n      entries: [{add: sphere}]\n",
+			line: 3, key: "repeat",
+			want: ErrInvalidValue,
+		},
+		{
+			name: "a count over the limit",
+			yaml: "- repeat: 1000000000\n  entries: []\n",
+			line: 1, key: "repeat",
+			want: ErrInvalidValue,
+		},
+		{
+			name: "repeats of nothing within repeats",
+			yaml: "- repeat: 100000\n  entries:\n    - repeat: 100000\n      entries: []\n",
+			line: 3, key: "repeat",
+			want: ErrInvalidValue,
+		},
+		{
+			name: "an error in a repeated entry",
+			yaml: "- repeat: 2\n  entries:\n    - add: sphere\n      transform: [[scale, j, 1, 1]]\n",
+			line: 4, key: "transform",
+			want: ErrInvalidValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseScene([]byte(tt.yaml), ".")
+			var se *SceneError
+			if !errors.As(err, &se) || !errors.Is(err, tt.want) {
+				t.Fatalf("ParseScene() error = %v, want a *SceneError for %v", err, tt.want)
+			}
+			if se.Index != 0 || se.Line != tt.line || se.Key != tt.key {
+				t.Errorf("ParseScene() error = %v, want entry 0, line %d, key %q", err, tt.line, tt.key)
+			}
+		})
+	}
+}
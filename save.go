@@ -0,0 +1,620 @@
+package jtracer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnsavable is returned when saving a scene that holds something a scene file can't describe, such as an image
+// that wasn't loaded from a file
+var ErrUnsavable = errors.New("can't be saved")
+
+// SaveSceneFile writes a scene to a file that LoadSceneFile reads back, as JSON when the path ends in .json and as
+// YAML otherwise. Image files the scene uses are referred to relative to the new file.
+func SaveSceneFile(path string, scene *Scene) error {
+	marshal := MarshalScene
+	if isJSONFile(path) {
+		marshal = MarshalSceneJSON
+	}
+
+	data, err := marshal(scene, filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// MarshalScene writes a scene in the YAML that ParseScene reads, with the paths of image files relative to dir
+func MarshalScene(scene *Scene, dir string) ([]byte, error) {
+	n, err := sceneEncoder{dir}.scene(scene)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// MarshalSceneJSON writes a scene in the typed JSON that ParseSceneJSON reads, with the paths of image files relative
+// to dir
+func MarshalSceneJSON(scene *Scene, dir string) ([]byte, error) {
+	doc, err := jsonEncoder{sceneEncoder{dir}}.scene(scene)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	// keep vectors and colors on one line
+	data = numberList.ReplaceAllFunc(data, func(list []byte) []byte {
+		return []byte("[" + strings.Join(strings.Fields(string(list[1:len(list)-1])), " ") + "]")
+	})
+	return append(data, '\n'), nil
+}
+
+// numberList matches a list of numbers that json.MarshalIndent has put one to a line
+var numberList = regexp.MustCompile(`\[\s*-?[0-9][-+.0-9eE]*(,\s*-?[0-9][-+.0-9eE]*)*\s*\]`)
+
+// sceneEncoder builds the entries of a scene file, writing the paths of files relative to dir
+type sceneEncoder struct {
+	dir string
+}
+
+func (e sceneEncoder) scene(scene *Scene) (*yaml.Node, error) {
+	root := sequenceNode()
+
+	if d := scene.Description; d != (SceneDescription{}) {
+		entry := mappingNode("add", stringNode("description"))
+		for _, field := range []struct{ key, value string }{
+			{"title", d.Title}, {"description", d.Description}, {"author", d.Author},
+		} {
+			if field.value != "" {
+				appendPair(entry, field.key, stringNode(field.value))
+			}
+		}
+		root.Content = append(root.Content, entry)
+	}
+
+	a := scene.Animation
+	if a == nil {
+		a = &Animation{}
+	} else {
+		root.Content = append(root.Content, mappingNode(
+			"add", stringNode("animation"),
+			"frames", intNode(a.Frames),
+			"fps", floatNode(a.FPS),
+		))
+	}
+
+	if scene.Camera.Hsize > 0 {
+		root.Content = append(root.Content, animated(cameraNode(scene.Camera), a.Camera, cameraProperties))
+	}
+
+	if scene.Light != (Light{}) {
+		p := scene.Light.Position
+		root.Content = append(root.Content, animated(mappingNode(
+			"add", stringNode("light"),
+			"at", floatsNode(p.X, p.Y, p.Z),
+			"intensity", colorNode(scene.Light.Intensity),
+		), a.Light, lightProperties))
+	}
+
+	if scene.Background != nil {
+		n, err := e.background(scene.Background)
+		if err != nil {
+			return nil, fmt.Errorf("background: %w", err)
+		}
+		root.Content = append(root.Content, n)
+	}
+
+	if env := scene.Environment; env != nil {
+		file, err := e.path(env.File)
+		if err != nil {
+			return nil, fmt.Errorf("environment: %w", err)
+		}
+		root.Content = append(root.Content, mappingNode(
+			"add", stringNode("environment"),
+			"file", stringNode(file),
+			"intensity", floatNode(env.Intensity),
+			"rotation", floatNode(env.Rotation),
+			"samples", intNode(env.Samples),
+		))
+	}
+
+	for i, s := range scene.Objects {
+		n, err := e.shape(s)
+		if err != nil {
+			return nil, fmt.Errorf("object %d: %w", i, err)
+		}
+		root.Content = append(root.Content, animated(n, a.Objects[i], objectProperties))
+	}
+
+	return root, nil
+}
+
+// cameraNode writes a camera with the vectors cameraVectors recovers from its view transform
+func cameraNode(c Camera) *yaml.Node {
+	from, to, up := cameraVectors(c)
+	return mappingNode(
+		"add", stringNode("camera"),
+		"width", intNode(int(c.Hsize)),
+		"height", intNode(int(c.Vsize)),
+		"field-of-view", floatNode(c.Fov),
+		"from", floatsNode(from.X, from.Y, from.Z),
+		"to", floatsNode(to.X, to.Y, to.Z),
+		"up", floatsNode(up.X, up.Y, up.Z),
+	)
+}
+
+// cameraVectors recovers where a camera is looking from and to from its view transform. ViewTransform only
+// normalizes the up vector it is given, so the rows of the transform are left, the true up scaled by the sine of the
+// angle between up and forward, and backward. Any up at that angle to forward gives the same transform again.
+func cameraVectors(c Camera) (from, to, up *Tuple) {
+	t := c.Transform
+	from = t.Inverse().MultiplyByTuple(*NewPoint(0, 0, 0))
+	forward := NewVector(-t[2][0], -t[2][1], -t[2][2])
+	to = from.Add(forward)
+
+	sin := NewVector(t[0][0], t[0][1], t[0][2]).Magnitude()
+	cos := math.Sqrt(math.Max(0, 1-sin*sin))
+	up = NewVector(t[1][0], t[1][1], t[1][2]).Normalize().Multiply(sin).Add(forward.Multiply(cos))
+	return from, to, up
+}
+
+// animated adds the tracks of the thing an entry adds under its animate key, in the order of props
+func animated(entry *yaml.Node, tracks Tracks, props []trackProperty) *yaml.Node {
+	if len(tracks) == 0 {
+		return entry
+	}
+
+	n := mappingNode()
+	for _, p := range props {
+		track, ok := tracks[p.name]
+		if !ok {
+			continue
+		}
+
+		keys := sequenceNode()
+		for _, k := range track {
+			value := floatsNode(k.Value...)
+			if p.size == 1 {
+				value = floatNode(k.Value[0])
+			}
+			key := mappingNode("frame", floatNode(k.Frame), "value", value)
+			if k.Ease != Linear {
+				appendPair(key, "ease", stringNode(k.Ease.String()))
+			}
+			key.Style = yaml.FlowStyle
+			keys.Content = append(keys.Content, key)
+		}
+		appendPair(n, p.name, keys)
+	}
+	appendPair(entry, "animate", n)
+	return entry
+}
+
+func (e sceneEncoder) background(b Background) (*yaml.Node, error) {
+	entry := mappingNode("add", stringNode("background"))
+
+	switch b := b.(type) {
+	case SolidBackground:
+		appendPair(entry, "type", stringNode("solid"))
+		appendPair(entry, "color", colorNode(b.Color))
+	case GradientBackground:
+		appendPair(entry, "type", stringNode("gradient"))
+		appendPair(entry, "bottom", colorNode(b.Bottom))
+		appendPair(entry, "top", colorNode(b.Top))
+	case CubeMapBackground:
+		files := mappingNode()
+		for face, name := range []string{"left", "front", "right", "back", "up", "down"} {
+			file, err := e.path(b.Files[face])
+			if err != nil {
+				return nil, err
+			}
+			appendPair(files, name, stringNode(file))
+		}
+		appendPair(entry, "type", stringNode("cube-map"))
+		appendPair(entry, "files", files)
+	case EquirectangularBackground:
+		file, err := e.path(b.File)
+		if err != nil {
+			return nil, err
+		}
+		appendPair(entry, "type", stringNode("equirectangular"))
+		appendPair(entry, "file", stringNode(file))
+	default:
+		return nil, fmt.Errorf("%T %w", b, ErrUnsavable)
+	}
+
+	return entry, nil
+}
+
+func (e sceneEncoder) shape(s Shape) (*yaml.Node, error) {
+	var kind string
+	switch s.(type) {
+	case *Plane:
+		kind = "plane"
+	case *Sphere:
+		kind = "sphere"
+	default:
+		return nil, fmt.Errorf("%T %w", s, ErrUnsavable)
+	}
+	entry := mappingNode("add", stringNode(kind))
+
+	if err := appendTransform(entry, s.GetTransform()); err != nil {
+		return nil, err
+	}
+
+	m, err := e.material(s.GetMaterial())
+	if err != nil {
+		return nil, fmt.Errorf("material: %w", err)
+	}
+	if len(m.Content) > 0 {
+		appendPair(entry, "material", m)
+	}
+
+	return entry, nil
+}
+
+// material writes the properties of m that differ from those of NewMaterial, which every shape starts with
+func (e sceneEncoder) material(m Material) (*yaml.Node, error) {
+	def := NewMaterial()
+	n := mappingNode()
+
+	if m.Color != def.Color {
+		appendPair(n, "color", colorNode(m.Color))
+	}
+	if m.HasPattern {
+		p, err := e.pattern(m.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		appendPair(n, "pattern", p)
+	}
+
+	for _, field := range []struct {
+		key           string
+		value, unless float64
+	}{
+		{"ambient", m.Ambient, def.Ambient},
+		{"diffuse", m.Diffuse, def.Diffuse},
+		{"specular", m.Specular, def.Specular},
+		{"shininess", m.Shininess, def.Shininess},
+		{"reflective", m.Reflectivity, def.Reflectivity},
+		{"transparency", m.Transparency, def.Transparency},
+		{"refractive-index", m.RefractiveIndex, def.RefractiveIndex},
+		{"metallic", m.Metallic, def.Metallic},
+		{"roughness", m.Roughness, def.Roughness},
+	} {
+		if field.value != field.unless {
+			appendPair(n, field.key, floatNode(field.value))
+		}
+	}
+
+	if m.GlossySamples != 0 {
+		appendPair(n, "glossy-samples", intNode(m.GlossySamples))
+	}
+
+	if m.Shading != nil {
+		s, err := shadingNode(m.Shading)
+		if err != nil {
+			return nil, err
+		}
+		appendPair(n, "shading", s)
+	}
+
+	if m.IsConductor() {
+		appendPair(n, "conductor", conductorNode(m.Conductor))
+	}
+
+	switch d := m.Dispersion; d.Model {
+	case CauchyDispersion:
+		appendPair(n, "dispersion", mappingNode("model", stringNode("cauchy"), "coefficients", floatsNode(d.B[:]...)))
+	case SellmeierDispersion:
+		appendPair(n, "dispersion", mappingNode(
+			"model", stringNode("sellmeier"), "b", floatsNode(d.B[:]...), "c", floatsNode(d.C[:]...),
+		))
+	}
+
+	if m.Bump != 0 {
+		if m.BumpFrequency != 0 {
+			appendPair(n, "bump", mappingNode("amount", floatNode(m.Bump), "frequency", floatNode(m.BumpFrequency)))
+		} else {
+			appendPair(n, "bump", floatNode(m.Bump))
+		}
+	}
+
+	if nm := m.NormalMap; nm != nil {
+		sm, err := e.surfaceMap(nm.File, nm.Mapper)
+		if err != nil {
+			return nil, fmt.Errorf("normal-map: %w", err)
+		}
+		if nm.Strength != 0 {
+			appendPair(sm, "strength", floatNode(nm.Strength))
+		}
+		appendPair(n, "normal-map", sm)
+	}
+
+	if bm := m.BumpMap; bm != nil {
+		sm, err := e.surfaceMap(bm.File, bm.Mapper)
+		if err != nil {
+			return nil, fmt.Errorf("bump-map: %w", err)
+		}
+		if bm.Depth != 0 {
+			appendPair(sm, "depth", floatNode(bm.Depth))
+		}
+		appendPair(n, "bump-map", sm)
+	}
+
+	return n, nil
+}
+
+func shadingNode(s ShadingModel) (*yaml.Node, error) {
+	switch s := s.(type) {
+	case PhongShading:
+		return stringNode("phong"), nil
+	case BlinnPhongShading:
+		return stringNode("blinn-phong"), nil
+	case LambertShading:
+		return stringNode("lambert"), nil
+	case MicrofacetShading:
+		return stringNode("pbr"), nil
+	case ToonShading:
+		n := mappingNode("type", stringNode("toon"))
+		if s.Bands != 0 {
+			appendPair(n, "bands", intNode(s.Bands))
+		}
+		if s.Outline != 0 {
+			appendPair(n, "outline", floatNode(s.Outline))
+		}
+		if s.OutlineColor != (Color{}) {
+			appendPair(n, "outline-color", colorNode(s.OutlineColor))
+		}
+		return n, nil
+	}
+
+	return nil, fmt.Errorf("shading %T %w", s, ErrUnsavable)
+}
+
+// conductorNode names a conductor by its preset when it has one
+func conductorNode(ior ComplexIOR) *yaml.Node {
+	if name, ok := conductorPreset(ior); ok {
+		return stringNode(name)
+	}
+	return mappingNode("n", colorNode(ior.N), "k", colorNode(ior.K))
+}
+
+// conductorPreset returns the first name, in order, of the preset a conductor is, if any
+func conductorPreset(ior ComplexIOR) (string, bool) {
+	var names []string
+	for name, preset := range ConductorPresets {
+		if preset == ior {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+
+	sort.Strings(names)
+	return names[0], true
+}
+
+// surfaceMap writes the file and mapping shared by normal and bump maps
+func (e sceneEncoder) surfaceMap(file string, mapper UVMapper) (*yaml.Node, error) {
+	path, err := e.path(file)
+	if err != nil {
+		return nil, err
+	}
+	n := mappingNode("file", stringNode(path))
+
+	if mapper != nil {
+		name, err := mapperName(mapper)
+		if err != nil {
+			return nil, err
+		}
+		appendPair(n, "mapping", stringNode(name))
+	}
+	return n, nil
+}
+
+func mapperName(m UVMapper) (string, error) {
+	switch m.(type) {
+	case SphericalMap:
+		return "spherical", nil
+	case PlanarMap:
+		return "planar", nil
+	case CylindricalMap:
+		return "cylindrical", nil
+	case CubicMap:
+		return "cube", nil
+	}
+
+	return "", fmt.Errorf("mapping %T %w", m, ErrUnsavable)
+}
+
+func (e sceneEncoder) pattern(p Pattern) (*yaml.Node, error) {
+	var n *yaml.Node
+	var err error
+
+	switch p := p.(type) {
+	case *StripePattern:
+		n, err = e.twoColorPattern("stripes", p.A, p.B, p.PatternA, p.PatternB)
+	case *CheckersPattern:
+		n, err = e.twoColorPattern("checkers", p.A, p.B, p.PatternA, p.PatternB)
+	case *GradientPattern:
+		n, err = e.twoColorPattern("gradient", p.A, p.B, p.PatternA, p.PatternB)
+	case *RingPattern:
+		n, err = e.twoColorPattern("rings", p.A, p.B, p.PatternA, p.PatternB)
+	case *RadialGradientPattern:
+		n, err = e.twoColorPattern("radial-gradient", p.A, p.B, p.PatternA, p.PatternB)
+	case *MarblePattern:
+		if n, err = e.twoColorPattern("marble", p.A, p.B, p.PatternA, p.PatternB); err == nil {
+			appendNoise(n, "turbulence", p.Frequency, p.Turbulence, p.Octaves, p.Noise)
+		}
+	case *WoodPattern:
+		if n, err = e.twoColorPattern("wood", p.A, p.B, p.PatternA, p.PatternB); err == nil {
+			appendNoise(n, "turbulence", p.Frequency, p.Turbulence, p.Octaves, p.Noise)
+		}
+	case *WorleyPattern:
+		if n, err = e.twoColorPattern("worley", p.A, p.B, p.PatternA, p.PatternB); err == nil {
+			appendPair(n, "frequency", floatNode(p.Frequency))
+			appendPair(n, "seed", intNode(int(p.Seed)))
+			appendPair(n, "edges", boolNode(p.Edges))
+		}
+	case *TextureMapPattern:
+		var file, mapping string
+		if file, err = e.path(p.File); err == nil {
+			if mapping, err = mapperName(p.Mapper); err == nil {
+				n = mappingNode("type", stringNode("map"), "file", stringNode(file), "mapping", stringNode(mapping))
+			}
+		}
+	case *TriplanarPattern:
+		n = mappingNode("type", stringNode("triplanar"))
+		if p.Pattern != nil {
+			var inner *yaml.Node
+			if inner, err = e.pattern(p.Pattern); err == nil {
+				appendPair(n, "pattern", inner)
+			}
+		} else {
+			var file string
+			if file, err = e.path(p.File); err == nil {
+				appendPair(n, "file", stringNode(file))
+			}
+		}
+		appendPair(n, "sharpness", floatNode(p.Sharpness))
+	case *BlendedPattern:
+		var patterns *yaml.Node
+		if patterns, err = e.patterns(p.A, p.B); err == nil {
+			n = mappingNode("type", stringNode("blend"), "patterns", patterns, "weight", floatNode(p.Weight))
+		}
+	case *MaskPattern:
+		var patterns, mask *yaml.Node
+		if patterns, err = e.patterns(p.A, p.B); err == nil {
+			if mask, err = e.pattern(p.Mask); err == nil {
+				n = mappingNode("type", stringNode("mask"), "patterns", patterns, "mask", mask)
+			}
+		}
+	case *SolidPattern:
+		n = mappingNode("type", stringNode("solid"), "color", colorNode(p.Color))
+	case *PerturbedPattern:
+		var inner *yaml.Node
+		if inner, err = e.pattern(p.Pattern); err == nil {
+			n = mappingNode("type", stringNode("perturbed"), "pattern", inner)
+			appendNoise(n, "scale", p.Frequency, p.Scale, p.Octaves, p.Noise)
+		}
+	default:
+		return nil, fmt.Errorf("pattern %T %w", p, ErrUnsavable)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return n, appendTransform(n, p.GetTransform())
+}
+
+// twoColorPattern writes the colors of a pattern, or the patterns nested in their place
+func (e sceneEncoder) twoColorPattern(kind string, a, b Color, pa, pb Pattern) (*yaml.Node, error) {
+	colors := sequenceNode()
+	for _, slot := range []struct {
+		c Color
+		p Pattern
+	}{{a, pa}, {b, pb}} {
+		if slot.p == nil {
+			colors.Content = append(colors.Content, colorNode(slot.c))
+			continue
+		}
+
+		n, err := e.pattern(slot.p)
+		if err != nil {
+			return nil, err
+		}
+		colors.Content = append(colors.Content, n)
+	}
+
+	return mappingNode("type", stringNode(kind), "colors", colors), nil
+}
+
+func (e sceneEncoder) patterns(ps ...Pattern) (*yaml.Node, error) {
+	n := sequenceNode()
+	for _, p := range ps {
+		item, err := e.pattern(p)
+		if err != nil {
+			return nil, err
+		}
+		n.Content = append(n.Content, item)
+	}
+	return n, nil
+}
+
+// appendNoise writes the settings read by parseNoiseParameters, with the strength of the noise under strengthKey
+func appendNoise(n *yaml.Node, strengthKey string, frequency, strength float64, octaves int, noise *Perlin) {
+	appendPair(n, "frequency", floatNode(frequency))
+	appendPair(n, strengthKey, floatNode(strength))
+	appendPair(n, "octaves", intNode(octaves))
+	if noise != nil {
+		appendPair(n, "seed", intNode(int(noise.Seed)))
+	}
+}
+
+// path returns the path of a file the scene refers to, relative to the directory the scene is saved in
+func (e sceneEncoder) path(file string) (string, error) {
+	if file == "" {
+		return "", fmt.Errorf("an image that wasn't loaded from a file %w", ErrUnsavable)
+	}
+
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	dir, err := filepath.Abs(e.dir)
+	if err != nil {
+		return "", err
+	}
+
+	if rel, err := filepath.Rel(dir, abs); err == nil {
+		return filepath.ToSlash(rel), nil
+	}
+	return abs, nil
+}
+
+// appendTransform writes a transform as a list of the named transforms that compose it, unless it is the identity
+func appendTransform(n *yaml.Node, m Matrix) error {
+	steps, err := DecomposeTransform(m)
+	if err != nil {
+		return fmt.Errorf("transform: %w", err)
+	}
+	if len(steps) == 0 {
+		return nil
+	}
+
+	transforms := sequenceNode()
+	for _, step := range steps {
+		t := floatsNode(step.Args...)
+		t.Content = append([]*yaml.Node{stringNode(step.Name)}, t.Content...)
+		transforms.Content = append(transforms.Content, t)
+	}
+	appendPair(n, "transform", transforms)
+	return nil
+}
@@ -0,0 +1,337 @@
+package jtracer
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// sceneComparer compares loaded scenes, ignoring the random IDs of shapes and how the paths of files are written
+var sceneComparer = cmp.Options{
+	float64Comparer,
+	cmpopts.IgnoreFields(Scene{}, "InputFile"),
+	cmpopts.IgnoreFields(Camera{}, "Progress"),
+	cmpopts.IgnoreFields(AbstractShape{}, "ID"),
+	cmpopts.IgnoreUnexported(EnvironmentLight{}, Perlin{}),
+	cmp.FilterPath(func(p cmp.Path) bool {
+		for _, step := range p {
+			if f, ok := step.(cmp.StructField); ok && (f.Name() == "File" || f.Name() == "Files") {
+				return true
+			}
+		}
+		return false
+	}, cmp.Comparer(func(a, b string) bool {
+		absA, _ := filepath.Abs(a)
+		absB, _ := filepath.Abs(b)
+		return absA == absB
+	})),
+}
+
+// everythingScene uses every kind of entry, pattern and material property that can be saved
+const everythingScene = `
+- add: description
+  title: everything
+  author: "someone: with a colon"
+- add: camera
+  width: 40
+  height: 30
+  field-of-view: 1.2
+  from: [1, 2, -5]
+  to: [0, 1, 0]
+  up: [0, 1, 0]
+- add: light
+  at: [-10, 10, -10]
+  intensity: [1, 0.9, 0.8]
+- add: background
+  type: cube-map
+  files: {left: sky.ppm, front: sky.ppm, right: sky.ppm, back: sky.ppm, up: sky.ppm, down: sky.ppm}
+- add: environment
+  file: sky.ppm
+  intensity: 0.5
+  rotation: 1
+  samples: 4
+- add: plane
+  transform:
+    - [shear, 0.5, 0, 0, 0.25, 0, 0]
+    - [scale, 2, -1, 3]
+    - [rotate-x, 0.3]
+    - [rotate-y, -1.2]
+    - [rotate-z, 2]
+    - [translate, 1, 2, 3]
+  material:
+    color: [0.2, 0.4, 0.6]
+    ambient: 0
+    diffuse: 0.5
+    specular: 0.3
+    shininess: 20
+    reflective: 0.4
+    transparency: 0.8
+    refractive-index: 1.5
+    glossy-samples: 3
+    dispersion: {model: cauchy, coefficients: [1.5, 0.004, 0]}
+    shading: {type: toon, bands: 4, outline: 0.2, outline-color: [1, 0, 0]}
+    bump: {amount: 0.3, frequency: 2}
+    normal-map: {file: textures/normal.ppm, mapping: planar, strength: 2}
+    pattern:
+      type: stripes
+      colors:
+        - [1, 1, 1]
+        - type: checkers
+          colors: [[0, 0, 0], [1, 0, 0]]
+          transform: [[scale, 0.5, 0.5, 0.5]]
+- add: sphere
+  material:
+    shading: pbr
+    metallic: 1
+    roughness: 0.3
+    conductor: gold
+    dispersion: {model: sellmeier, b: [1, 0.2, 1], c: [0.006, 0.02, 100]}
+    bump: 0.1
+    bump-map: {file: textures/normal.ppm, depth: 0.05}
+    pattern:
+      type: blend
+      weight: 0.25
+      patterns:
+        - {type: gradient, colors: [[0, 0, 0], [1, 1, 1]]}
+        - type: mask
+          patterns: [[1, 0, 0], {type: rings, colors: [[0, 0, 1], [0, 1, 0]]}]
+          mask: {type: radial-gradient, colors: [[0, 0, 0], [1, 1, 1]]}
+- add: sphere
+  material:
+    conductor: {n: [1, 2, 3], k: [4, 5, 6]}
+    shading: lambert
+    pattern:
+      type: perturbed
+      scale: 0.3
+      frequency: 2
+      octaves: 3
+      seed: 7
+      pattern:
+        type: marble
+        colors: [[1, 1, 1], {type: wood, colors: [[0.5, 0.3, 0.1], [0.3, 0.2, 0.1]], seed: 3}]
+        turbulence: 4
+        octaves: 2
+- add: sphere
+  material:
+    shading: blinn-phong
+    pattern:
+      type: triplanar
+      sharpness: 2
+      pattern: {type: worley, colors: [[0, 0, 0], [1, 1, 1]], frequency: 3, seed: 5, edges: true}
+- add: sphere
+  material:
+    pattern: {type: map, file: textures/normal.ppm, mapping: cylindrical}
+- add: sphere
+  material:
+    pattern: {type: triplanar, file: sky.ppm}
+`
+
+func TestSaveSceneFile_RoundTrip(t *testing.T) {
+	fixtures := t.TempDir()
+	writeFiles(t, fixtures, map[string]string{
+		"everything.yaml":     everythingScene,
+		"sky.ppm":             "P3 2 1 255 10 20 30 200 210 220\n",
+		"textures/normal.ppm": "P3 1 1 255 128 128 255\n",
+	})
+
+	files := bundledScenes(t)
+	files = append(files, filepath.Join(fixtures, "everything.yaml"))
+
+	for _, file := range files {
+		for _, format := range []string{"yaml", "json"} {
+			t.Run(filepath.Base(file)+" as "+format, func(t *testing.T) {
+				original, err := LoadSceneFile(file)
+				if err != nil {
+					t.Fatalf("LoadSceneFile() error = %v", err)
+				}
+
+				// save somewhere else, so that the paths of image files have to be rewritten
+				saved := filepath.Join(t.TempDir(), "saved", "scene."+format)
+				if err := os.MkdirAll(filepath.Dir(saved), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := SaveSceneFile(saved, original); err != nil {
+					t.Fatalf("SaveSceneFile() error = %v", err)
+				}
+
+				loaded, err := LoadSceneFile(saved)
+				if err != nil {
+					data, _ := os.ReadFile(saved)
+					t.Fatalf("LoadSceneFile() of the saved scene error = %v\n%s", err, data)
+				}
+				if diff := cmp.Diff(original, loaded, sceneComparer); diff != "" {
+					t.Errorf("saved scene differs from the original (-want +got):\n%s", diff)
+				}
+
+				// saving again gives the same file
+				first, _ := os.ReadFile(saved)
+				again, err := MarshalScene(loaded, filepath.Dir(saved))
+				if format == "json" {
+					again, err = MarshalSceneJSON(loaded, filepath.Dir(saved))
+				}
+				if err != nil || !bytes.Equal(first, again) {
+					t.Errorf("saving the loaded scene again = %s, %v, want\n%s", again, err, first)
+				}
+			})
+		}
+	}
+}
+
+func TestMarshalScene(t *testing.T) {
+	s := &Scene{Camera: NewCamera(10, 20, math.Pi/2)}
+	s.Camera.Transform = ViewTransform(NewPoint(0, 0, -5), NewPoint(0, 0, 0), NewVector(0, 1, 0))
+	sphere := NewSphere()
+	sphere.SetTransform(NewTranslation(1, 2, 3).Multiply(Scaling(2, 2, 2)))
+	sphere.Material.Reflectivity = 0.5
+	s.Objects = []Shape{NewPlane(), sphere}
+
+	got, err := MarshalScene(s, ".")
+	if err != nil {
+		t.Fatalf("MarshalScene() error = %v", err)
+	}
+
+	want := `- add: camera
+  width: 10
+  height: 20
+  field-of-view: 1.57079632679
+  from: [0, 0, -5]
+  to: [0, 0, -4]
+  up: [0, 1, 0]
+- add: plane
+- add: sphere
+  transform:
+    - [scale, 2, 2, 2]
+    - [translate, 1, 2, 3]
+  material:
+    reflective: 0.5
+`
+	if string(got) != want {
+		t.Errorf("MarshalScene() = \n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMarshalScene_Unsavable(t *testing.T) {
+	tests := []struct {
+		name  string
+		scene *Scene
+		want  error
+	}{
+		{
+			name:  "a texture that wasn't loaded from a file",
+			scene: &Scene{Background: EquirectangularBackground{Image: NewCanvas(1, 1)}},
+			want:  ErrUnsavable,
+		},
+		{
+			name: "a pattern that scene files can't describe",
+			scene: &Scene{Objects: []Shape{func() Shape {
+				s := NewSphere()
+				s.Material.Pattern, s.Material.HasPattern = NewTestPattern(), true
+				return s
+			}()}},
+			want: ErrUnsavable,
+		},
+		{
+			name: "a transform squashed flat",
+			scene: &Scene{Objects: []Shape{func() Shape {
+				s := NewSphere()
+				s.SetTransform(Scaling(1, 0, 1))
+				return s
+			}()}},
+			want: ErrSingularTransform,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := MarshalScene(tt.scene, "."); !errors.Is(err, tt.want) {
+				t.Errorf("MarshalScene() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecomposeTransform(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform Matrix
+		want      []string
+	}{
+		{
+			name:      "the identity needs no steps",
+			transform: IdentityMatrix,
+		},
+		{
+			name:      "a translation",
+			transform: NewTranslation(1, -2, 3),
+			want:      []string{"translate"},
+		},
+		{
+			name:      "a reflection is a negative scale",
+			transform: Scaling(-1, 1, 1),
+			want:      []string{"scale"},
+		},
+		{
+			name:      "rotations about each axis",
+			transform: RotationZ(0.5).Multiply(RotationY(-1)).Multiply(RotationX(2)),
+			want:      []string{"rotate-x", "rotate-y", "rotate-z"},
+		},
+		{
+			name:      "a rotation about y of a quarter turn",
+			transform: RotationY(math.Pi / 2),
+			want:      []string{"rotate-y"},
+		},
+		{
+			name:      "rotations about x then a quarter turn about y",
+			transform: RotationY(math.Pi / 2).Multiply(RotationX(0.4)),
+			want:      []string{"rotate-x", "rotate-y"},
+		},
+		{
+			name: "everything at once",
+			transform: NewTranslation(1, 2, 3).Multiply(RotationX(1)).Multiply(Scaling(1, 2, 3)).
+				Multiply(Shearing(1, 0, 0, 0.5, 0, 0)).Multiply(RotationZ(-0.7)),
+			want: []string{"shear", "scale", "rotate-x", "rotate-y", "rotate-z", "translate"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := DecomposeTransform(tt.transform)
+			if err != nil {
+				t.Fatalf("DecomposeTransform() error = %v", err)
+			}
+
+			var names []string
+			composed := IdentityMatrix
+			for _, step := range steps {
+				names = append(names, step.Name)
+				var m Matrix
+				switch a := step.Args; step.Name {
+				case "shear":
+					m = Shearing(a[0], a[1], a[2], a[3], a[4], a[5])
+				case "scale":
+					m = Scaling(a[0], a[1], a[2])
+				case "rotate-x":
+					m = RotationX(a[0])
+				case "rotate-y":
+					m = RotationY(a[0])
+				case "rotate-z":
+					m = RotationZ(a[0])
+				case "translate":
+					m = NewTranslation(a[0], a[1], a[2])
+				}
+				composed = m.Multiply(composed)
+			}
+
+			if !cmp.Equal(names, tt.want) {
+				t.Errorf("DecomposeTransform() = %v, want steps %v", steps, tt.want)
+			}
+			if !cmp.Equal(composed, tt.transform, float64Comparer) {
+				t.Errorf("DecomposeTransform() steps compose to %v, want %v", composed, tt.transform)
+			}
+		})
+	}
+}
@@ -1,223 +1,1106 @@
 package jtracer
 
-// A quick and dirty scene yaml parser
+// A scene file is a YAML list of entries, each of which adds something to the scene, defines a value that other
+// entries can refer to by name, or includes the entries of another file
 
 import (
-	"gopkg.in/yaml.v3"
+	"fmt"
 	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
 )
 
 type SceneDescription struct {
-	Title       string
-	Description string
-	Author      string
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Author      string `json:"author,omitempty"`
 }
 type Scene struct {
 	InputFile   string
 	Camera      Camera
 	Description SceneDescription
 	Light       Light
+	Background  Background
+	Environment *EnvironmentLight
 	Objects     []Shape
+	Animation   *Animation // nil for a still
+}
+
+// World returns the world of the scene's objects and lights, for its camera to render
+func (s *Scene) World() World {
+	return World{
+		Objects:     s.Objects,
+		Light:       s.Light,
+		Background:  s.Background,
+		Environment: s.Environment,
+	}
 }
 
+// LoadSceneFile reads a scene from a YAML file, or a JSON one when its name ends in .json. Problems with its contents
+// are reported as a *SceneError.
 func LoadSceneFile(path string) (*Scene, error) {
-	yamlFile, err := os.ReadFile(path)
+	data, err := os.ReadFile(path)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	var entries []map[string]interface{}
-	err = yaml.Unmarshal(yamlFile, &entries)
+	var scene *Scene
+	if isJSONFile(path) {
+		scene, err = parseSceneJSON(data, path, filepath.Dir(path))
+	} else {
+		scene, err = parseScene(data, path, filepath.Dir(path))
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	var scene Scene
 	scene.InputFile = path
+	return scene, nil
+}
 
-	defines := make(map[string]interface{})
+// ParseScene reads a scene from YAML, resolving the relative paths of any files it refers to against dir
+func ParseScene(data []byte, dir string) (*Scene, error) {
+	return parseScene(data, "", dir)
+}
 
-	// first look for defines
-	for _, k := range entries {
-		if k["define"] != nil {
-			defines[k["define"].(string)] = k["value"]
+// parseScene reads a scene from YAML that came from file, if any
+func parseScene(data []byte, file, dir string) (*Scene, error) {
+	var including []string
+	if file != "" {
+		including = []string{file}
+	}
+
+	entries, err := sceneEntries(data, file, dir, including)
+	if err != nil {
+		return nil, err
+	}
+
+	defs, errs := collectDefines(entries)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	if entries, errs = expandRepeats(entries, defs); len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	var scene Scene
+	for _, entry := range entries {
+		if lookup(entry.node, "define") != nil {
+			continue
 		}
 
+		if err := scene.add(entry, defs); err != nil {
+			return nil, entry.locate(err)
+		}
+	}
+	if scene.Animation != nil {
+		scene.Animation.setFrames()
 	}
 
-	for _, k := range entries {
-		switch k["add"] {
-		case "description":
-			scene.Description = SceneDescription{
-				Description: k["description"].(string),
-				Title:       k["title"].(string),
-			}
-		case "camera":
-			scene.Camera = NewCamera(
-				float64(k["width"].(int)),
-				float64(k["height"].(int)),
-				k["field-of-view"].(float64),
-			)
-
-			from := ConvertToFloat64(k["from"].([]interface{}))
-			up := ConvertToFloat64(k["up"].([]interface{}))
-			to := ConvertToFloat64(k["to"].([]interface{}))
-			scene.Camera.Transform = ViewTransform(
-				NewPoint(from[0], from[1], from[2]),
-				NewPoint(to[0], to[1], to[2]),
-				NewVector(up[0], up[1], up[2]),
-			)
-		case "light":
-			at := ConvertToFloat64(k["at"].([]interface{}))
-			intensity := ConvertToFloat64(k["intensity"].([]interface{}))
-			scene.Light = NewPointLight(
-				*NewPoint(at[0], at[1], at[2]),
-				Color{Red: intensity[0], Green: intensity[1], Blue: intensity[2]},
-			)
-		case "plane":
-			p := NewPlane()
-			if k["transform"] != nil {
-				tf := ParseTransforms(k["transform"].([]interface{}))
-				p.SetTransform(tf)
-			}
+	return &scene, nil
+}
 
-			if k["material"] != nil {
-				if _, ok := k["material"].(string); ok {
-					k["material"] = defines[k["material"].(string)]
-				}
+// add adds the thing described by an entry to the scene. The entry's material, its patterns and its transforms may
+// refer to defines by name, and its numbers may be expressions of numeric defines and the counters of the repeats
+// that made the entry.
+func (scene *Scene) add(e sceneEntry, defs defines) error {
+	entry, sc := e.node, scope{defs: defs, counters: e.counters}
+	kind, err := requiredString(entry, "add")
+	if err != nil {
+		return err
+	}
 
-				p.Material = ParseMaterial(p.Material, k["material"].(map[string]interface{}))
-			}
+	if err := firstError(
+		expandValue(entry, "transform", defs.expandTransforms),
+		expandValue(entry, "material", defs.expandMaterial),
+	); err != nil {
+		return err
+	}
 
-			scene.Objects = append(scene.Objects, p)
-		case "sphere":
-			s := NewSphere()
-			if k["transform"] != nil {
-				tf := ParseTransforms(k["transform"].([]interface{}))
-				s.SetTransform(tf)
+	switch kind {
+	case "description":
+		for _, field := range []struct {
+			key string
+			dst *string
+		}{
+			{"title", &scene.Description.Title},
+			{"description", &scene.Description.Description},
+			{"author", &scene.Description.Author},
+		} {
+			if v := lookup(entry, field.key); v != nil {
+				if *field.dst, err = decodeString(v, field.key); err != nil {
+					return err
+				}
 			}
+		}
+	case "animation":
+		a := scene.animation()
+		err = firstError(sc.requiredInt(entry, "frames", &a.Frames), sc.optionalFloat(entry, "fps", &a.FPS))
+		if err == nil && a.Frames < 1 {
+			err = invalid(lookup(entry, "frames"), "frames", "expected 1 or more frames, got %d", a.Frames)
+		}
+		if err == nil && a.FPS <= 0 {
+			err = invalid(lookup(entry, "fps"), "fps", "expected more than 0 frames a second, got %g", a.FPS)
+		}
+	case "camera":
+		scene.Camera, err = sc.parseCamera(entry)
+	case "light":
+		scene.Light, err = sc.parseLight(entry)
+	case "background":
+		scene.Background, err = sc.parseBackground(entry)
+	case "environment":
+		scene.Environment, err = sc.parseEnvironment(entry)
+	case "plane":
+		p := NewPlane()
+		p.Material, err = sc.parseShape(p, p.Material, entry)
+		scene.Objects = append(scene.Objects, p)
+	case "sphere":
+		s := NewSphere()
+		s.Material, err = sc.parseShape(s, s.Material, entry)
+		scene.Objects = append(scene.Objects, s)
+	default:
+		return nodeError(lookup(entry, "add"), "add", fmt.Errorf("%w %q", ErrUnknownType, kind))
+	}
+
+	if err != nil {
+		return err
+	}
+	return scene.addTracks(kind, entry, sc)
+}
 
-			if k["material"] != nil {
-				s.Material = ParseMaterial(s.Material, k["material"].(map[string]interface{}))
+// addTracks adds the tracks under the animate key of an entry that added a camera, a light or an object
+func (scene *Scene) addTracks(kind string, entry *yaml.Node, sc scope) error {
+	props, ok := trackProperties[kind]
+	if !ok {
+		return nil
+	}
+
+	tracks, err := sc.parseAnimate(entry, props)
+	if err != nil {
+		return err
+	}
+
+	if kind == "camera" && tracks != nil {
+		var view [3][]float64
+		for i, key := range []string{"from", "to", "up"} {
+			view[i], _ = sc.convertToFloat64(lookup(entry, key).Content)
+		}
+		tracks.holdView(view[0], view[1], view[2])
+	}
+	scene.setTracks(kind, tracks)
+	return nil
+}
+
+// parseAnimate reads the tracks under an entry's animate key, of the properties in props. It returns nil when the
+// entry isn't animated.
+func (sc scope) parseAnimate(entry *yaml.Node, props []trackProperty) (Tracks, error) {
+	n := lookup(entry, "animate")
+	if n == nil {
+		return nil, nil
+	}
+	if err := expectMapping(n, "animate"); err != nil {
+		return nil, err
+	}
+
+	tracks := make(Tracks)
+	err := pairs(n, func(name string, v *yaml.Node) error {
+		if err := expectSequence(v, name); err != nil {
+			return err
+		}
+
+		var track Track
+		for _, kn := range v.Content {
+			k, err := sc.parseKeyframe(kn, name)
+			if err != nil {
+				return err
 			}
+			track = append(track, k)
+		}
+
+		if err := checkTrack(props, name, track); err != nil {
+			return nodeError(v, name, err)
+		}
+		tracks[name] = track
+		return nil
+	})
+	if err != nil {
+		return nil, keyed(err, "animate")
+	}
+	return tracks, nil
+}
+
+// parseKeyframe reads a keyframe of the track of a property, whose value is a number or a list of them
+func (sc scope) parseKeyframe(n *yaml.Node, property string) (Keyframe, error) {
+	var k Keyframe
+	if err := expectMapping(n, property); err != nil {
+		return k, err
+	}
 
-			scene.Objects = append(scene.Objects, s)
+	err := pairs(n, func(key string, v *yaml.Node) (err error) {
+		switch key {
+		case "frame":
+			k.Frame, err = sc.decodeFloat(v, key)
+		case "value":
+			if v.Kind == yaml.SequenceNode {
+				k.Value, err = sc.convertToFloat64(v.Content)
+				err = keyed(err, key)
+			} else {
+				var f float64
+				f, err = sc.decodeFloat(v, key)
+				k.Value = []float64{f}
+			}
+		case "ease":
+			var name string
+			if name, err = decodeString(v, key); err == nil {
+				if k.Ease, err = parseEasing(name); err != nil {
+					err = nodeError(v, key, err)
+				}
+			}
 		default:
-			// TODO: error UI
-			//fmt.Fprintf(os.Stderr, "unknown type %v\n", k["add"])
+			err = nodeError(v, key, fmt.Errorf("%w %q", ErrUnknownKey, key))
 		}
+		return err
+	})
+	if err != nil {
+		return k, err
 	}
 
-	return &scene, nil
+	for _, key := range []string{"frame", "value"} {
+		if _, err := require(n, key); err != nil {
+			return k, err
+		}
+	}
+	return k, nil
 }
 
-func ParseMaterial(m Material, cfg map[string]interface{}) Material {
-	for k, v := range cfg {
+// parseShape sets the transform of a shape and returns its material, updated from the entry
+func (sc scope) parseShape(s Shape, m Material, entry *yaml.Node) (Material, error) {
+	if v := lookup(entry, "transform"); v != nil {
+		tf, err := sc.parseTransforms(v)
+		if err != nil {
+			return m, keyed(err, "transform")
+		}
+		s.SetTransform(tf)
+	}
+
+	if v := lookup(entry, "material"); v != nil {
+		return sc.parseMaterial(m, v)
+	}
+	return m, nil
+}
+
+// parseCamera reads a camera's image size, field of view and view transform
+func (sc scope) parseCamera(n *yaml.Node) (Camera, error) {
+	var width, height int
+	var fov float64
+	if err := firstError(
+		sc.requiredInt(n, "width", &width),
+		sc.requiredInt(n, "height", &height),
+		sc.requiredFloat(n, "field-of-view", &fov),
+	); err != nil {
+		return Camera{}, err
+	}
+	for _, size := range []struct {
+		key   string
+		value int
+	}{{"width", width}, {"height", height}} {
+		if size.value < 1 {
+			return Camera{}, invalid(lookup(n, size.key), size.key, "expected 1 or more, got %d", size.value)
+		}
+	}
+
+	var view [3][]float64
+	for i, key := range []string{"from", "to", "up"} {
+		v, err := require(n, key)
+		if err != nil {
+			return Camera{}, err
+		}
+		if view[i], err = sc.decodeFloats(v, key, 3); err != nil {
+			return Camera{}, err
+		}
+	}
+	from, to, up := view[0], view[1], view[2]
+
+	c := NewCamera(float64(width), float64(height), fov)
+	c.Transform = ViewTransform(
+		NewPoint(from[0], from[1], from[2]),
+		NewPoint(to[0], to[1], to[2]),
+		NewVector(up[0], up[1], up[2]),
+	)
+	return c, nil
+}
+
+// parseLight reads a point light's position and intensity
+func (sc scope) parseLight(n *yaml.Node) (Light, error) {
+	v, err := require(n, "at")
+	if err != nil {
+		return Light{}, err
+	}
+	at, err := sc.decodeFloats(v, "at", 3)
+	if err != nil {
+		return Light{}, err
+	}
+
+	intensity, err := sc.requiredColor(n, "intensity")
+	if err != nil {
+		return Light{}, err
+	}
+
+	return NewPointLight(*NewPoint(at[0], at[1], at[2]), intensity), nil
+}
+
+// parseMaterial returns m updated from the keys of a material definition
+func (sc scope) parseMaterial(m Material, cfg *yaml.Node) (Material, error) {
+	if err := expectMapping(cfg, "material"); err != nil {
+		return m, err
+	}
+
+	floats := map[string]*float64{
+		"shininess":        &m.Shininess,
+		"specular":         &m.Specular,
+		"ambient":          &m.Ambient,
+		"diffuse":          &m.Diffuse,
+		"reflective":       &m.Reflectivity,
+		"transparency":     &m.Transparency,
+		"refractive-index": &m.RefractiveIndex,
+		"metallic":         &m.Metallic,
+		"roughness":        &m.Roughness,
+	}
+
+	err := pairs(cfg, func(k string, v *yaml.Node) (err error) {
+		if dst, ok := floats[k]; ok {
+			*dst, err = sc.decodeFloat(v, k)
+			return err
+		}
+
 		switch k {
 		case "color":
-			rgb := ConvertToFloat64(v.([]interface{}))
-			m.Color = Color{rgb[0], rgb[1], rgb[2]}
-		case "shininess":
-			f := ConvertToFloat64([]interface{}{v})
-			m.Shininess = f[0]
-		case "specular":
-			f := ConvertToFloat64([]interface{}{v})
-			m.Specular = f[0]
-		case "ambient":
-			f := ConvertToFloat64([]interface{}{v})
-			m.Ambient = f[0]
-		case "diffuse":
-			f := ConvertToFloat64([]interface{}{v})
-			m.Diffuse = f[0]
-		case "reflective":
-			f := ConvertToFloat64([]interface{}{v})
-			m.Reflectivity = f[0]
-		case "transparency":
-			f := ConvertToFloat64([]interface{}{v})
-			m.Transparency = f[0]
-		case "refractive-index":
-			f := ConvertToFloat64([]interface{}{v})
-			m.RefractiveIndex = f[0]
+			m.Color, err = sc.parseColor(v)
+		case "glossiness":
+			var g float64
+			g, err = sc.decodeFloat(v, k)
+			m.Roughness = 1 - g
+		case "glossy-samples":
+			m.GlossySamples, err = sc.decodeInt(v, k)
+		case "shading":
+			m.Shading, err = sc.parseShading(v)
+		case "conductor":
+			m.Conductor, err = sc.parseConductor(v)
+		case "dispersion":
+			m.Dispersion, err = sc.parseDispersion(v)
 		case "pattern":
-			pDef := v.(map[string]interface{})
-
-			switch pDef["type"] {
-			case "stripes":
-				colors := pDef["colors"].([]interface{})
-				a := ConvertToFloat64(colors[0].([]interface{}))
-				b := ConvertToFloat64(colors[1].([]interface{}))
-				p := NewStripePattern(
-					Color{a[0], a[1], a[2]},
-					Color{b[0], b[1], b[2]},
-				)
-				if pDef["transform"] != nil {
-					tf := ParseTransforms(pDef["transform"].([]interface{}))
-					p.SetTransform(tf)
-				}
-				m.HasPattern = true
-				m.Pattern = p
-
-			case "checkers":
-				colors := pDef["colors"].([]interface{})
-				a := ConvertToFloat64(colors[0].([]interface{}))
-				b := ConvertToFloat64(colors[1].([]interface{}))
-				p := NewCheckersPattern(
-					Color{a[0], a[1], a[2]},
-					Color{b[0], b[1], b[2]},
-				)
-				if pDef["transform"] != nil {
-					tf := ParseTransforms(pDef["transform"].([]interface{}))
-					p.SetTransform(tf)
-				}
-				m.HasPattern = true
-				m.Pattern = &p
+			m.Pattern, err = sc.parsePattern(v)
+			m.HasPattern = m.Pattern != nil
+		case "bump":
+			if v.Kind == yaml.MappingNode {
+				err = firstError(sc.requiredFloat(v, "amount", &m.Bump), sc.optionalFloat(v, "frequency", &m.BumpFrequency))
+			} else {
+				m.Bump, err = sc.decodeFloat(v, k)
+			}
+		case "normal-map":
+			nm := &NormalMap{}
+			nm.Image, nm.File, nm.Mapper, err = parseSurfaceMap(v)
+			if err == nil {
+				err = sc.optionalFloat(v, "strength", &nm.Strength)
+			}
+			m.NormalMap = nm
+		case "bump-map":
+			bm := &BumpMap{}
+			bm.Image, bm.File, bm.Mapper, err = parseSurfaceMap(v)
+			if err == nil {
+				err = sc.optionalFloat(v, "depth", &bm.Depth)
+			}
+			m.BumpMap = bm
+		default:
+			return nodeError(v, k, ErrUnknownKey)
+		}
+		return keyed(err, k)
+	})
+
+	return m, err
+}
+
+// parseBackground reads a solid, gradient, cube-map or equirectangular background
+func (sc scope) parseBackground(cfg *yaml.Node) (Background, error) {
+	kind := "solid"
+	if v := lookup(cfg, "type"); v != nil {
+		kind = v.Value
+	}
+
+	switch kind {
+	case "solid":
+		c, err := sc.requiredColor(cfg, "color")
+		return SolidBackground{Color: c}, err
+	case "gradient":
+		bottom, err := sc.requiredColor(cfg, "bottom")
+		if err != nil {
+			return nil, err
+		}
+		top, err := sc.requiredColor(cfg, "top")
+		return GradientBackground{Bottom: bottom, Top: top}, err
+	case "cube-map":
+		files, err := require(cfg, "files")
+		if err != nil {
+			return nil, err
+		}
+		if err := expectMapping(files, "files"); err != nil {
+			return nil, err
+		}
+
+		var b CubeMapBackground
+		for face, name := range map[CubeFace]string{
+			CubeLeft: "left", CubeFront: "front", CubeRight: "right", CubeBack: "back", CubeUp: "up", CubeDown: "down",
+		} {
+			if b.Faces[face], b.Files[face], err = loadImageAt(files, name); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case "equirectangular":
+		img, file, err := loadImageAt(cfg, "file")
+		return EquirectangularBackground{Image: img, File: file}, err
+	}
+
+	return nil, nodeError(lookup(cfg, "type"), "type", fmt.Errorf("%w %q", ErrUnknownType, kind))
+}
+
+// parseEnvironment reads an environment light from an equirectangular image, usually a Radiance HDR file, with
+// optional intensity, rotation about the y axis in radians, and number of samples
+func (sc scope) parseEnvironment(cfg *yaml.Node) (*EnvironmentLight, error) {
+	img, file, err := loadImageAt(cfg, "file")
+	if err != nil {
+		return nil, err
+	}
+
+	e := NewEnvironmentLight(img)
+	e.File = file
+	return e, firstError(
+		sc.optionalFloat(cfg, "intensity", &e.Intensity),
+		sc.optionalFloat(cfg, "rotation", &e.Rotation),
+		sc.optionalInt(cfg, "samples", &e.Samples),
+	)
+}
+
+// parsePattern builds a pattern from its definition. Patterns that wrap other patterns read them recursively from
+// the pattern key.
+func (sc scope) parsePattern(pDef *yaml.Node) (Pattern, error) {
+	if err := expectMapping(pDef, "pattern"); err != nil {
+		return nil, err
+	}
+
+	kind, err := requiredString(pDef, "type")
+	if err != nil {
+		return nil, err
+	}
+
+	var p Pattern
 
+	switch kind {
+	case "stripes", "checkers", "gradient", "rings", "radial-gradient", "marble", "wood", "worley":
+		p, err = sc.parseTwoColorPattern(kind, pDef)
+	case "map":
+		var img *Canvas
+		var file string
+		var mapper UVMapper
+		if img, file, err = loadImageAt(pDef, "file"); err != nil {
+			return nil, err
+		}
+		if mapper, err = parseOptionalMapper(pDef); err != nil {
+			return nil, err
+		}
+		if mapper == nil {
+			mapper = SphericalMap{}
+		}
+		t := NewTextureMapPattern(mapper, img)
+		t.File = file
+		p = t
+	case "triplanar":
+		t := NewTriplanarPattern(nil)
+		if v := lookup(pDef, "pattern"); v != nil {
+			if t.Pattern, err = sc.parsePattern(v); err != nil {
+				return nil, err
 			}
+		} else if t.Image, t.File, err = loadImageAt(pDef, "file"); err != nil {
+			return nil, err
+		}
+		err = sc.optionalFloat(pDef, "sharpness", &t.Sharpness)
+		p = t
+	case "blend":
+		var patterns []Pattern
+		if patterns, err = sc.parsePatternList(pDef, "patterns", 2); err != nil {
+			return nil, err
+		}
+		bp := NewBlendedPattern(patterns[0], patterns[1])
+		err = sc.optionalFloat(pDef, "weight", &bp.Weight)
+		p = bp
+	case "mask":
+		var patterns []Pattern
+		var mask Pattern
+		if patterns, err = sc.parsePatternList(pDef, "patterns", 2); err != nil {
+			return nil, err
+		}
+		if mask, err = sc.requiredPatternOrColor(pDef, "mask"); err != nil {
+			return nil, err
+		}
+		p = NewMaskPattern(patterns[0], patterns[1], mask)
+	case "solid":
+		var c Color
+		if c, err = sc.requiredColor(pDef, "color"); err != nil {
+			return nil, err
+		}
+		p = NewSolidPattern(c)
+	case "perturbed":
+		var inner Pattern
+		if inner, err = sc.requiredPattern(pDef, "pattern"); err != nil {
+			return nil, err
+		}
+		pp := NewPerturbedPattern(inner, 0.2)
+		err = sc.parseNoiseParameters(pDef, "scale", &pp.Frequency, &pp.Scale, &pp.Octaves, &pp.Noise)
+		p = pp
+	default:
+		return nil, nodeError(lookup(pDef, "type"), "type", fmt.Errorf("%w %q", ErrUnknownType, kind))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if v := lookup(pDef, "transform"); v != nil {
+		tf, err := sc.parseTransforms(v)
+		if err != nil {
+			return nil, keyed(err, "transform")
+		}
+		p.SetTransform(tf)
+	}
+
+	return p, nil
+}
+
+// parseTwoColorPattern builds one of the patterns that alternate or blend between two colors, given by the colors
+// key, each of which may be replaced by a nested pattern
+func (sc scope) parseTwoColorPattern(kind string, pDef *yaml.Node) (Pattern, error) {
+	colors, err := require(pDef, "colors")
+	if err != nil {
+		return nil, err
+	}
+	a, b, pa, pb, err := sc.parsePatternSlots(colors)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case "stripes":
+		s := NewStripePattern(a, b)
+		s.PatternA, s.PatternB = pa, pb
+		return s, nil
+	case "checkers":
+		c := NewCheckersPattern(a, b)
+		c.PatternA, c.PatternB = pa, pb
+		return &c, nil
+	case "gradient":
+		g := NewGradientPattern(a, b)
+		g.PatternA, g.PatternB = pa, pb
+		return g, nil
+	case "rings":
+		r := NewRingPattern(a, b)
+		r.PatternA, r.PatternB = pa, pb
+		return r, nil
+	case "radial-gradient":
+		r := NewRadialGradientPattern(a, b)
+		r.PatternA, r.PatternB = pa, pb
+		return r, nil
+	case "marble":
+		m := NewMarblePattern(a, b)
+		m.PatternA, m.PatternB = pa, pb
+		return m, sc.parseNoiseParameters(pDef, "turbulence", &m.Frequency, &m.Turbulence, &m.Octaves, &m.Noise)
+	case "wood":
+		w := NewWoodPattern(a, b)
+		w.PatternA, w.PatternB = pa, pb
+		return w, sc.parseNoiseParameters(pDef, "turbulence", &w.Frequency, &w.Turbulence, &w.Octaves, &w.Noise)
+	}
+
+	w := NewWorleyPattern(a, b)
+	w.PatternA, w.PatternB = pa, pb
+	var seed int
+	err = firstError(
+		sc.optionalFloat(pDef, "frequency", &w.Frequency),
+		sc.optionalInt(pDef, "seed", &seed),
+		optionalBool(pDef, "edges", &w.Edges),
+	)
+	w.Seed = int64(seed)
+	return w, err
+}
 
-			//m.Pattern = f[0]
+// ParseMapper returns the UV mapper with the given name
+func ParseMapper(v *yaml.Node) (UVMapper, error) {
+	switch v.Value {
+	case "spherical":
+		return SphericalMap{}, nil
+	case "planar":
+		return PlanarMap{}, nil
+	case "cylindrical":
+		return CylindricalMap{}, nil
+	case "cube", "cubic":
+		return CubicMap{}, nil
+	}
+
+	return nil, nodeError(v, "mapping", fmt.Errorf("%w %q", ErrUnknownType, v.Value))
+}
+
+// parseOptionalMapper reads the mapping key of a definition, returning nil when it's absent
+func parseOptionalMapper(cfg *yaml.Node) (UVMapper, error) {
+	v := lookup(cfg, "mapping")
+	if v == nil {
+		return nil, nil
+	}
+	return ParseMapper(v)
+}
+
+// parseSurfaceMap loads the image of a normal or bump map along with its mapping, which is left nil so the shape's own
+// is used unless one is given
+func parseSurfaceMap(cfg *yaml.Node) (*Canvas, string, UVMapper, error) {
+	if err := expectMapping(cfg, ""); err != nil {
+		return nil, "", nil, err
+	}
+
+	img, file, err := loadImageAt(cfg, "file")
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	mapper, err := parseOptionalMapper(cfg)
+	return img, file, mapper, err
+}
+
+// loadImageAt loads the image file named by key
+func loadImageAt(cfg *yaml.Node, key string) (*Canvas, string, error) {
+	file, err := requiredString(cfg, key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	img, err := LoadImage(file)
+	if err == nil {
+		err = checkImage(img, file)
+	}
+	if err != nil {
+		return nil, "", nodeError(lookup(cfg, key), key, err)
+	}
+	return img, file, nil
+}
+
+// checkImage reports an image without any pixels, which can't be sampled
+func checkImage(img *Canvas, file string) error {
+	if img.Width == 0 || img.Height == 0 {
+		return fmt.Errorf("%w: %s has no pixels", ErrInvalidValue, file)
+	}
+	return nil
+}
+
+// parseNoiseParameters reads the settings shared by noise driven patterns. The strength of the noise is read from
+// strengthKey, which is turbulence but for perturbed patterns, whose strength is their scale; the other key is an
+// error.
+func (sc scope) parseNoiseParameters(pDef *yaml.Node, strengthKey string, frequency, strength *float64, octaves *int, noise **Perlin) error {
+	if other := otherStrengthKey(strengthKey); lookup(pDef, other) != nil {
+		return invalid(lookup(pDef, other), other, "the strength of this pattern's noise is its %s, not %s", strengthKey, other)
+	}
+
+	seed := -1
+	err := firstError(
+		sc.optionalFloat(pDef, "frequency", frequency),
+		sc.optionalFloat(pDef, strengthKey, strength),
+		sc.optionalInt(pDef, "octaves", octaves),
+		sc.optionalInt(pDef, "seed", &seed),
+	)
+	if lookup(pDef, "seed") != nil {
+		*noise = NewPerlin(int64(seed))
+	}
+	return err
+}
+
+// otherStrengthKey returns the key that gives the strength of the noise of the patterns that don't use key
+func otherStrengthKey(key string) string {
+	if key == "scale" {
+		return "turbulence"
+	}
+	return "scale"
+}
+
+// parsePatternSlots reads the two entries of a colors list, each of which is either an [r, g, b] triple or the
+// definition of a nested pattern
+func (sc scope) parsePatternSlots(colors *yaml.Node) (a, b Color, pa, pb Pattern, err error) {
+	if err = expectSequence(colors, "colors"); err != nil {
+		return
+	}
+	if len(colors.Content) != 2 {
+		err = invalid(colors, "colors", "expected 2 colors or patterns, got %d", len(colors.Content))
+		return
+	}
+
+	slot := func(v *yaml.Node) (Color, Pattern, error) {
+		v = resolveAlias(v)
+		if v.Kind == yaml.MappingNode {
+			p, err := sc.parsePattern(v)
+			return Black, p, err
+		}
+		c, err := sc.parseColor(v)
+		return c, nil, keyed(err, "colors")
+	}
+
+	if a, pa, err = slot(colors.Content[0]); err != nil {
+		return
+	}
+	b, pb, err = slot(colors.Content[1])
+	return
+}
+
+// parsePatternList reads a list of exactly count patterns or colors
+func (sc scope) parsePatternList(pDef *yaml.Node, key string, count int) ([]Pattern, error) {
+	v, err := require(pDef, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectSequence(v, key); err != nil {
+		return nil, err
+	}
+	if len(v.Content) != count {
+		return nil, invalid(v, key, "expected %d patterns, got %d", count, len(v.Content))
+	}
+
+	patterns := make([]Pattern, count)
+	for i, item := range v.Content {
+		if patterns[i], err = sc.parsePatternOrColor(resolveAlias(item), key); err != nil {
+			return nil, err
 		}
 	}
+	return patterns, nil
+}
+
+// requiredPattern reads the pattern defined under key, which must be present
+func (sc scope) requiredPattern(cfg *yaml.Node, key string) (Pattern, error) {
+	v, err := require(cfg, key)
+	if err != nil {
+		return nil, err
+	}
+	return sc.parsePattern(v)
+}
+
+// requiredPatternOrColor reads the pattern or color under key, which must be present
+func (sc scope) requiredPatternOrColor(cfg *yaml.Node, key string) (Pattern, error) {
+	v, err := require(cfg, key)
+	if err != nil {
+		return nil, err
+	}
+	return sc.parsePatternOrColor(v, key)
+}
+
+// parsePatternOrColor reads a pattern definition, treating a plain [r, g, b] triple as a solid pattern
+func (sc scope) parsePatternOrColor(v *yaml.Node, key string) (Pattern, error) {
+	if v.Kind == yaml.MappingNode {
+		return sc.parsePattern(v)
+	}
+
+	c, err := sc.parseColor(v)
+	if err != nil {
+		return nil, keyed(err, key)
+	}
+	return NewSolidPattern(c), nil
+}
+
+// parseColor reads an [r, g, b] triple
+func (sc scope) parseColor(v *yaml.Node) (Color, error) {
+	rgb, err := sc.decodeFloats(v, "", 3)
+	if err != nil {
+		return Color{}, err
+	}
+	return Color{rgb[0], rgb[1], rgb[2]}, nil
+}
 
-	return m
+// requiredColor reads the [r, g, b] triple under key, which must be present
+func (sc scope) requiredColor(cfg *yaml.Node, key string) (Color, error) {
+	v, err := require(cfg, key)
+	if err != nil {
+		return Color{}, err
+	}
+
+	c, err := sc.parseColor(v)
+	return c, keyed(err, key)
 }
 
-func ParseTransforms(transforms []interface{}) Matrix {
+// requiredFloat sets *dst to the value of key in the mapping node n, which must be present
+func (sc scope) requiredFloat(n *yaml.Node, key string, dst *float64) error {
+	v, err := require(n, key)
+	if err != nil {
+		return err
+	}
+	*dst, err = sc.decodeFloat(v, key)
+	return err
+}
+
+// requiredInt sets *dst to the value of key in the mapping node n, which must be present
+func (sc scope) requiredInt(n *yaml.Node, key string, dst *int) error {
+	v, err := require(n, key)
+	if err != nil {
+		return err
+	}
+	*dst, err = sc.decodeInt(v, key)
+	return err
+}
+
+// parseDispersion reads either Cauchy coefficients (A, B and optionally C) or the Sellmeier B and C terms
+func (sc scope) parseDispersion(cfg *yaml.Node) (Dispersion, error) {
+	var d Dispersion
+	if err := expectMapping(cfg, "dispersion"); err != nil {
+		return d, err
+	}
+
+	model, err := requiredString(cfg, "model")
+	if err != nil {
+		return d, err
+	}
+
+	// reads up to three terms from the list under key
+	terms := func(key string, dst *[3]float64) error {
+		v, err := require(cfg, key)
+		if err != nil {
+			return err
+		}
+		if err := expectSequence(v, key); err != nil {
+			return err
+		}
+		if len(v.Content) < 2 || len(v.Content) > 3 {
+			return invalid(v, key, "expected 2 or 3 numbers, got %d", len(v.Content))
+		}
+		f, err := sc.convertToFloat64(v.Content)
+		copy(dst[:], f)
+		return keyed(err, key)
+	}
+
+	switch model {
+	case "cauchy":
+		d.Model = CauchyDispersion
+		return d, terms("coefficients", &d.B)
+	case "sellmeier":
+		d.Model = SellmeierDispersion
+		return d, firstError(terms("b", &d.B), terms("c", &d.C))
+	}
+
+	return d, nodeError(lookup(cfg, "model"), "model", fmt.Errorf("%w %q", ErrUnknownType, model))
+}
+
+// parseShading reads a shading model given either by name or as a map with a type and its parameters
+func (sc scope) parseShading(v *yaml.Node) (ShadingModel, error) {
+	cfg, kind := v, v.Value
+	if v.Kind == yaml.MappingNode {
+		var err error
+		if kind, err = requiredString(v, "type"); err != nil {
+			return nil, err
+		}
+		v = lookup(v, "type")
+	}
+
+	switch kind {
+	case "phong":
+		return PhongShading{}, nil
+	case "blinn-phong":
+		return BlinnPhongShading{}, nil
+	case "lambert":
+		return LambertShading{}, nil
+	case "pbr":
+		return MicrofacetShading{}, nil
+	case "toon":
+		s := ToonShading{}
+		if cfg.Kind != yaml.MappingNode {
+			return s, nil
+		}
+		err := firstError(
+			sc.optionalInt(cfg, "bands", &s.Bands),
+			sc.optionalFloat(cfg, "outline", &s.Outline),
+		)
+		if err == nil && lookup(cfg, "outline-color") != nil {
+			s.OutlineColor, err = sc.requiredColor(cfg, "outline-color")
+		}
+		return s, err
+	}
+
+	return nil, nodeError(v, "shading", fmt.Errorf("%w %q", ErrUnknownType, kind))
+}
+
+// parseConductor reads either the name of one of the ConductorPresets or a map of per channel n and k values
+func (sc scope) parseConductor(v *yaml.Node) (ComplexIOR, error) {
+	if v.Kind == yaml.ScalarNode {
+		ior, ok := ConductorPresets[v.Value]
+		if !ok {
+			return ComplexIOR{}, nodeError(v, "conductor", fmt.Errorf("%w %q", ErrUnknownType, v.Value))
+		}
+		return ior, nil
+	}
+
+	n, err := sc.requiredColor(v, "n")
+	if err != nil {
+		return ComplexIOR{}, err
+	}
+	k, err := sc.requiredColor(v, "k")
+	return ComplexIOR{N: n, K: k}, err
+}
+
+// transformArgs gives the number of arguments each named transform takes
+var transformArgs = map[string]int{
+	"translate": 3,
+	"scale":     3,
+	"rotate-x":  1,
+	"rotate-y":  1,
+	"rotate-z":  1,
+	"shear":     6,
+}
+
+// parseTransforms reads a list of transforms, which apply in the order listed
+func (sc scope) parseTransforms(transforms *yaml.Node) (Matrix, error) {
 	result := IdentityMatrix
+	if err := expectSequence(transforms, ""); err != nil {
+		return result, err
+	}
 
 	// Apply transforms in reverse order
-	for i := len(transforms) - 1; i >= 0; i-- {
-		transform := transforms[i].([]interface{})
-		switch transform[0] {
+	for i := len(transforms.Content) - 1; i >= 0; i-- {
+		transform := resolveAlias(transforms.Content[i])
+		if err := expectSequence(transform, ""); err != nil {
+			return result, err
+		}
+		if len(transform.Content) == 0 {
+			return result, invalid(transform, "", "expected the name of a transform")
+		}
+
+		name := transform.Content[0].Value
+		count, ok := transformArgs[name]
+		if !ok {
+			return result, nodeError(transform.Content[0], "", fmt.Errorf("%w %q", ErrUnknownTransform, name))
+		}
+		if len(transform.Content)-1 != count {
+			return result, invalid(transform, "", "%s expects %d numbers, got %d", name, count, len(transform.Content)-1)
+		}
+
+		f, err := sc.convertToFloat64(transform.Content[1:])
+		if err != nil {
+			return result, err
+		}
+
+		switch name {
 		case "translate":
-			xyz := ConvertToFloat64(transform[1:])
-			result = result.Multiply(NewTranslation(xyz[0], xyz[1], xyz[2]))
+			result = result.Multiply(NewTranslation(f[0], f[1], f[2]))
 		case "rotate-x":
-			result = result.Multiply(RotationX(transform[1].(float64)))
+			result = result.Multiply(RotationX(f[0]))
 		case "rotate-y":
-			result = result.Multiply(RotationY(transform[1].(float64)))
+			result = result.Multiply(RotationY(f[0]))
 		case "rotate-z":
-			result = result.Multiply(RotationZ(transform[1].(float64)))
+			result = result.Multiply(RotationZ(f[0]))
 		case "scale":
-			f := ConvertToFloat64(transform[1:])
-			result = result.Multiply(
-				Scaling(f[0], f[1], f[2]),
-			)
-		default:
-			// TODO: error UI
-			//fmt.Fprintf(os.Stderr, "Unknown transform: "+transform[0].(string))
+			result = result.Multiply(Scaling(f[0], f[1], f[2]))
+		case "shear":
+			result = result.Multiply(Shearing(f[0], f[1], f[2], f[3], f[4], f[5]))
 		}
 	}
 
-	return result
+	return result, nil
+}
+
+// resolvePaths rewrites every relative path given by a file key, or listed in a files map, at any depth, to be
+// relative to dir instead
+func resolvePaths(n *yaml.Node, dir string) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i].Value, n.Content[i+1]
+			if key == "file" && value.Kind == yaml.ScalarNode && !filepath.IsAbs(value.Value) {
+				value.Value = filepath.Join(dir, value.Value)
+			} else if key == "files" && value.Kind == yaml.MappingNode {
+				for j := 1; j < len(value.Content); j += 2 {
+					if f := value.Content[j]; f.Kind == yaml.ScalarNode && !filepath.IsAbs(f.Value) {
+						f.Value = filepath.Join(dir, f.Value)
+					}
+				}
+			} else {
+				resolvePaths(value, dir)
+			}
+		}
+	case yaml.SequenceNode:
+		for _, value := range n.Content {
+			resolvePaths(value, dir)
+		}
+	}
 }
 
-func ConvertToFloat64(values []interface{}) (results []float64) {
+// convertToFloat64 reads a list of numbers
+func (sc scope) convertToFloat64(values []*yaml.Node) (results []float64, err error) {
 	for _, v := range values {
-		if _, ok := v.(int); ok {
-			results = append(results, float64(v.(int)))
-		} else {
-			results = append(results, v.(float64))
+		f, err := sc.decodeFloat(resolveAlias(v), "")
+		if err != nil {
+			return nil, err
 		}
+		results = append(results, f)
 	}
-	return results
+	return results, nil
+}
+
+// The exported parsers read a part of a scene on its own, outside of any scene file, so the expressions within it
+// can't refer to defines
+
+// ParseCamera reads a camera's image size, field of view and view transform
+func ParseCamera(n *yaml.Node) (Camera, error) {
+	return scope{}.parseCamera(n)
+}
+
+// ParseLight reads a point light's position and intensity
+func ParseLight(n *yaml.Node) (Light, error) {
+	return scope{}.parseLight(n)
+}
+
+// ParseMaterial returns m updated from the keys of a material definition
+func ParseMaterial(m Material, cfg *yaml.Node) (Material, error) {
+	return scope{}.parseMaterial(m, cfg)
+}
+
+// ParseBackground reads a solid, gradient, cube-map or equirectangular background
+func ParseBackground(cfg *yaml.Node) (Background, error) {
+	return scope{}.parseBackground(cfg)
+}
+
+// ParseEnvironment reads an environment light from an equirectangular image
+func ParseEnvironment(cfg *yaml.Node) (*EnvironmentLight, error) {
+	return scope{}.parseEnvironment(cfg)
+}
+
+// ParsePattern builds a pattern from its definition
+func ParsePattern(pDef *yaml.Node) (Pattern, error) {
+	return scope{}.parsePattern(pDef)
+}
+
+// ParseColor reads an [r, g, b] triple
+func ParseColor(v *yaml.Node) (Color, error) {
+	return scope{}.parseColor(v)
+}
+
+// ParseDispersion reads either Cauchy coefficients or the Sellmeier B and C terms
+func ParseDispersion(cfg *yaml.Node) (Dispersion, error) {
+	return scope{}.parseDispersion(cfg)
+}
+
+// ParseShading reads a shading model given either by name or as a map with a type and its parameters
+func ParseShading(v *yaml.Node) (ShadingModel, error) {
+	return scope{}.parseShading(v)
+}
+
+// ParseConductor reads either the name of one of the ConductorPresets or a map of per channel n and k values
+func ParseConductor(v *yaml.Node) (ComplexIOR, error) {
+	return scope{}.parseConductor(v)
+}
+
+// ParseTransforms reads a list of transforms, which apply in the order listed
+func ParseTransforms(transforms *yaml.Node) (Matrix, error) {
+	return scope{}.parseTransforms(transforms)
+}
+
+// ConvertToFloat64 reads a list of numbers
+func ConvertToFloat64(values []*yaml.Node) ([]float64, error) {
+	return scope{}.convertToFloat64(values)
 }
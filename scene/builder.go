@@ -0,0 +1,299 @@
+// Package scene builds jtracer scenes in Go, as scene files do without the boilerplate of setting up each shape by
+// hand:
+//
+//	s, err := scene.New().
+//		Camera(400, 300, math.Pi/3).From(0, 1.5, -5).To(0, 1, 0).
+//		Light(-10, 10, -10, jtracer.White).
+//		DefineMaterial("red", red).
+//		Plane().
+//		Sphere().Translate(-0.5, 1, 0.5).Material("red").
+//		Build()
+//
+// Methods that change an object apply to the one added last. Mistakes are kept until Build, which reports them
+// along with the problems jtracer.LintScene finds in the built scene.
+package scene
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"jtracer"
+)
+
+var (
+	ErrNoObject        = errors.New("no object to change")
+	ErrUnknownMaterial = errors.New("unknown material")
+	ErrNoCameraToAim   = errors.New("no camera to aim")
+)
+
+// Builder collects the parts of a scene. The zero value isn't ready to use; start with New.
+type Builder struct {
+	scene     jtracer.Scene
+	hasCamera bool
+	from, to  [3]float64
+	up        [3]float64
+	materials map[string]jtracer.Material
+	errs      []error
+}
+
+// New starts an empty scene
+func New() *Builder {
+	return &Builder{
+		to:        [3]float64{0, 0, -1},
+		up:        [3]float64{0, 1, 0},
+		materials: make(map[string]jtracer.Material),
+	}
+}
+
+// Title sets the title of the scene
+func (b *Builder) Title(title string) *Builder {
+	b.scene.Description.Title = title
+	return b
+}
+
+// Describe sets the description and author of the scene
+func (b *Builder) Describe(description, author string) *Builder {
+	b.scene.Description.Description, b.scene.Description.Author = description, author
+	return b
+}
+
+// Camera sets the size of the image and the field of view, in radians. It looks from the origin down the negative z
+// axis until From and To aim it.
+func (b *Builder) Camera(width, height int, fov float64) *Builder {
+	if width <= 0 || height <= 0 || fov <= 0 {
+		return b.fail(fmt.Errorf("camera: %w: width, height and field of view must be above 0", jtracer.ErrInvalidValue))
+	}
+
+	b.scene.Camera = jtracer.NewCamera(float64(width), float64(height), fov)
+	b.hasCamera = true
+	return b
+}
+
+// From moves the camera to a point
+func (b *Builder) From(x, y, z float64) *Builder {
+	return b.aim(&b.from, x, y, z)
+}
+
+// To points the camera at a point
+func (b *Builder) To(x, y, z float64) *Builder {
+	return b.aim(&b.to, x, y, z)
+}
+
+// Up sets which way is up for the camera, (0, 1, 0) by default
+func (b *Builder) Up(x, y, z float64) *Builder {
+	return b.aim(&b.up, x, y, z)
+}
+
+func (b *Builder) aim(v *[3]float64, x, y, z float64) *Builder {
+	if !b.hasCamera {
+		return b.fail(ErrNoCameraToAim)
+	}
+	*v = [3]float64{x, y, z}
+	return b
+}
+
+// Light places the point light
+func (b *Builder) Light(x, y, z float64, intensity jtracer.Color) *Builder {
+	b.scene.Light = jtracer.NewPointLight(*jtracer.NewPoint(x, y, z), intensity)
+	return b
+}
+
+// Background sets what rays that miss every object see
+func (b *Builder) Background(background jtracer.Background) *Builder {
+	b.scene.Background = background
+	return b
+}
+
+// Environment lights the scene from every direction
+func (b *Builder) Environment(env *jtracer.EnvironmentLight) *Builder {
+	b.scene.Environment = env
+	return b
+}
+
+// DefineMaterial names a material for Material to use, replacing any defined before under the same name
+func (b *Builder) DefineMaterial(name string, m jtracer.Material) *Builder {
+	b.materials[name] = m
+	return b
+}
+
+// Sphere adds a unit sphere at the origin
+func (b *Builder) Sphere() *Builder {
+	return b.Add(jtracer.NewSphere())
+}
+
+// Plane adds the xz plane
+func (b *Builder) Plane() *Builder {
+	return b.Add(jtracer.NewPlane())
+}
+
+// Add adds a shape made elsewhere, for the methods that follow to change
+func (b *Builder) Add(s jtracer.Shape) *Builder {
+	b.scene.Objects = append(b.scene.Objects, s)
+	return b
+}
+
+// Transform applies m to the last object, after any transforms it already has. The transforms given an object are
+// applied in the order they are called, so Scale(2, 2, 2).Translate(1, 0, 0) scales the object and then moves it.
+func (b *Builder) Transform(m jtracer.Matrix) *Builder {
+	s, ok := b.last("transform")
+	if ok {
+		s.SetTransform(m.Multiply(s.GetTransform()))
+	}
+	return b
+}
+
+// Translate moves the last object by x, y and z along the axes, after any transforms it already has
+func (b *Builder) Translate(x, y, z float64) *Builder {
+	return b.Transform(jtracer.NewTranslation(x, y, z))
+}
+
+// Scale stretches the last object by x, y and z along the axes about the origin, after any transforms it already has
+func (b *Builder) Scale(x, y, z float64) *Builder {
+	return b.Transform(jtracer.Scaling(x, y, z))
+}
+
+// RotateX turns the last object about the x axis by rad radians, after any transforms it already has
+func (b *Builder) RotateX(rad float64) *Builder {
+	return b.Transform(jtracer.RotationX(rad))
+}
+
+// RotateY turns the last object about the y axis by rad radians, after any transforms it already has
+func (b *Builder) RotateY(rad float64) *Builder {
+	return b.Transform(jtracer.RotationY(rad))
+}
+
+// RotateZ turns the last object about the z axis by rad radians, after any transforms it already has
+func (b *Builder) RotateZ(rad float64) *Builder {
+	return b.Transform(jtracer.RotationZ(rad))
+}
+
+// Shear moves each coordinate of the last object in proportion to the other two, xy being how far x moves for y and
+// so on, after any transforms it already has
+func (b *Builder) Shear(xy, xz, yx, yz, zx, zy float64) *Builder {
+	return b.Transform(jtracer.Shearing(xy, xz, yx, yz, zx, zy))
+}
+
+// Material gives the last object the material defined under name
+func (b *Builder) Material(name string) *Builder {
+	m, ok := b.materials[name]
+	if !ok {
+		if _, ok := b.last("material"); ok {
+			b.fail(fmt.Errorf("object %d: %w %q", len(b.scene.Objects)-1, ErrUnknownMaterial, name))
+		}
+		return b
+	}
+	return b.SetMaterial(m)
+}
+
+// SetMaterial gives the last object a material
+func (b *Builder) SetMaterial(m jtracer.Material) *Builder {
+	return b.EditMaterial(func(old *jtracer.Material) { *old = m })
+}
+
+// EditMaterial changes the material of the last object
+func (b *Builder) EditMaterial(edit func(m *jtracer.Material)) *Builder {
+	s, ok := b.last("material")
+	if !ok {
+		return b
+	}
+
+	switch s := s.(type) {
+	case *jtracer.Sphere:
+		edit(&s.Material)
+	case *jtracer.Plane:
+		edit(&s.Material)
+	default:
+		b.fail(fmt.Errorf("object %d: %w: can't change the material of a %T", len(b.scene.Objects)-1, jtracer.ErrInvalidValue, s))
+	}
+	return b
+}
+
+// Color sets the color of the last object's material
+func (b *Builder) Color(c jtracer.Color) *Builder {
+	return b.EditMaterial(func(m *jtracer.Material) { m.Color = c })
+}
+
+// Pattern sets the pattern of the last object's material
+func (b *Builder) Pattern(p jtracer.Pattern) *Builder {
+	return b.EditMaterial(func(m *jtracer.Material) { m.Pattern, m.HasPattern = p, true })
+}
+
+// last returns the object added last, for a change named what
+func (b *Builder) last(what string) (jtracer.Shape, bool) {
+	if len(b.scene.Objects) == 0 {
+		b.fail(fmt.Errorf("%s: %w", what, ErrNoObject))
+		return nil, false
+	}
+	return b.scene.Objects[len(b.scene.Objects)-1], true
+}
+
+func (b *Builder) fail(err error) *Builder {
+	b.errs = append(b.errs, err)
+	return b
+}
+
+// Build returns the scene, or an *Error listing the mistakes made building it and the errors jtracer.LintScene finds
+// in it. Warnings from LintScene don't stop the scene being built. The spheres and planes of the scene are copies, so
+// that changing the builder afterwards leaves it as it was; shapes of other kinds passed to Add are shared.
+func (b *Builder) Build() (*jtracer.Scene, error) {
+	scene := b.scene
+	scene.Objects = make([]jtracer.Shape, len(b.scene.Objects))
+	for i, s := range b.scene.Objects {
+		scene.Objects[i] = copyShape(s)
+	}
+	if b.hasCamera {
+		scene.Camera.Transform = jtracer.ViewTransform(
+			jtracer.NewPoint(b.from[0], b.from[1], b.from[2]),
+			jtracer.NewPoint(b.to[0], b.to[1], b.to[2]),
+			jtracer.NewVector(b.up[0], b.up[1], b.up[2]),
+		)
+	}
+
+	errs := append([]error(nil), b.errs...)
+	for _, p := range jtracer.LintScene(&scene) {
+		if p.Severity == jtracer.Error {
+			errs = append(errs, p.Err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, &Error{errs}
+	}
+	return &scene, nil
+}
+
+// copyShape returns a copy of a sphere or plane, or s itself for any other kind of shape
+func copyShape(s jtracer.Shape) jtracer.Shape {
+	switch s := s.(type) {
+	case *jtracer.Sphere:
+		c := *s
+		return &c
+	case *jtracer.Plane:
+		c := *s
+		return &c
+	}
+	return s
+}
+
+// Error lists what stopped a scene from being built
+type Error struct {
+	Errs []error
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return "building scene: " + strings.Join(msgs, "; ")
+}
+
+// Is reports whether any of the errors is target, for errors.Is
+func (e *Error) Is(target error) bool {
+	for _, err := range e.Errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package scene
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"jtracer"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	red := jtracer.NewMaterial()
+	red.Color = jtracer.Color{Red: 1}
+
+	s, err := New().
+		Title("two spheres").
+		Camera(100, 50, math.Pi/3).From(0, 1.5, -5).To(0, 1, 0).
+		Light(-10, 10, -10, jtracer.White).
+		DefineMaterial("red", red).
+		Plane().
+		Sphere().Scale(2, 2, 2).Translate(1, 2, 3).Material("red").
+		Sphere().Material("red").EditMaterial(func(m *jtracer.Material) { m.Reflectivity = 0.5 }).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if s.Description.Title != "two spheres" || s.Camera.Hsize != 100 || s.Camera.Vsize != 50 {
+		t.Errorf("Build() = %q, %v x %v, want %q, 100 x 50", s.Description.Title, s.Camera.Hsize, s.Camera.Vsize, "two spheres")
+	}
+	wantView := jtracer.ViewTransform(jtracer.NewPoint(0, 1.5, -5), jtracer.NewPoint(0, 1, 0), jtracer.NewVector(0, 1, 0))
+	if !matrixEquals(s.Camera.Transform, wantView) {
+		t.Errorf("Camera.Transform = %v, want %v", s.Camera.Transform, wantView)
+	}
+	if len(s.Objects) != 3 {
+		t.Fatalf("len(Objects) = %d, want 3", len(s.Objects))
+	}
+
+	// transforms apply in the order they are given
+	wantTransform := jtracer.NewTranslation(1, 2, 3).Multiply(jtracer.Scaling(2, 2, 2))
+	if !matrixEquals(s.Objects[1].GetTransform(), wantTransform) {
+		t.Errorf("Objects[1] transform = %v, want %v", s.Objects[1].GetTransform(), wantTransform)
+	}
+	if got := s.Objects[1].GetMaterial(); got.Color != red.Color || got.Reflectivity != 0 {
+		t.Errorf("Objects[1] material = %v, want the red material", got)
+	}
+	if got := s.Objects[2].GetMaterial(); got.Color != red.Color || got.Reflectivity != 0.5 {
+		t.Errorf("Objects[2] material = %v, want the red material, edited", got)
+	}
+	if got := s.Objects[0].GetMaterial(); got.Color != jtracer.White {
+		t.Errorf("Objects[0] material = %v, want the default", got)
+	}
+}
+
+func TestBuilder_Build_Errors(t *testing.T) {
+	lit := func() *Builder { return New().Camera(10, 10, 1).Light(0, 10, 0, jtracer.White) }
+
+	tests := []struct {
+		name    string
+		builder *Builder
+		want    []error
+	}{
+		{
+			name:    "nothing to see it by",
+			builder: New().Sphere(),
+			want:    []error{jtracer.ErrNoCamera, jtracer.ErrNoLight},
+		},
+		{
+			name:    "a transform before any object",
+			builder: lit().Translate(1, 0, 0).Sphere(),
+			want:    []error{ErrNoObject},
+		},
+		{
+			name:    "an unknown material",
+			builder: lit().Sphere().Material("glass"),
+			want:    []error{ErrUnknownMaterial},
+		},
+		{
+			name:    "a camera that can't see",
+			builder: New().Camera(0, 10, 1).From(0, 0, -5).Light(0, 10, 0, jtracer.White),
+			want:    []error{jtracer.ErrInvalidValue, ErrNoCameraToAim, jtracer.ErrNoCamera},
+		},
+		{
+			name:    "an object squashed flat",
+			builder: lit().Sphere().Scale(1, 0, 1),
+			want:    []error{jtracer.ErrSingularTransform},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := tt.builder.Build()
+			var be *Error
+			if !errors.As(err, &be) {
+				t.Fatalf("Build() = %v, %v, want an *Error", s, err)
+			}
+			if len(be.Errs) != len(tt.want) {
+				t.Fatalf("Build() error = %v, want %v", err, tt.want)
+			}
+			for i, want := range tt.want {
+				if !errors.Is(be.Errs[i], want) {
+					t.Errorf("Build() error %d = %v, want %v", i, be.Errs[i], want)
+				}
+			}
+			if !errors.Is(err, tt.want[0]) {
+				t.Errorf("errors.Is(%v, %v) = false, want true", err, tt.want[0])
+			}
+		})
+	}
+}
+
+func TestBuilder_Build_Renders(t *testing.T) {
+	s, err := New().
+		Camera(11, 11, math.Pi/2).From(0, 0, -5).
+		Light(-10, 10, -10, jtracer.White).
+		Sphere().Color(jtracer.Color{Red: 0.8, Green: 1, Blue: 0.6}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	// the camera looks towards (0, 0, -1) by default, through the sphere at the origin
+	go func() {
+		for range s.Camera.Progress {
+		}
+	}()
+	image := s.Camera.Render(s.World())
+	if got := image.PixelAt(5, 5); got.Equals(&jtracer.Black) {
+		t.Errorf("PixelAt(5, 5) = %v, want the sphere", got)
+	}
+}
+
+func matrixEquals(a, b jtracer.Matrix) bool {
+	for i := range a {
+		for j := range a[i] {
+			if math.Abs(a[i][j]-b[i][j]) > 1e-9 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestBuilder_Build_Copies(t *testing.T) {
+	b := New().Camera(10, 10, 1).Light(0, 10, 0, jtracer.White).Sphere()
+	s, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	b.Translate(1, 2, 3).Color(jtracer.Color{Red: 1})
+	if got := s.Objects[0].GetTransform(); !matrixEquals(got, jtracer.IdentityMatrix) {
+		t.Errorf("transform = %v after changing the builder, want the identity", got)
+	}
+	if got := s.Objects[0].GetMaterial().Color; got != jtracer.NewMaterial().Color {
+		t.Errorf("color = %v after changing the builder, want the default", got)
+	}
+}
@@ -0,0 +1,287 @@
+package jtracer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+)
+
+// bundledScenes returns the scene files in scenes, in both formats
+func bundledScenes(t *testing.T) []string {
+	var files []string
+	for _, pattern := range []string{"scenes/*.yaml", "scenes/*.json"} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, matches...)
+	}
+	return files
+}
+
+func TestLoadSceneFile_BundledScenes(t *testing.T) {
+	files := bundledScenes(t)
+
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			if _, err := LoadSceneFile(file); err != nil {
+				t.Errorf("LoadSceneFile() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestParseScene(t *testing.T) {
+	scene, err := ParseScene([]byte(`
+- add: description
+  title: a test
+- add: camera
+  width: 100
+  height: 50
+  field-of-view: 1
+  from: [0, 1, -5]
+  to: [0, 1, 0]
+  up: [0, 1, 0]
+- add: light
+  at: [-10, 10, -10]
+  intensity: [1, 1, 1]
+- define: shiny
+  value:
+    color: [1, 0, 0]
+    reflective: 0.5
+- add: plane
+  material: shiny
+- add: sphere
+  transform:
+    - [translate, 0, 1, 0]
+  material:
+    pattern:
+      type: checkers
+      colors:
+        - [1, 1, 1]
+        - [0, 0, 0]
+`), ".")
+	if err != nil {
+		t.Fatalf("ParseScene() error = %v", err)
+	}
+
+	if scene.Description.Title != "a test" {
+		t.Errorf("ParseScene() title = %q, want %q", scene.Description.Title, "a test")
+	}
+	if scene.Camera.Hsize != 100 || scene.Camera.Vsize != 50 {
+		t.Errorf("ParseScene() camera = %vx%v, want 100x50", scene.Camera.Hsize, scene.Camera.Vsize)
+	}
+	if want := NewPoint(-10, 10, -10); !scene.Light.Position.Equals(want) {
+		t.Errorf("ParseScene() light at %v, want %v", scene.Light.Position, want)
+	}
+	if len(scene.Objects) != 2 {
+		t.Fatalf("ParseScene() = %d objects, want 2", len(scene.Objects))
+	}
+	if m := scene.Objects[0].GetMaterial(); !m.Color.Equals(&Red) || m.Reflectivity != 0.5 {
+		t.Errorf("ParseScene() plane material = %v, want the shiny define", m)
+	}
+	if m := scene.Objects[1].GetMaterial(); !m.HasPattern {
+		t.Errorf("ParseScene() sphere material has no pattern")
+	}
+}
+
+func TestParseScene_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		yaml   string
+		index  int
+		line   int
+		column int
+		key    string
+		want   error
+	}{
+		{
+			name:  "an unknown type of entry",
+			yaml:  "- add: sphere\n- add: cone\n",
+			index: 1, line: 2, column: 8, key: "add",
+			want: ErrUnknownType,
+		},
+		{
+			name:  "an entry that neither adds nor defines",
+			yaml:  "- color: [1, 0, 0]\n",
+			index: 0, line: 1, column: 3, key: "add",
+			want: ErrMissingKey,
+		},
+		{
+			name:  "a camera size that isn't a number",
+			yaml:  "- add: camera\n  width: 10\n  height: tall\n",
+			index: 0, line: 3, column: 11, key: "height",
+			want: ErrInvalidValue,
+		},
+		{
+			name:  "a camera with a negative width",
+			yaml:  "- add: camera\n  width: -10\n  height: 10\n  field-of-view: 1\n",
+			index: 0, line: 2, column: 10, key: "width",
+			want: ErrInvalidValue,
+		},
+		{
+			name:  "a camera without a field of view",
+			yaml:  "- add: camera\n  width: 10\n  height: 10\n",
+			index: 0, line: 1, column: 3, key: "field-of-view",
+			want: ErrMissingKey,
+		},
+		{
+			name:  "an unknown transform",
+			yaml:  "- add: sphere\n  transform:\n    - [translate, 1, 2, 3]\n    - [twist, 1]\n",
+			index: 0, line: 4, column: 8, key: "transform",
+			want: ErrUnknownTransform,
+		},
+		{
+			name:  "a transform with too few arguments",
+			yaml:  "- add: sphere\n  transform:\n    - [scale, 1, 2]\n",
+			index: 0, line: 3, column: 7, key: "transform",
+			want: ErrInvalidValue,
+		},
+		{
+			name:  "a color with too few channels",
+			yaml:  "- add: sphere\n  material:\n    color: [1, 0]\n",
+			index: 0, line: 3, column: 12, key: "color",
+			want: ErrInvalidValue,
+		},
+		{
+			name:  "an unknown material key",
+			yaml:  "- add: plane\n  material:\n    colour: [1, 0, 0]\n",
+			index: 0, line: 3, column: 13, key: "colour",
+			want: ErrUnknownKey,
+		},
+		{
+			name:  "an unknown pattern type",
+			yaml:  "- add: plane\n  material:\n    pattern:\n      type: plaid\n",
+			index: 0, line: 4, column: 13, key: "type",
+			want: ErrUnknownType,
+		},
+		{
+			name:  "a noise strength under the key of other patterns",
+			yaml:  "- add: plane\n  material:\n    pattern:\n      type: marble\n      colors: [[1, 1, 1], [0, 0, 0]]\n      turbulence: 2\n      scale: 0.5\n",
+			index: 0, line: 7, column: 14, key: "scale",
+			want: ErrInvalidValue,
+		},
+		{
+			name:  "a material naming a missing define",
+			yaml:  "- add: plane\n  material: glass\n",
+			index: 0, line: 2, column: 13, key: "material",
+			want: ErrUnknownDefine,
+		},
+		{
+			name:  "an error after a define is counted from the start of the file",
+			yaml:  "- define: red\n  value:\n    color: [1, 0, 0]\n- add: light\n  at: [0, 0, 0]\n",
+			index: 1, line: 4, column: 3, key: "intensity",
+			want: ErrMissingKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseScene([]byte(tt.yaml), ".")
+
+			var se *SceneError
+			if !errors.As(err, &se) {
+				t.Fatalf("ParseScene() error = %v, want a *SceneError", err)
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ParseScene() error = %v, want %v", err, tt.want)
+			}
+			if se.Index != tt.index || se.Line != tt.line || se.Column != tt.column || se.Key != tt.key {
+				t.Errorf("ParseScene() error at entry %d, %d:%d, key %q, want entry %d, %d:%d, key %q",
+					se.Index, se.Line, se.Column, se.Key, tt.index, tt.line, tt.column, tt.key)
+			}
+		})
+	}
+}
+
+func TestLoadSceneFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := LoadSceneFile(filepath.Join(dir, "missing.yaml")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadSceneFile() of a missing file error = %v, want %v", err, os.ErrNotExist)
+	}
+
+	path := filepath.Join(dir, "scene.yaml")
+	if err := os.WriteFile(path, []byte("- add: plane\n  material:\n    pattern:\n      type: map\n      file: gone.png\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadSceneFile(path)
+	var se *SceneError
+	if !errors.As(err, &se) || !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("LoadSceneFile() error = %v, want a *SceneError for the missing texture", err)
+	}
+	if se.File != path || se.Line != 5 || se.Key != "file" {
+		t.Errorf("LoadSceneFile() error = %v, want it at %v line 5 key file", err, path)
+	}
+}
+
+func TestLoadSceneFile_RelativePaths(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "textures"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	texture := NewCanvas(1, 1)
+	texture.WritePixel(0, 0, &Red)
+	if err := texture.SavePNG(filepath.Join(dir, "textures", "red.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "scene.yaml")
+	scene := "- add: sphere\n  material:\n    pattern:\n      type: map\n      file: textures/red.png\n"
+	if err := os.WriteFile(path, []byte(scene), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadSceneFile(path)
+	if err != nil {
+		t.Fatalf("LoadSceneFile() error = %v", err)
+	}
+	if p, ok := s.Objects[0].GetMaterial().Pattern.(*TextureMapPattern); !ok || p.File != filepath.Join(dir, "textures", "red.png") {
+		t.Errorf("LoadSceneFile() pattern = %v, want a texture loaded relative to the scene", s.Objects[0].GetMaterial().Pattern)
+	}
+}
+
+func TestParseTransforms(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want Matrix
+	}{
+		{
+			name: "transforms apply in the order listed",
+			yaml: "[[scale, 2, 2, 2], [translate, 1, 0, 0]]",
+			want: NewTranslation(1, 0, 0).Multiply(Scaling(2, 2, 2)),
+		},
+		{
+			name: "rotations take a single angle",
+			yaml: "[[rotate-x, 1], [rotate-y, 2.5], [rotate-z, 3]]",
+			want: RotationZ(3).Multiply(RotationY(2.5)).Multiply(RotationX(1)),
+		},
+		{
+			name: "shearing takes six proportions",
+			yaml: "[[shear, 1, 0, 0, 0, 0, 0]]",
+			want: Shearing(1, 0, 0, 0, 0, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var n yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.yaml), &n); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ParseTransforms(n.Content[0])
+			if err != nil {
+				t.Fatalf("ParseTransforms() error = %v", err)
+			}
+			if !cmp.Equal(got, tt.want, float64Comparer) {
+				t.Errorf("ParseTransforms() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
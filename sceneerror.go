@@ -0,0 +1,70 @@
+package jtracer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrUnknownType      = errors.New("unknown type")
+	ErrUnknownTransform = errors.New("unknown transform")
+	ErrUnknownKey       = errors.New("unknown key")
+	ErrUnknownDefine    = errors.New("unknown define")
+	ErrMissingKey       = errors.New("missing key")
+	ErrInvalidValue     = errors.New("invalid value")
+	ErrIncludeCycle     = errors.New("include cycle")
+)
+
+// SceneError reports a problem found while loading a scene, pointing at the entry and the YAML at fault. Err is one of
+// the Err... values above, wrapped with details, or the error from reading a file the scene refers to.
+type SceneError struct {
+	File   string // the scene file, when the scene was loaded from one
+	Index  int    // the entry at fault counting from 0, or -1 when the problem isn't within an entry
+	Line   int    // position of the offending YAML, 0 when unknown
+	Column int
+	Key    string // the key whose value is at fault, if any
+	Err    error
+}
+
+func (e *SceneError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File)
+		b.WriteString(":")
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d:", e.Line, e.Column)
+	}
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+	if e.Index >= 0 {
+		fmt.Fprintf(&b, "entry %d: ", e.Index)
+	}
+	if e.Key != "" {
+		fmt.Fprintf(&b, "%s: ", e.Key)
+	}
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+func (e *SceneError) Unwrap() error {
+	return e.Err
+}
+
+// inEntry records the entry an error was found in, and the scene file when known
+func inEntry(err error, index int, file string) error {
+	var se *SceneError
+	if !errors.As(err, &se) {
+		return &SceneError{File: file, Index: index, Err: err}
+	}
+
+	if se.Index < 0 {
+		se.Index = index
+	}
+	if se.File == "" {
+		se.File = file
+	}
+	return se
+}
@@ -0,0 +1,1353 @@
+package jtracer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A JSON scene file holds a single JSONScene. Unlike the YAML dialect, where a value may be a number or a list, a name
+// or a map, every field of a JSON scene has one type, so scenes written by other programs decode the same way every
+// time and unknown fields are rejected. schema/scene.schema.json describes the format for editors.
+
+// JSONScene is the document of a JSON scene file
+type JSONScene struct {
+	Schema      string                  `json:"$schema,omitempty"`
+	Description *SceneDescription       `json:"description,omitempty"`
+	Animation   *JSONAnimation          `json:"animation,omitempty"`
+	Camera      *JSONCamera             `json:"camera,omitempty"`
+	Light       *JSONLight              `json:"light,omitempty"`
+	Background  *JSONBackground         `json:"background,omitempty"`
+	Environment *JSONEnvironment        `json:"environment,omitempty"`
+	Materials   map[string]JSONMaterial `json:"materials,omitempty"` // named materials that others can extend
+	Objects     []JSONObject            `json:"objects"`
+}
+
+type JSONCamera struct {
+	Width       int        `json:"width"`
+	Height      int        `json:"height"`
+	FieldOfView float64    `json:"field-of-view"`
+	From        [3]float64 `json:"from"`
+	To          [3]float64 `json:"to"`
+	Up          [3]float64 `json:"up"`
+	Animate     JSONTracks `json:"animate,omitempty"`
+}
+
+type JSONLight struct {
+	At        [3]float64 `json:"at"`
+	Intensity [3]float64 `json:"intensity"`
+	Animate   JSONTracks `json:"animate,omitempty"`
+}
+
+// JSONBackground is one of the solid, gradient, cube-map or equirectangular backgrounds, named by Type
+type JSONBackground struct {
+	Type   string         `json:"type"`
+	Color  *[3]float64    `json:"color,omitempty"`
+	Bottom *[3]float64    `json:"bottom,omitempty"`
+	Top    *[3]float64    `json:"top,omitempty"`
+	Files  *JSONCubeFiles `json:"files,omitempty"`
+	File   string         `json:"file,omitempty"`
+}
+
+type JSONCubeFiles struct {
+	Left  string `json:"left"`
+	Front string `json:"front"`
+	Right string `json:"right"`
+	Back  string `json:"back"`
+	Up    string `json:"up"`
+	Down  string `json:"down"`
+}
+
+type JSONEnvironment struct {
+	File      string   `json:"file"`
+	Intensity *float64 `json:"intensity,omitempty"` // 1 when absent
+	Rotation  float64  `json:"rotation,omitempty"`
+	Samples   int      `json:"samples,omitempty"`
+}
+
+// JSONObject is a plane or a sphere, named by Type
+type JSONObject struct {
+	Type      string          `json:"type"`
+	Transform []JSONTransform `json:"transform,omitempty"`
+	Material  *JSONMaterial   `json:"material,omitempty"`
+	Animate   JSONTracks      `json:"animate,omitempty"`
+}
+
+// JSONTransform is a single transform, given by setting exactly one of its fields. A list of them applies in order.
+type JSONTransform struct {
+	Translate *[3]float64 `json:"translate,omitempty"`
+	Scale     *[3]float64 `json:"scale,omitempty"`
+	RotateX   *float64    `json:"rotate-x,omitempty"`
+	RotateY   *float64    `json:"rotate-y,omitempty"`
+	RotateZ   *float64    `json:"rotate-z,omitempty"`
+	Shear     *[6]float64 `json:"shear,omitempty"`
+}
+
+// JSONMaterial changes the fields of the material it extends, or of NewMaterial, that it sets
+type JSONMaterial struct {
+	Extends         string          `json:"extends,omitempty"` // the name of a material of the scene
+	Color           *[3]float64     `json:"color,omitempty"`
+	Pattern         *JSONPattern    `json:"pattern,omitempty"`
+	Ambient         *float64        `json:"ambient,omitempty"`
+	Diffuse         *float64        `json:"diffuse,omitempty"`
+	Specular        *float64        `json:"specular,omitempty"`
+	Shininess       *float64        `json:"shininess,omitempty"`
+	Reflective      *float64        `json:"reflective,omitempty"`
+	Transparency    *float64        `json:"transparency,omitempty"`
+	RefractiveIndex *float64        `json:"refractive-index,omitempty"`
+	Metallic        *float64        `json:"metallic,omitempty"`
+	Roughness       *float64        `json:"roughness,omitempty"`
+	GlossySamples   *int            `json:"glossy-samples,omitempty"`
+	Shading         *JSONShading    `json:"shading,omitempty"`
+	Conductor       *JSONConductor  `json:"conductor,omitempty"`
+	Dispersion      *JSONDispersion `json:"dispersion,omitempty"`
+	Bump            *JSONBump       `json:"bump,omitempty"`
+	NormalMap       *JSONNormalMap  `json:"normal-map,omitempty"`
+	BumpMap         *JSONBumpMap    `json:"bump-map,omitempty"`
+}
+
+type JSONShading struct {
+	Type         string      `json:"type"`
+	Bands        int         `json:"bands,omitempty"`
+	Outline      float64     `json:"outline,omitempty"`
+	OutlineColor *[3]float64 `json:"outline-color,omitempty"`
+}
+
+// JSONConductor is either one of ConductorPresets or a complex index of refraction
+type JSONConductor struct {
+	Preset string      `json:"preset,omitempty"`
+	N      *[3]float64 `json:"n,omitempty"`
+	K      *[3]float64 `json:"k,omitempty"`
+}
+
+type JSONDispersion struct {
+	Model        string      `json:"model"`
+	Coefficients *[3]float64 `json:"coefficients,omitempty"` // cauchy
+	B            *[3]float64 `json:"b,omitempty"`            // sellmeier
+	C            *[3]float64 `json:"c,omitempty"`            // sellmeier
+}
+
+type JSONBump struct {
+	Amount    float64 `json:"amount"`
+	Frequency float64 `json:"frequency,omitempty"`
+}
+
+type JSONNormalMap struct {
+	File     string  `json:"file"`
+	Mapping  string  `json:"mapping,omitempty"`
+	Strength float64 `json:"strength,omitempty"`
+}
+
+type JSONBumpMap struct {
+	File    string  `json:"file"`
+	Mapping string  `json:"mapping,omitempty"`
+	Depth   float64 `json:"depth,omitempty"`
+}
+
+// JSONPattern is a pattern of the kind named by Type. Patterns of two colors take Colors, with Patterns optionally
+// nesting a pattern in place of either color where its entry isn't null. Blend and mask patterns take two Patterns,
+// and perturbed and triplanar patterns a single Pattern. Fields left out take the defaults of the pattern's
+// constructor.
+type JSONPattern struct {
+	Type       string          `json:"type"`
+	Colors     [][3]float64    `json:"colors,omitempty"`
+	Patterns   []*JSONPattern  `json:"patterns,omitempty"`
+	Pattern    *JSONPattern    `json:"pattern,omitempty"`
+	Mask       *JSONPattern    `json:"mask,omitempty"`
+	Color      *[3]float64     `json:"color,omitempty"`
+	File       string          `json:"file,omitempty"`
+	Mapping    string          `json:"mapping,omitempty"`
+	Frequency  *float64        `json:"frequency,omitempty"`
+	Turbulence *float64        `json:"turbulence,omitempty"`
+	Scale      *float64        `json:"scale,omitempty"`
+	Octaves    *int            `json:"octaves,omitempty"`
+	Seed       *int64          `json:"seed,omitempty"`
+	Edges      bool            `json:"edges,omitempty"`
+	Weight     *float64        `json:"weight,omitempty"`
+	Sharpness  *float64        `json:"sharpness,omitempty"`
+	Transform  []JSONTransform `json:"transform,omitempty"`
+}
+
+// JSONAnimation gives the length of a scene's animation
+type JSONAnimation struct {
+	Frames int     `json:"frames"`
+	FPS    float64 `json:"fps,omitempty"` // 24 when absent
+}
+
+// JSONTracks holds the keyframes of animated properties by the names of the properties
+type JSONTracks map[string][]JSONKeyframe
+
+// JSONKeyframe is the value of a property at a frame
+type JSONKeyframe struct {
+	Frame float64   `json:"frame"`
+	Value []float64 `json:"value"` // a single number, or three for a vector or a color
+	Ease  string    `json:"ease,omitempty"`
+}
+
+// isJSONFile reports whether a scene file is in the JSON format rather than YAML
+func isJSONFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// ParseSceneJSON reads a scene from a JSON scene file, resolving the relative paths of any files it refers to against
+// dir. Problems are reported as a *SceneError, where Index is that of the object at fault and Key the path to the
+// field within it.
+func ParseSceneJSON(data []byte, dir string) (*Scene, error) {
+	return parseSceneJSON(data, "", dir)
+}
+
+// parseSceneJSON reads a scene from JSON that came from file, if any
+func parseSceneJSON(data []byte, file, dir string) (*Scene, error) {
+	scene, err := decodeSceneJSON(data, dir)
+	var se *SceneError
+	if errors.As(err, &se) {
+		se.File = file
+	}
+	return scene, err
+}
+
+func decodeSceneJSON(data []byte, dir string) (*Scene, error) {
+	var doc JSONScene
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, jsonSyntaxError(data, dec, err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		line, column := offsetPosition(data, dec.InputOffset())
+		return nil, &SceneError{Index: -1, Line: line, Column: column, Err: fmt.Errorf("%w: more than one value", ErrInvalidValue)}
+	}
+
+	return jsonDecoder{dir: dir, materials: doc.Materials, resolved: make(map[string]*Material)}.scene(doc)
+}
+
+// jsonSyntaxError locates an error from decoding JSON
+func jsonSyntaxError(data []byte, dec *json.Decoder, err error) error {
+	offset := dec.InputOffset()
+	var key string
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset - 1
+	case errors.As(err, &typeErr):
+		offset, key = typeErr.Offset-1, typeErr.Field
+		err = fmt.Errorf("%w: expected %v, got %s", ErrInvalidValue, typeErr.Type, typeErr.Value)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		key = strings.Trim(field, `"`)
+		err = fmt.Errorf("%w %s", ErrUnknownKey, field)
+
+		// the decoder doesn't say where the field was, but a key that appears only once can only be there
+		offset = -1
+		if keys := regexp.MustCompile(regexp.QuoteMeta(field)+`\s*:`).FindAllIndex(data, 2); len(keys) == 1 {
+			offset = int64(keys[0][0])
+		}
+	}
+
+	if offset < 0 {
+		return &SceneError{Index: -1, Key: key, Err: err}
+	}
+	line, column := offsetPosition(data, offset)
+	return &SceneError{Index: -1, Line: line, Column: column, Key: key, Err: err}
+}
+
+// offsetPosition returns the line and column of a byte offset into data, counting from 1
+func offsetPosition(data []byte, offset int64) (line, column int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// jsonError reports a problem with the value of a field
+func jsonError(key string, err error) *SceneError {
+	return &SceneError{Index: -1, Key: key, Err: err}
+}
+
+// jsonInvalid reports that the value of a field isn't what was expected
+func jsonInvalid(key string, format string, args ...interface{}) *SceneError {
+	return jsonError(key, fmt.Errorf("%w: %s", ErrInvalidValue, fmt.Sprintf(format, args...)))
+}
+
+// within prefixes the field an error was found in with the path to the value holding it. Errors found in the scene's
+// named materials are left alone, as their path already starts from the scene.
+func within(err error, path string) error {
+	var se *SceneError
+	if !errors.As(err, &se) {
+		return jsonError(path, err)
+	}
+
+	if strings.HasPrefix(se.Key, "materials.") {
+		return se
+	} else if se.Key == "" {
+		se.Key = path
+	} else if strings.HasPrefix(se.Key, "[") {
+		se.Key = path + se.Key
+	} else {
+		se.Key = path + "." + se.Key
+	}
+	return se
+}
+
+// jsonDecoder builds a scene from a JSONScene
+type jsonDecoder struct {
+	dir       string
+	materials map[string]JSONMaterial
+	resolved  map[string]*Material // named materials once built, or nil while being built
+}
+
+func (d jsonDecoder) scene(doc JSONScene) (*Scene, error) {
+	var scene Scene
+	var err error
+
+	if doc.Description != nil {
+		scene.Description = *doc.Description
+	}
+
+	if a := doc.Animation; a != nil {
+		if a.Frames < 1 {
+			return nil, jsonInvalid("animation.frames", "expected 1 or more frames, got %d", a.Frames)
+		}
+		if a.FPS < 0 {
+			return nil, jsonInvalid("animation.fps", "expected more than 0 frames a second, got %g", a.FPS)
+		}
+		scene.animation().Frames = a.Frames
+		if a.FPS > 0 {
+			scene.Animation.FPS = a.FPS
+		}
+	}
+
+	if c := doc.Camera; c != nil {
+		if c.Width < 1 || c.Height < 1 {
+			return nil, jsonInvalid("camera", "expected a width and height of 1 or more, got %dx%d", c.Width, c.Height)
+		}
+		scene.Camera = NewCamera(float64(c.Width), float64(c.Height), c.FieldOfView)
+		scene.Camera.Transform = ViewTransform(
+			NewPoint(c.From[0], c.From[1], c.From[2]),
+			NewPoint(c.To[0], c.To[1], c.To[2]),
+			NewVector(c.Up[0], c.Up[1], c.Up[2]),
+		)
+		if err := d.tracks(&scene, "camera", c.Animate); err != nil {
+			return nil, within(err, "camera")
+		}
+		if a := scene.Animation; a != nil && a.Camera != nil {
+			a.Camera.holdView(c.From[:], c.To[:], c.Up[:])
+		}
+	}
+
+	if l := doc.Light; l != nil {
+		scene.Light = NewPointLight(*NewPoint(l.At[0], l.At[1], l.At[2]), arrayColor(l.Intensity))
+		if err := d.tracks(&scene, "light", l.Animate); err != nil {
+			return nil, within(err, "light")
+		}
+	}
+
+	if b := doc.Background; b != nil {
+		if scene.Background, err = d.background(*b); err != nil {
+			return nil, within(err, "background")
+		}
+	}
+
+	if e := doc.Environment; e != nil {
+		img, file, err := d.image(e.File, "file")
+		if err != nil {
+			return nil, within(err, "environment")
+		}
+		scene.Environment = NewEnvironmentLight(img)
+		scene.Environment.File = file
+		if e.Intensity != nil {
+			scene.Environment.Intensity = *e.Intensity
+		}
+		scene.Environment.Rotation, scene.Environment.Samples = e.Rotation, e.Samples
+	}
+
+	for i, o := range doc.Objects {
+		s, err := d.object(o)
+		if err != nil {
+			return nil, inEntry(err, i, "")
+		}
+		scene.Objects = append(scene.Objects, s)
+		if err := d.tracks(&scene, o.Type, o.Animate); err != nil {
+			return nil, inEntry(err, i, "")
+		}
+	}
+
+	if scene.Animation != nil {
+		scene.Animation.setFrames()
+	}
+	return &scene, nil
+}
+
+// tracks sets the tracks of the camera, the light or the object just added to the scene, by its kind
+func (d jsonDecoder) tracks(scene *Scene, kind string, animate JSONTracks) error {
+	if len(animate) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(animate))
+	for name := range animate {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tracks := make(Tracks)
+	for _, name := range names {
+		track := make(Track, len(animate[name]))
+		for i, k := range animate[name] {
+			ease := Linear
+			if k.Ease != "" {
+				var err error
+				if ease, err = parseEasing(k.Ease); err != nil {
+					return jsonError(fmt.Sprintf("animate.%s[%d].ease", name, i), err)
+				}
+			}
+			track[i] = Keyframe{Frame: k.Frame, Value: k.Value, Ease: ease}
+		}
+
+		if err := checkTrack(trackProperties[kind], name, track); err != nil {
+			return jsonError("animate."+name, err)
+		}
+		tracks[name] = track
+	}
+
+	scene.setTracks(kind, tracks)
+	return nil
+}
+
+func (d jsonDecoder) background(b JSONBackground) (Background, error) {
+	switch b.Type {
+	case "solid":
+		if b.Color == nil {
+			return nil, jsonError("color", ErrMissingKey)
+		}
+		return SolidBackground{Color: arrayColor(*b.Color)}, nil
+	case "gradient":
+		if b.Bottom == nil || b.Top == nil {
+			return nil, jsonError("bottom", fmt.Errorf("%w: gradients need a bottom and a top", ErrMissingKey))
+		}
+		return GradientBackground{Bottom: arrayColor(*b.Bottom), Top: arrayColor(*b.Top)}, nil
+	case "cube-map":
+		if b.Files == nil {
+			return nil, jsonError("files", ErrMissingKey)
+		}
+		var c CubeMapBackground
+		for face, f := range []struct{ key, file string }{
+			{"left", b.Files.Left}, {"front", b.Files.Front}, {"right", b.Files.Right},
+			{"back", b.Files.Back}, {"up", b.Files.Up}, {"down", b.Files.Down},
+		} {
+			var err error
+			if c.Faces[face], c.Files[face], err = d.image(f.file, f.key); err != nil {
+				return nil, within(err, "files")
+			}
+		}
+		return c, nil
+	case "equirectangular":
+		img, file, err := d.image(b.File, "file")
+		return EquirectangularBackground{Image: img, File: file}, err
+	}
+
+	return nil, jsonError("type", fmt.Errorf("%w %q", ErrUnknownType, b.Type))
+}
+
+func (d jsonDecoder) object(o JSONObject) (Shape, error) {
+	var s Shape
+	var m *Material
+	switch o.Type {
+	case "plane":
+		p := NewPlane()
+		s, m = p, &p.Material
+	case "sphere":
+		sp := NewSphere()
+		s, m = sp, &sp.Material
+	default:
+		return nil, jsonError("type", fmt.Errorf("%w %q", ErrUnknownType, o.Type))
+	}
+
+	tf, err := jsonTransforms(o.Transform)
+	if err != nil {
+		return nil, within(err, "transform")
+	}
+	s.SetTransform(tf)
+
+	if o.Material != nil {
+		if *m, err = d.material(*m, *o.Material); err != nil {
+			return nil, within(err, "material")
+		}
+	}
+	return s, nil
+}
+
+// jsonTransforms composes a list of transforms, each applied after those before it
+func jsonTransforms(transforms []JSONTransform) (Matrix, error) {
+	result := IdentityMatrix
+	for i, t := range transforms {
+		var steps []Matrix
+		if v := t.Translate; v != nil {
+			steps = append(steps, NewTranslation(v[0], v[1], v[2]))
+		}
+		if v := t.Scale; v != nil {
+			steps = append(steps, Scaling(v[0], v[1], v[2]))
+		}
+		if t.RotateX != nil {
+			steps = append(steps, RotationX(*t.RotateX))
+		}
+		if t.RotateY != nil {
+			steps = append(steps, RotationY(*t.RotateY))
+		}
+		if t.RotateZ != nil {
+			steps = append(steps, RotationZ(*t.RotateZ))
+		}
+		if v := t.Shear; v != nil {
+			steps = append(steps, Shearing(v[0], v[1], v[2], v[3], v[4], v[5]))
+		}
+
+		if len(steps) != 1 {
+			return nil, jsonInvalid(fmt.Sprintf("[%d]", i), "expected exactly one transform, got %d", len(steps))
+		}
+		result = steps[0].Multiply(result)
+	}
+	return result, nil
+}
+
+// material applies the fields set in j to m, or to the named material j extends
+func (d jsonDecoder) material(m Material, j JSONMaterial) (Material, error) {
+	if j.Extends != "" {
+		parent, err := d.named(j.Extends)
+		if err != nil {
+			return m, err
+		}
+		m = parent
+	}
+
+	if j.Color != nil {
+		m.Color = arrayColor(*j.Color)
+	}
+	for _, f := range []struct {
+		src *float64
+		dst *float64
+	}{
+		{j.Ambient, &m.Ambient},
+		{j.Diffuse, &m.Diffuse},
+		{j.Specular, &m.Specular},
+		{j.Shininess, &m.Shininess},
+		{j.Reflective, &m.Reflectivity},
+		{j.Transparency, &m.Transparency},
+		{j.RefractiveIndex, &m.RefractiveIndex},
+		{j.Metallic, &m.Metallic},
+		{j.Roughness, &m.Roughness},
+	} {
+		if f.src != nil {
+			*f.dst = *f.src
+		}
+	}
+	if j.GlossySamples != nil {
+		m.GlossySamples = *j.GlossySamples
+	}
+
+	var err error
+	if j.Pattern != nil {
+		if m.Pattern, err = d.pattern(*j.Pattern); err != nil {
+			return m, within(err, "pattern")
+		}
+		m.HasPattern = true
+	}
+	if j.Shading != nil {
+		if m.Shading, err = jsonShading(*j.Shading); err != nil {
+			return m, within(err, "shading")
+		}
+	}
+	if c := j.Conductor; c != nil {
+		if m.Conductor, err = jsonConductor(*c); err != nil {
+			return m, within(err, "conductor")
+		}
+	}
+	if j.Dispersion != nil {
+		if m.Dispersion, err = jsonDispersion(*j.Dispersion); err != nil {
+			return m, within(err, "dispersion")
+		}
+	}
+	if b := j.Bump; b != nil {
+		m.Bump, m.BumpFrequency = b.Amount, b.Frequency
+	}
+	if nm := j.NormalMap; nm != nil {
+		m.NormalMap = &NormalMap{Strength: nm.Strength}
+		if m.NormalMap.Image, m.NormalMap.File, m.NormalMap.Mapper, err = d.surfaceMap(nm.File, nm.Mapping); err != nil {
+			return m, within(err, "normal-map")
+		}
+	}
+	if bm := j.BumpMap; bm != nil {
+		m.BumpMap = &BumpMap{Depth: bm.Depth}
+		if m.BumpMap.Image, m.BumpMap.File, m.BumpMap.Mapper, err = d.surfaceMap(bm.File, bm.Mapping); err != nil {
+			return m, within(err, "bump-map")
+		}
+	}
+
+	return m, nil
+}
+
+// named builds one of the scene's named materials, once
+func (d jsonDecoder) named(name string) (Material, error) {
+	if m, ok := d.resolved[name]; ok {
+		if m == nil {
+			return Material{}, jsonInvalid("extends", "extending %q goes round in a circle", name)
+		}
+		return *m, nil
+	}
+
+	j, ok := d.materials[name]
+	if !ok {
+		return Material{}, jsonError("extends", fmt.Errorf("%w %q", ErrUnknownDefine, name))
+	}
+
+	d.resolved[name] = nil
+	m, err := d.material(NewMaterial(), j)
+	if err != nil {
+		delete(d.resolved, name)
+		return m, within(err, "materials."+name)
+	}
+	d.resolved[name] = &m
+	return m, nil
+}
+
+func jsonShading(s JSONShading) (ShadingModel, error) {
+	switch s.Type {
+	case "phong":
+		return PhongShading{}, nil
+	case "blinn-phong":
+		return BlinnPhongShading{}, nil
+	case "lambert":
+		return LambertShading{}, nil
+	case "pbr":
+		return MicrofacetShading{}, nil
+	case "toon":
+		t := ToonShading{Bands: s.Bands, Outline: s.Outline}
+		if s.OutlineColor != nil {
+			t.OutlineColor = arrayColor(*s.OutlineColor)
+		}
+		return t, nil
+	}
+
+	return nil, jsonError("type", fmt.Errorf("%w %q", ErrUnknownType, s.Type))
+}
+
+func jsonConductor(c JSONConductor) (ComplexIOR, error) {
+	if c.Preset != "" {
+		ior, ok := ConductorPresets[c.Preset]
+		if !ok {
+			return ComplexIOR{}, jsonError("preset", fmt.Errorf("%w %q", ErrUnknownType, c.Preset))
+		}
+		return ior, nil
+	}
+
+	if c.N == nil || c.K == nil {
+		return ComplexIOR{}, jsonError("n", fmt.Errorf("%w: expected a preset or both n and k", ErrMissingKey))
+	}
+	return ComplexIOR{N: arrayColor(*c.N), K: arrayColor(*c.K)}, nil
+}
+
+func jsonDispersion(j JSONDispersion) (Dispersion, error) {
+	switch j.Model {
+	case "cauchy":
+		if j.Coefficients == nil {
+			return Dispersion{}, jsonError("coefficients", ErrMissingKey)
+		}
+		return Dispersion{Model: CauchyDispersion, B: *j.Coefficients}, nil
+	case "sellmeier":
+		if j.B == nil || j.C == nil {
+			return Dispersion{}, jsonError("b", fmt.Errorf("%w: sellmeier needs both b and c", ErrMissingKey))
+		}
+		return Dispersion{Model: SellmeierDispersion, B: *j.B, C: *j.C}, nil
+	}
+
+	return Dispersion{}, jsonError("model", fmt.Errorf("%w %q", ErrUnknownType, j.Model))
+}
+
+// surfaceMap loads the image of a normal or bump map along with its mapping, if any
+func (d jsonDecoder) surfaceMap(file, mapping string) (*Canvas, string, UVMapper, error) {
+	img, path, err := d.image(file, "file")
+	if err != nil || mapping == "" {
+		return img, path, nil, err
+	}
+
+	mapper, err := jsonMapper(mapping)
+	return img, path, mapper, err
+}
+
+func jsonMapper(name string) (UVMapper, error) {
+	switch name {
+	case "spherical":
+		return SphericalMap{}, nil
+	case "planar":
+		return PlanarMap{}, nil
+	case "cylindrical":
+		return CylindricalMap{}, nil
+	case "cube", "cubic":
+		return CubicMap{}, nil
+	}
+
+	return nil, jsonError("mapping", fmt.Errorf("%w %q", ErrUnknownType, name))
+}
+
+// image loads an image file named relative to the scene
+func (d jsonDecoder) image(file, key string) (*Canvas, string, error) {
+	if file == "" {
+		return nil, "", jsonError(key, ErrMissingKey)
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(d.dir, file)
+	}
+
+	img, err := LoadImage(file)
+	if err == nil {
+		err = checkImage(img, file)
+	}
+	if err != nil {
+		return nil, "", jsonError(key, err)
+	}
+	return img, file, nil
+}
+
+func (d jsonDecoder) pattern(j JSONPattern) (Pattern, error) {
+	var p Pattern
+	var err error
+
+	switch j.Type {
+	case "stripes", "checkers", "gradient", "rings", "radial-gradient", "marble", "wood", "worley":
+		p, err = d.twoColorPattern(j)
+	case "map":
+		var img *Canvas
+		var file string
+		var mapper UVMapper = SphericalMap{}
+		if img, file, err = d.image(j.File, "file"); err != nil {
+			return nil, err
+		}
+		if j.Mapping != "" {
+			if mapper, err = jsonMapper(j.Mapping); err != nil {
+				return nil, err
+			}
+		}
+		t := NewTextureMapPattern(mapper, img)
+		t.File = file
+		p = t
+	case "triplanar":
+		t := NewTriplanarPattern(nil)
+		if j.Pattern != nil {
+			if t.Pattern, err = d.pattern(*j.Pattern); err != nil {
+				return nil, within(err, "pattern")
+			}
+		} else if t.Image, t.File, err = d.image(j.File, "file"); err != nil {
+			return nil, err
+		}
+		setFloat(&t.Sharpness, j.Sharpness)
+		p = t
+	case "blend":
+		var patterns []Pattern
+		if patterns, err = d.patterns(j.Patterns); err != nil {
+			return nil, err
+		}
+		bp := NewBlendedPattern(patterns[0], patterns[1])
+		setFloat(&bp.Weight, j.Weight)
+		p = bp
+	case "mask":
+		var patterns []Pattern
+		if patterns, err = d.patterns(j.Patterns); err != nil {
+			return nil, err
+		}
+		if j.Mask == nil {
+			return nil, jsonError("mask", ErrMissingKey)
+		}
+		var mask Pattern
+		if mask, err = d.pattern(*j.Mask); err != nil {
+			return nil, within(err, "mask")
+		}
+		p = NewMaskPattern(patterns[0], patterns[1], mask)
+	case "solid":
+		if j.Color == nil {
+			return nil, jsonError("color", ErrMissingKey)
+		}
+		p = NewSolidPattern(arrayColor(*j.Color))
+	case "perturbed":
+		if j.Pattern == nil {
+			return nil, jsonError("pattern", ErrMissingKey)
+		}
+		var inner Pattern
+		if inner, err = d.pattern(*j.Pattern); err != nil {
+			return nil, within(err, "pattern")
+		}
+		pp := NewPerturbedPattern(inner, 0.2)
+		err = jsonNoise(j, "scale", &pp.Frequency, &pp.Scale, &pp.Octaves, &pp.Noise)
+		p = pp
+	default:
+		return nil, jsonError("type", fmt.Errorf("%w %q", ErrUnknownType, j.Type))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tf, err := jsonTransforms(j.Transform)
+	if err != nil {
+		return nil, within(err, "transform")
+	}
+	p.SetTransform(tf)
+	return p, nil
+}
+
+// twoColorPattern builds one of the patterns that alternate or blend between two colors, either of which may be
+// replaced by a nested pattern
+func (d jsonDecoder) twoColorPattern(j JSONPattern) (Pattern, error) {
+	if len(j.Colors) != 2 {
+		return nil, jsonInvalid("colors", "expected 2 colors, got %d", len(j.Colors))
+	}
+	if len(j.Patterns) != 0 && len(j.Patterns) != 2 {
+		return nil, jsonInvalid("patterns", "expected 2 patterns or nulls, got %d", len(j.Patterns))
+	}
+
+	a, b := arrayColor(j.Colors[0]), arrayColor(j.Colors[1])
+	var nested [2]Pattern
+	for i, np := range j.Patterns {
+		if np == nil {
+			continue
+		}
+		var err error
+		if nested[i], err = d.pattern(*np); err != nil {
+			return nil, within(err, fmt.Sprintf("patterns[%d]", i))
+		}
+	}
+	pa, pb := nested[0], nested[1]
+
+	switch j.Type {
+	case "stripes":
+		s := NewStripePattern(a, b)
+		s.PatternA, s.PatternB = pa, pb
+		return s, nil
+	case "checkers":
+		c := NewCheckersPattern(a, b)
+		c.PatternA, c.PatternB = pa, pb
+		return &c, nil
+	case "gradient":
+		g := NewGradientPattern(a, b)
+		g.PatternA, g.PatternB = pa, pb
+		return g, nil
+	case "rings":
+		r := NewRingPattern(a, b)
+		r.PatternA, r.PatternB = pa, pb
+		return r, nil
+	case "radial-gradient":
+		r := NewRadialGradientPattern(a, b)
+		r.PatternA, r.PatternB = pa, pb
+		return r, nil
+	case "marble":
+		m := NewMarblePattern(a, b)
+		m.PatternA, m.PatternB = pa, pb
+		return m, jsonNoise(j, "turbulence", &m.Frequency, &m.Turbulence, &m.Octaves, &m.Noise)
+	case "wood":
+		w := NewWoodPattern(a, b)
+		w.PatternA, w.PatternB = pa, pb
+		return w, jsonNoise(j, "turbulence", &w.Frequency, &w.Turbulence, &w.Octaves, &w.Noise)
+	}
+
+	w := NewWorleyPattern(a, b)
+	w.PatternA, w.PatternB = pa, pb
+	setFloat(&w.Frequency, j.Frequency)
+	w.Seed = 0
+	if j.Seed != nil {
+		w.Seed = *j.Seed
+	}
+	w.Edges = j.Edges
+	return w, nil
+}
+
+// patterns builds the two patterns of a blend or mask
+func (d jsonDecoder) patterns(js []*JSONPattern) ([]Pattern, error) {
+	if len(js) != 2 {
+		return nil, jsonInvalid("patterns", "expected 2 patterns, got %d", len(js))
+	}
+
+	patterns := make([]Pattern, 2)
+	for i, j := range js {
+		if j == nil {
+			return nil, jsonError(fmt.Sprintf("patterns[%d]", i), ErrMissingKey)
+		}
+		var err error
+		if patterns[i], err = d.pattern(*j); err != nil {
+			return nil, within(err, fmt.Sprintf("patterns[%d]", i))
+		}
+	}
+	return patterns, nil
+}
+
+// jsonNoise sets those settings of a noise driven pattern that are given. The strength of the noise is read from
+// strengthKey, as parseNoiseParameters does.
+func jsonNoise(j JSONPattern, strengthKey string, frequency, strength *float64, octaves *int, noise **Perlin) error {
+	given := map[string]*float64{"turbulence": j.Turbulence, "scale": j.Scale}
+	if other := otherStrengthKey(strengthKey); given[other] != nil {
+		return jsonInvalid(other, "the strength of this pattern's noise is its %s, not %s", strengthKey, other)
+	}
+
+	setFloat(frequency, j.Frequency)
+	setFloat(strength, given[strengthKey])
+	if j.Octaves != nil {
+		*octaves = *j.Octaves
+	}
+	if j.Seed != nil {
+		*noise = NewPerlin(*j.Seed)
+	}
+	return nil
+}
+
+// setFloat sets *dst to *src, when given
+func setFloat(dst, src *float64) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func arrayColor(c [3]float64) Color {
+	return Color{c[0], c[1], c[2]}
+}
+
+// jsonEncoder builds the JSONScene of a scene, writing the paths of files as sceneEncoder does. Numbers are rounded
+// to the 12 significant digits that MarshalScene writes, so that saving a scene again gives the same file.
+type jsonEncoder struct {
+	sceneEncoder
+}
+
+func (e jsonEncoder) scene(scene *Scene) (*JSONScene, error) {
+	doc := JSONScene{Objects: []JSONObject{}}
+	var err error
+
+	if d := scene.Description; d != (SceneDescription{}) {
+		doc.Description = &d
+	}
+
+	a := scene.Animation
+	if a == nil {
+		a = &Animation{}
+	} else {
+		doc.Animation = &JSONAnimation{Frames: a.Frames, FPS: jsonFloat(a.FPS)}
+	}
+
+	if c := scene.Camera; c.Hsize > 0 {
+		from, to, up := cameraVectors(c)
+		doc.Camera = &JSONCamera{
+			Width:       int(c.Hsize),
+			Height:      int(c.Vsize),
+			FieldOfView: jsonFloat(c.Fov),
+			From:        tupleArray(from),
+			To:          tupleArray(to),
+			Up:          tupleArray(up),
+			Animate:     jsonTracks(a.Camera),
+		}
+	}
+
+	if l := scene.Light; l != (Light{}) {
+		doc.Light = &JSONLight{At: tupleArray(&l.Position), Intensity: colorArray(l.Intensity), Animate: jsonTracks(a.Light)}
+	}
+
+	if scene.Background != nil {
+		if doc.Background, err = e.background(scene.Background); err != nil {
+			return nil, fmt.Errorf("background: %w", err)
+		}
+	}
+
+	if env := scene.Environment; env != nil {
+		file, err := e.path(env.File)
+		if err != nil {
+			return nil, fmt.Errorf("environment: %w", err)
+		}
+		doc.Environment = &JSONEnvironment{
+			File:      file,
+			Intensity: floatPtr(env.Intensity),
+			Rotation:  jsonFloat(env.Rotation),
+			Samples:   env.Samples,
+		}
+	}
+
+	for i, s := range scene.Objects {
+		o, err := e.object(s)
+		if err != nil {
+			return nil, fmt.Errorf("object %d: %w", i, err)
+		}
+		o.Animate = jsonTracks(a.Objects[i])
+		doc.Objects = append(doc.Objects, o)
+	}
+
+	return &doc, nil
+}
+
+// jsonTracks writes the keyframes of tracks, or nil when there are none
+func jsonTracks(tracks Tracks) JSONTracks {
+	if len(tracks) == 0 {
+		return nil
+	}
+
+	j := make(JSONTracks)
+	for name, track := range tracks {
+		for _, k := range track {
+			value := make([]float64, len(k.Value))
+			for i, v := range k.Value {
+				value[i] = jsonFloat(v)
+			}
+
+			var ease string
+			if k.Ease != Linear {
+				ease = k.Ease.String()
+			}
+			j[name] = append(j[name], JSONKeyframe{Frame: jsonFloat(k.Frame), Value: value, Ease: ease})
+		}
+	}
+	return j
+}
+
+func (e jsonEncoder) background(b Background) (*JSONBackground, error) {
+	switch b := b.(type) {
+	case SolidBackground:
+		return &JSONBackground{Type: "solid", Color: colorPtr(b.Color)}, nil
+	case GradientBackground:
+		return &JSONBackground{Type: "gradient", Bottom: colorPtr(b.Bottom), Top: colorPtr(b.Top)}, nil
+	case CubeMapBackground:
+		var files [6]string
+		for face := range files {
+			var err error
+			if files[face], err = e.path(b.Files[face]); err != nil {
+				return nil, err
+			}
+		}
+		return &JSONBackground{Type: "cube-map", Files: &JSONCubeFiles{
+			Left: files[0], Front: files[1], Right: files[2], Back: files[3], Up: files[4], Down: files[5],
+		}}, nil
+	case EquirectangularBackground:
+		file, err := e.path(b.File)
+		return &JSONBackground{Type: "equirectangular", File: file}, err
+	}
+
+	return nil, fmt.Errorf("%T %w", b, ErrUnsavable)
+}
+
+func (e jsonEncoder) object(s Shape) (JSONObject, error) {
+	var o JSONObject
+	switch s.(type) {
+	case *Plane:
+		o.Type = "plane"
+	case *Sphere:
+		o.Type = "sphere"
+	default:
+		return o, fmt.Errorf("%T %w", s, ErrUnsavable)
+	}
+
+	var err error
+	if o.Transform, err = jsonTransformSteps(s.GetTransform()); err != nil {
+		return o, err
+	}
+
+	m, err := e.material(s.GetMaterial())
+	if err != nil {
+		return o, fmt.Errorf("material: %w", err)
+	}
+	if m != (JSONMaterial{}) {
+		o.Material = &m
+	}
+	return o, nil
+}
+
+// jsonTransformSteps writes a transform as the list of named transforms that compose it
+func jsonTransformSteps(m Matrix) ([]JSONTransform, error) {
+	steps, err := DecomposeTransform(m)
+	if err != nil {
+		return nil, fmt.Errorf("transform: %w", err)
+	}
+
+	var transforms []JSONTransform
+	for _, step := range steps {
+		args := make([]float64, len(step.Args))
+		for i, a := range step.Args {
+			args[i] = jsonFloat(a)
+		}
+
+		var t JSONTransform
+		switch step.Name {
+		case "translate":
+			t.Translate = &[3]float64{args[0], args[1], args[2]}
+		case "scale":
+			t.Scale = &[3]float64{args[0], args[1], args[2]}
+		case "rotate-x":
+			t.RotateX = &args[0]
+		case "rotate-y":
+			t.RotateY = &args[0]
+		case "rotate-z":
+			t.RotateZ = &args[0]
+		case "shear":
+			t.Shear = &[6]float64{args[0], args[1], args[2], args[3], args[4], args[5]}
+		}
+		transforms = append(transforms, t)
+	}
+	return transforms, nil
+}
+
+// material writes the properties of m that differ from those of NewMaterial, as sceneEncoder.material does
+func (e jsonEncoder) material(m Material) (JSONMaterial, error) {
+	def := NewMaterial()
+	var j JSONMaterial
+	var err error
+
+	if m.Color != def.Color {
+		j.Color = colorPtr(m.Color)
+	}
+	if m.HasPattern {
+		if j.Pattern, err = e.pattern(m.Pattern); err != nil {
+			return j, err
+		}
+	}
+
+	for _, field := range []struct {
+		dst           **float64
+		value, unless float64
+	}{
+		{&j.Ambient, m.Ambient, def.Ambient},
+		{&j.Diffuse, m.Diffuse, def.Diffuse},
+		{&j.Specular, m.Specular, def.Specular},
+		{&j.Shininess, m.Shininess, def.Shininess},
+		{&j.Reflective, m.Reflectivity, def.Reflectivity},
+		{&j.Transparency, m.Transparency, def.Transparency},
+		{&j.RefractiveIndex, m.RefractiveIndex, def.RefractiveIndex},
+		{&j.Metallic, m.Metallic, def.Metallic},
+		{&j.Roughness, m.Roughness, def.Roughness},
+	} {
+		if field.value != field.unless {
+			*field.dst = floatPtr(field.value)
+		}
+	}
+
+	if m.GlossySamples != 0 {
+		samples := m.GlossySamples
+		j.GlossySamples = &samples
+	}
+
+	if m.Shading != nil {
+		if j.Shading, err = jsonShadingOf(m.Shading); err != nil {
+			return j, err
+		}
+	}
+
+	if m.IsConductor() {
+		if name, ok := conductorPreset(m.Conductor); ok {
+			j.Conductor = &JSONConductor{Preset: name}
+		} else {
+			j.Conductor = &JSONConductor{N: colorPtr(m.Conductor.N), K: colorPtr(m.Conductor.K)}
+		}
+	}
+
+	switch d := m.Dispersion; d.Model {
+	case CauchyDispersion:
+		j.Dispersion = &JSONDispersion{Model: "cauchy", Coefficients: floatsPtr(d.B)}
+	case SellmeierDispersion:
+		j.Dispersion = &JSONDispersion{Model: "sellmeier", B: floatsPtr(d.B), C: floatsPtr(d.C)}
+	}
+
+	if m.Bump != 0 {
+		j.Bump = &JSONBump{Amount: jsonFloat(m.Bump), Frequency: jsonFloat(m.BumpFrequency)}
+	}
+
+	if nm := m.NormalMap; nm != nil {
+		j.NormalMap = &JSONNormalMap{Strength: jsonFloat(nm.Strength)}
+		if j.NormalMap.File, j.NormalMap.Mapping, err = e.surfaceMap(nm.File, nm.Mapper); err != nil {
+			return j, fmt.Errorf("normal-map: %w", err)
+		}
+	}
+
+	if bm := m.BumpMap; bm != nil {
+		j.BumpMap = &JSONBumpMap{Depth: jsonFloat(bm.Depth)}
+		if j.BumpMap.File, j.BumpMap.Mapping, err = e.surfaceMap(bm.File, bm.Mapper); err != nil {
+			return j, fmt.Errorf("bump-map: %w", err)
+		}
+	}
+
+	return j, nil
+}
+
+func jsonShadingOf(s ShadingModel) (*JSONShading, error) {
+	switch s := s.(type) {
+	case PhongShading:
+		return &JSONShading{Type: "phong"}, nil
+	case BlinnPhongShading:
+		return &JSONShading{Type: "blinn-phong"}, nil
+	case LambertShading:
+		return &JSONShading{Type: "lambert"}, nil
+	case MicrofacetShading:
+		return &JSONShading{Type: "pbr"}, nil
+	case ToonShading:
+		t := &JSONShading{Type: "toon", Bands: s.Bands, Outline: jsonFloat(s.Outline)}
+		if s.OutlineColor != (Color{}) {
+			t.OutlineColor = colorPtr(s.OutlineColor)
+		}
+		return t, nil
+	}
+
+	return nil, fmt.Errorf("shading %T %w", s, ErrUnsavable)
+}
+
+// surfaceMap returns the file and mapping shared by normal and bump maps
+func (e jsonEncoder) surfaceMap(file string, mapper UVMapper) (string, string, error) {
+	path, err := e.path(file)
+	if err != nil || mapper == nil {
+		return path, "", err
+	}
+
+	name, err := mapperName(mapper)
+	return path, name, err
+}
+
+func (e jsonEncoder) pattern(p Pattern) (*JSONPattern, error) {
+	var j *JSONPattern
+	var err error
+
+	switch p := p.(type) {
+	case *StripePattern:
+		j, err = e.twoColorPattern("stripes", p.A, p.B, p.PatternA, p.PatternB)
+	case *CheckersPattern:
+		j, err = e.twoColorPattern("checkers", p.A, p.B, p.PatternA, p.PatternB)
+	case *GradientPattern:
+		j, err = e.twoColorPattern("gradient", p.A, p.B, p.PatternA, p.PatternB)
+	case *RingPattern:
+		j, err = e.twoColorPattern("rings", p.A, p.B, p.PatternA, p.PatternB)
+	case *RadialGradientPattern:
+		j, err = e.twoColorPattern("radial-gradient", p.A, p.B, p.PatternA, p.PatternB)
+	case *MarblePattern:
+		if j, err = e.twoColorPattern("marble", p.A, p.B, p.PatternA, p.PatternB); err == nil {
+			j.Turbulence = jsonNoiseOf(j, p.Frequency, p.Turbulence, p.Octaves, p.Noise)
+		}
+	case *WoodPattern:
+		if j, err = e.twoColorPattern("wood", p.A, p.B, p.PatternA, p.PatternB); err == nil {
+			j.Turbulence = jsonNoiseOf(j, p.Frequency, p.Turbulence, p.Octaves, p.Noise)
+		}
+	case *WorleyPattern:
+		if j, err = e.twoColorPattern("worley", p.A, p.B, p.PatternA, p.PatternB); err == nil {
+			seed := p.Seed
+			j.Frequency, j.Seed, j.Edges = floatPtr(p.Frequency), &seed, p.Edges
+		}
+	case *TextureMapPattern:
+		j = &JSONPattern{Type: "map"}
+		if j.File, err = e.path(p.File); err == nil {
+			j.Mapping, err = mapperName(p.Mapper)
+		}
+	case *TriplanarPattern:
+		j = &JSONPattern{Type: "triplanar", Sharpness: floatPtr(p.Sharpness)}
+		if p.Pattern != nil {
+			j.Pattern, err = e.pattern(p.Pattern)
+		} else {
+			j.File, err = e.path(p.File)
+		}
+	case *BlendedPattern:
+		j = &JSONPattern{Type: "blend", Weight: floatPtr(p.Weight)}
+		j.Patterns, err = e.patterns(p.A, p.B)
+	case *MaskPattern:
+		j = &JSONPattern{Type: "mask"}
+		if j.Patterns, err = e.patterns(p.A, p.B); err == nil {
+			j.Mask, err = e.pattern(p.Mask)
+		}
+	case *SolidPattern:
+		j = &JSONPattern{Type: "solid", Color: colorPtr(p.Color)}
+	case *PerturbedPattern:
+		j = &JSONPattern{Type: "perturbed"}
+		if j.Pattern, err = e.pattern(p.Pattern); err == nil {
+			j.Scale = jsonNoiseOf(j, p.Frequency, p.Scale, p.Octaves, p.Noise)
+		}
+	default:
+		return nil, fmt.Errorf("pattern %T %w", p, ErrUnsavable)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	j.Transform, err = jsonTransformSteps(p.GetTransform())
+	return j, err
+}
+
+// twoColorPattern writes the colors of a pattern, and the patterns nested in place of either
+func (e jsonEncoder) twoColorPattern(kind string, a, b Color, pa, pb Pattern) (*JSONPattern, error) {
+	j := &JSONPattern{Type: kind, Colors: [][3]float64{colorArray(a), colorArray(b)}}
+	if pa == nil && pb == nil {
+		return j, nil
+	}
+
+	j.Patterns = make([]*JSONPattern, 2)
+	for i, p := range []Pattern{pa, pb} {
+		if p == nil {
+			continue
+		}
+		var err error
+		if j.Patterns[i], err = e.pattern(p); err != nil {
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+func (e jsonEncoder) patterns(ps ...Pattern) ([]*JSONPattern, error) {
+	var js []*JSONPattern
+	for _, p := range ps {
+		j, err := e.pattern(p)
+		if err != nil {
+			return nil, err
+		}
+		js = append(js, j)
+	}
+	return js, nil
+}
+
+// jsonNoiseOf writes the settings read by jsonNoise but for the strength of the noise, which it returns to be stored
+// under turbulence or scale
+func jsonNoiseOf(j *JSONPattern, frequency, strength float64, octaves int, noise *Perlin) *float64 {
+	j.Frequency, j.Octaves = floatPtr(frequency), &octaves
+	if noise != nil {
+		seed := noise.Seed
+		j.Seed = &seed
+	}
+	return floatPtr(strength)
+}
+
+// jsonFloat rounds f to the digits floatNode writes
+func jsonFloat(f float64) float64 {
+	f, _ = strconv.ParseFloat(strconv.FormatFloat(f, 'g', 12, 64), 64)
+	if f == 0 {
+		return 0 // and not -0
+	}
+	return f
+}
+
+func floatPtr(f float64) *float64 {
+	f = jsonFloat(f)
+	return &f
+}
+
+func floatsPtr(fs [3]float64) *[3]float64 {
+	for i := range fs {
+		fs[i] = jsonFloat(fs[i])
+	}
+	return &fs
+}
+
+func colorArray(c Color) [3]float64 {
+	return *floatsPtr([3]float64{c.Red, c.Green, c.Blue})
+}
+
+func colorPtr(c Color) *[3]float64 {
+	a := colorArray(c)
+	return &a
+}
+
+func tupleArray(t *Tuple) [3]float64 {
+	return *floatsPtr([3]float64{t.X, t.Y, t.Z})
+}
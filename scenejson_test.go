@@ -0,0 +1,324 @@
+package jtracer
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseSceneJSON(t *testing.T) {
+	scene, err := ParseSceneJSON([]byte(`{
+  "description": {"title": "a test"},
+  "camera": {"width": 100, "height": 50, "field-of-view": 1, "from": [0, 1, -5], "to": [0, 1, 0], "up": [0, 1, 0]},
+  "light": {"at": [-10, 10, -10], "intensity": [1, 1, 1]},
+  "background": {"type": "gradient", "bottom": [1, 1, 1], "top": [0, 0, 1]},
+  "materials": {
+    "shiny": {"specular": 1, "reflective": 0.5},
+    "red-shiny": {"extends": "shiny", "color": [1, 0, 0]}
+  },
+  "objects": [
+    {"type": "plane", "material": {"pattern": {"type": "stripes", "colors": [[1, 1, 1], [0, 0, 0]], "patterns": [null, {"type": "solid", "color": [0, 1, 0]}]}}},
+    {"type": "sphere", "transform": [{"scale": [2, 2, 2]}, {"translate": [1, 2, 3]}], "material": {"extends": "red-shiny", "diffuse": 0.2}}
+  ]
+}`), ".")
+	if err != nil {
+		t.Fatalf("ParseSceneJSON() error = %v", err)
+	}
+
+	if scene.Description.Title != "a test" {
+		t.Errorf("Description.Title = %q, want %q", scene.Description.Title, "a test")
+	}
+	if scene.Camera.Hsize != 100 || scene.Camera.Vsize != 50 || scene.Camera.Fov != 1 {
+		t.Errorf("Camera = %v x %v, fov %v, want 100 x 50, fov 1", scene.Camera.Hsize, scene.Camera.Vsize, scene.Camera.Fov)
+	}
+	if want := (GradientBackground{Bottom: White, Top: Color{0, 0, 1}}); scene.Background != want {
+		t.Errorf("Background = %v, want %v", scene.Background, want)
+	}
+	if len(scene.Objects) != 2 {
+		t.Fatalf("len(Objects) = %d, want 2", len(scene.Objects))
+	}
+
+	stripes, ok := scene.Objects[0].GetMaterial().Pattern.(*StripePattern)
+	if !ok || stripes.PatternA != nil || stripes.PatternB == nil {
+		t.Errorf("plane pattern = %#v, want stripes with the second color replaced", scene.Objects[0].GetMaterial().Pattern)
+	}
+
+	sphere := scene.Objects[1]
+	if want := NewTranslation(1, 2, 3).Multiply(Scaling(2, 2, 2)); !cmp.Equal(sphere.GetTransform(), want, float64Comparer) {
+		t.Errorf("sphere transform = %v, want %v", sphere.GetTransform(), want)
+	}
+	want := NewMaterial()
+	want.Specular, want.Reflectivity, want.Color, want.Diffuse = 1, 0.5, Color{1, 0, 0}, 0.2
+	if diff := cmp.Diff(want, sphere.GetMaterial(), float64Comparer); diff != "" {
+		t.Errorf("sphere material differs (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseSceneJSON_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		json   string
+		index  int
+		line   int
+		column int
+		key    string
+		want   error
+	}{
+		{
+			name:  "not JSON",
+			json:  "{\n  \"objects\": [}",
+			index: -1, line: 2, column: 15,
+			want: nil,
+		},
+		{
+			name:  "an unknown key",
+			json:  "{\"objects\": [\n  {\"type\": \"plane\", \"colour\": [1, 0, 0]}\n]}",
+			index: -1, line: 2, column: 21, key: "colour",
+			want: ErrUnknownKey,
+		},
+		{
+			name:  "a value of the wrong type",
+			json:  "{\"objects\": [\n  {\"type\": \"plane\", \"transform\": [{\"scale\": 2}]}\n]}",
+			index: -1, line: 2, column: 45, key: "objects.0.transform.0.scale",
+			want: ErrInvalidValue,
+		},
+		{
+			name:  "an unknown key that appears more than once",
+			json:  "{\"objects\": [\n  {\"type\": \"plane\", \"colour\": [1, 0, 0]},\n  {\"type\": \"plane\", \"colour\": [1, 0, 0]}\n]}",
+			index: -1, key: "colour",
+			want: ErrUnknownKey,
+		},
+		{
+			name:  "more than one scene",
+			json:  "{\"objects\": []}\n{\"objects\": []}",
+			index: -1, line: 2, column: 2,
+			want: ErrInvalidValue,
+		},
+		{
+			name:  "an unknown type of object",
+			json:  `{"objects": [{"type": "sphere"}, {"type": "cone"}]}`,
+			index: 1, key: "type",
+			want: ErrUnknownType,
+		},
+		{
+			name:  "a transform that does two things",
+			json:  `{"objects": [{"type": "sphere", "transform": [{"scale": [1, 1, 1]}, {"rotate-x": 1, "rotate-y": 1}]}]}`,
+			index: 0, key: "transform[1]",
+			want: ErrInvalidValue,
+		},
+		{
+			name:  "a nested pattern with one color",
+			json:  `{"objects": [{"type": "plane", "material": {"pattern": {"type": "blend", "patterns": [{"type": "rings", "colors": [[1, 1, 1]]}, {"type": "solid", "color": [0, 0, 0]}]}}}]}`,
+			index: 0, key: "material.pattern.patterns[0].colors",
+			want: ErrInvalidValue,
+		},
+		{
+			name:  "a noise strength under the key of other patterns",
+			json:  `{"objects": [{"type": "plane", "material": {"pattern": {"type": "perturbed", "turbulence": 2, "pattern": {"type": "solid", "color": [1, 1, 1]}}}}]}`,
+			index: 0, key: "material.pattern.turbulence",
+			want: ErrInvalidValue,
+		},
+		{
+			name:  "an unknown conductor",
+			json:  `{"objects": [{"type": "sphere", "material": {"conductor": {"preset": "brass"}}}]}`,
+			index: 0, key: "material.conductor.preset",
+			want: ErrUnknownType,
+		},
+		{
+			name:  "extending an unknown material",
+			json:  `{"objects": [{"type": "sphere", "material": {"extends": "glass"}}]}`,
+			index: 0, key: "material.extends",
+			want: ErrUnknownDefine,
+		},
+		{
+			name:  "materials that extend each other",
+			json:  `{"materials": {"a": {"extends": "b"}, "b": {"extends": "a"}}, "objects": [{"type": "sphere", "material": {"extends": "a"}}]}`,
+			index: 0, key: "materials.b.extends",
+			want: ErrInvalidValue,
+		},
+		{
+			name:  "a background without its colors",
+			json:  `{"background": {"type": "solid"}, "objects": []}`,
+			index: -1, key: "background.color",
+			want: ErrMissingKey,
+		},
+		{
+			name:  "an animation without frames",
+			json:  `{"animation": {"frames": 0}, "objects": []}`,
+			index: -1, key: "animation.frames",
+			want: ErrInvalidValue,
+		},
+		{
+			name:  "a camera with a negative width",
+			json:  `{"camera": {"width": -10, "height": 10, "field-of-view": 1, "from": [0, 0, -5], "to": [0, 0, 0], "up": [0, 1, 0]}, "objects": []}`,
+			index: -1, key: "camera",
+			want: ErrInvalidValue,
+		},
+		{
+			name:  "a camera track of a property it doesn't have",
+			json:  `{"camera": {"width": 10, "height": 10, "field-of-view": 1, "from": [0, 0, -5], "to": [0, 0, 0], "up": [0, 1, 0], "animate": {"at": [{"frame": 0, "value": [0, 0, 0]}]}}, "objects": []}`,
+			index: -1, key: "camera.animate.at",
+			want: ErrUnknownKey,
+		},
+		{
+			name:  "an unknown easing",
+			json:  `{"objects": [{"type": "sphere", "animate": {"rotate-y": [{"frame": 0, "value": [0]}, {"frame": 10, "value": [1], "ease": "bounce"}]}}]}`,
+			index: 0, key: "animate.rotate-y[1].ease",
+			want: ErrUnknownType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSceneJSON([]byte(tt.json), ".")
+			var se *SceneError
+			if !errors.As(err, &se) {
+				t.Fatalf("ParseSceneJSON() error = %v, want a *SceneError", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("ParseSceneJSON() error = %v, want %v", err, tt.want)
+			}
+			if se.Index != tt.index || se.Line != tt.line || se.Column != tt.column || se.Key != tt.key {
+				t.Errorf("ParseSceneJSON() error at entry %d, %d:%d, key %q, want entry %d, %d:%d, key %q",
+					se.Index, se.Line, se.Column, se.Key, tt.index, tt.line, tt.column, tt.key)
+			}
+		})
+	}
+}
+
+func TestLoadSceneFile_JSON(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"scene.json": `{"background": {"type": "equirectangular", "file": "sky.ppm"}, "objects": [{"type": "cube"}]}`,
+		"good.json":  `{"background": {"type": "equirectangular", "file": "sky.ppm"}, "objects": []}`,
+		"sky.ppm":    "P3 1 1 255 0 0 0\n",
+	})
+
+	if _, err := LoadSceneFile(filepath.Join(dir, "good.json")); err != nil {
+		t.Errorf("LoadSceneFile() error = %v", err)
+	}
+
+	_, err := LoadSceneFile(filepath.Join(dir, "scene.json"))
+	var se *SceneError
+	if !errors.As(err, &se) || se.File != filepath.Join(dir, "scene.json") {
+		t.Errorf("LoadSceneFile() error = %v, want a *SceneError in scene.json", err)
+	}
+}
+
+func TestValidateSceneJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want []error
+	}{
+		{
+			name: "a valid scene",
+			json: `{"camera": {"width": 10, "height": 10, "field-of-view": 1, "from": [0, 0, -5], "to": [0, 0, 0], "up": [0, 1, 0]},
+				"light": {"at": [0, 10, 0], "intensity": [1, 1, 1]}, "objects": [{"type": "sphere"}]}`,
+		},
+		{
+			name: "a scene that doesn't decode",
+			json: `{"objects": [{"type": "cone"}]}`,
+			want: []error{ErrUnknownType},
+		},
+		{
+			name: "an empty scene",
+			json: `{"objects": []}`,
+			want: []error{ErrNoCamera, ErrNoLight},
+		},
+		{
+			name: "objects to lint",
+			json: `{"environment": {"file": "sky.ppm"}, "objects": [
+				{"type": "sphere", "transform": [{"scale": [1, 0, 1]}]},
+				{"type": "sphere", "material": {"transparency": 1}}]}`,
+			want: []error{ErrNoCamera, ErrSingularTransform, ErrNoRefraction},
+		},
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"sky.ppm": "P3 1 1 255 0 0 0\n"})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ValidateSceneJSON([]byte(tt.json), dir)
+			if len(got) != len(tt.want) {
+				t.Fatalf("ValidateSceneJSON() = %v, want %v", got, tt.want)
+			}
+			for i, p := range got {
+				if !errors.Is(p, tt.want[i]) {
+					t.Errorf("ValidateSceneJSON()[%d] = %v, want %v", i, p, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestSceneSchema checks that every struct of the JSON scene format has the same fields as its definition in the
+// schema, so that the two don't drift apart
+func TestSceneSchema(t *testing.T) {
+	data, err := os.ReadFile("schema/scene.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type definition struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	var schema struct {
+		definition
+		Defs map[string]definition `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("schema isn't JSON: %v", err)
+	}
+
+	for def, v := range map[string]interface{}{
+		"":            JSONScene{},
+		"description": SceneDescription{},
+		"camera":      JSONCamera{},
+		"light":       JSONLight{},
+		"background":  JSONBackground{},
+		"cubeFiles":   JSONCubeFiles{},
+		"environment": JSONEnvironment{},
+		"object":      JSONObject{},
+		"transform":   JSONTransform{},
+		"material":    JSONMaterial{},
+		"shading":     JSONShading{},
+		"conductor":   JSONConductor{},
+		"dispersion":  JSONDispersion{},
+		"bump":        JSONBump{},
+		"normalMap":   JSONNormalMap{},
+		"bumpMap":     JSONBumpMap{},
+		"pattern":     JSONPattern{},
+		"animation":   JSONAnimation{},
+		"keyframe":    JSONKeyframe{},
+	} {
+		d, ok := schema.Defs[def], true
+		if def == "" {
+			d = schema.definition
+		} else if _, ok = schema.Defs[def]; !ok {
+			t.Errorf("schema has no definition of %s", def)
+			continue
+		}
+
+		var fields, properties []string
+		typ := reflect.TypeOf(v)
+		for i := 0; i < typ.NumField(); i++ {
+			fields = append(fields, strings.Split(typ.Field(i).Tag.Get("json"), ",")[0])
+		}
+		for p := range d.Properties {
+			properties = append(properties, p)
+		}
+		sort.Strings(fields)
+		sort.Strings(properties)
+
+		if diff := cmp.Diff(fields, properties); diff != "" {
+			t.Errorf("%s fields differ from the schema's %q properties (-fields +schema):\n%s", typ.Name(), def, diff)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package jtracer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// nodeError reports a problem with the value of key, found at node n
+func nodeError(n *yaml.Node, key string, err error) *SceneError {
+	if n == nil {
+		return &SceneError{Index: -1, Key: key, Err: err}
+	}
+	return &SceneError{Index: -1, Line: n.Line, Column: n.Column, Key: key, Err: err}
+}
+
+// invalid reports that the value of key at n isn't what was expected
+func invalid(n *yaml.Node, key string, format string, args ...interface{}) *SceneError {
+	return nodeError(n, key, fmt.Errorf("%w: %s", ErrInvalidValue, fmt.Sprintf(format, args...)))
+}
+
+// keyed names the key an error was found under when the error doesn't name one already
+func keyed(err error, key string) error {
+	var se *SceneError
+	if errors.As(err, &se) && se.Key == "" {
+		se.Key = key
+	}
+	return err
+}
+
+// firstError returns the first of errs that isn't nil
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveAlias follows YAML aliases to the node they refer to
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n != nil && n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+// lookup returns the value of key in a mapping node, or nil when it's absent
+func lookup(n *yaml.Node, key string) *yaml.Node {
+	n = resolveAlias(n)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return resolveAlias(n.Content[i+1])
+		}
+	}
+	return nil
+}
+
+// require returns the value of key in the mapping node n, reporting it missing when it's absent
+func require(n *yaml.Node, key string) (*yaml.Node, error) {
+	v := lookup(n, key)
+	if v == nil {
+		return nil, nodeError(n, key, ErrMissingKey)
+	}
+	return v, nil
+}
+
+// pairs calls f with each key and value of a mapping node in order, stopping at the first error
+func pairs(n *yaml.Node, f func(key string, value *yaml.Node) error) error {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if err := f(n.Content[i].Value, resolveAlias(n.Content[i+1])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expectMapping checks that the value of key is a map
+func expectMapping(n *yaml.Node, key string) error {
+	if n.Kind != yaml.MappingNode {
+		return invalid(n, key, "expected a map")
+	}
+	return nil
+}
+
+// expectSequence checks that the value of key is a list
+func expectSequence(n *yaml.Node, key string) error {
+	if n.Kind != yaml.SequenceNode {
+		return invalid(n, key, "expected a list")
+	}
+	return nil
+}
+
+// decodeFloat reads a number, which may be written as an expression
+func (sc scope) decodeFloat(n *yaml.Node, key string) (float64, error) {
+	var f float64
+	if n.Kind == yaml.ScalarNode && n.ShortTag() == "!!str" {
+		return sc.decodeExpression(n, key)
+	}
+	if n.Kind != yaml.ScalarNode || (n.ShortTag() != "!!int" && n.ShortTag() != "!!float") || n.Decode(&f) != nil {
+		return 0, invalid(n, key, "expected a number, got %q", n.Value)
+	}
+	return f, nil
+}
+
+// decodeInt reads a whole number, which may be written as an expression
+func (sc scope) decodeInt(n *yaml.Node, key string) (int, error) {
+	var i int
+	if n.Kind == yaml.ScalarNode && n.ShortTag() == "!!str" {
+		f, err := sc.decodeExpression(n, key)
+		if err == nil && f != math.Trunc(f) {
+			err = invalid(n, key, "expected a whole number, got %q, which is %g", n.Value, f)
+		}
+		return int(f), err
+	}
+	if n.Kind != yaml.ScalarNode || n.ShortTag() != "!!int" || n.Decode(&i) != nil {
+		return 0, invalid(n, key, "expected a whole number, got %q", n.Value)
+	}
+	return i, nil
+}
+
+// decodeExpression works out the value of an expression, which may refer to the names in scope
+func (sc scope) decodeExpression(n *yaml.Node, key string) (float64, error) {
+	vars, err := sc.vars(n, key)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := EvalExpression(n.Value, vars)
+	if err != nil {
+		return 0, invalid(n, key, "expected a number, got %q: %v", n.Value, err)
+	}
+	return f, nil
+}
+
+func decodeBool(n *yaml.Node, key string) (bool, error) {
+	var b bool
+	if n.Kind != yaml.ScalarNode || n.ShortTag() != "!!bool" || n.Decode(&b) != nil {
+		return false, invalid(n, key, "expected true or false, got %q", n.Value)
+	}
+	return b, nil
+}
+
+func decodeString(n *yaml.Node, key string) (string, error) {
+	if n.Kind != yaml.ScalarNode {
+		return "", invalid(n, key, "expected a string")
+	}
+	return n.Value, nil
+}
+
+// decodeFloats reads a list of exactly count numbers
+func (sc scope) decodeFloats(n *yaml.Node, key string, count int) ([]float64, error) {
+	if err := expectSequence(n, key); err != nil {
+		return nil, err
+	}
+	if len(n.Content) != count {
+		return nil, invalid(n, key, "expected %d numbers, got %d", count, len(n.Content))
+	}
+
+	f, err := sc.convertToFloat64(n.Content)
+	return f, keyed(err, key)
+}
+
+// optionalFloat sets *dst to the value of key in the mapping node n, when present
+func (sc scope) optionalFloat(n *yaml.Node, key string, dst *float64) (err error) {
+	if v := lookup(n, key); v != nil {
+		*dst, err = sc.decodeFloat(v, key)
+	}
+	return err
+}
+
+// optionalInt sets *dst to the value of key in the mapping node n, when present
+func (sc scope) optionalInt(n *yaml.Node, key string, dst *int) (err error) {
+	if v := lookup(n, key); v != nil {
+		*dst, err = sc.decodeInt(v, key)
+	}
+	return err
+}
+
+// optionalBool sets *dst to the value of key in the mapping node n, when present
+func optionalBool(n *yaml.Node, key string, dst *bool) (err error) {
+	if v := lookup(n, key); v != nil {
+		*dst, err = decodeBool(v, key)
+	}
+	return err
+}
+
+// requiredString returns the value of key in the mapping node n, which must be present
+func requiredString(n *yaml.Node, key string) (string, error) {
+	v, err := require(n, key)
+	if err != nil {
+		return "", err
+	}
+	return decodeString(v, key)
+}
+
+// The functions below build the nodes of a scene being saved
+
+// mappingNode builds a map from alternating keys and values
+func mappingNode(pairs ...interface{}) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		n.Content = append(n.Content, stringNode(pairs[i].(string)), pairs[i+1].(*yaml.Node))
+	}
+	return n
+}
+
+// appendPair adds a key and its value to a mapping node
+func appendPair(n *yaml.Node, key string, value *yaml.Node) {
+	n.Content = append(n.Content, stringNode(key), value)
+}
+
+func sequenceNode(items ...*yaml.Node) *yaml.Node {
+	return &yaml.Node{Kind: yaml.SequenceNode, Content: items}
+}
+
+func stringNode(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+}
+
+// floatNode writes f with 12 significant digits, enough to hide the rounding errors of decomposing transforms
+func floatNode(f float64) *yaml.Node {
+	s := strconv.FormatFloat(f, 'g', 12, 64)
+	if s == "-0" {
+		s = "0"
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: s}
+}
+
+func intNode(i int) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: strconv.Itoa(i)}
+}
+
+func boolNode(b bool) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(b)}
+}
+
+// floatsNode builds a list of numbers, written on one line
+func floatsNode(fs ...float64) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+	for _, f := range fs {
+		n.Content = append(n.Content, floatNode(f))
+	}
+	return n
+}
+
+func colorNode(c Color) *yaml.Node {
+	return floatsNode(c.Red, c.Green, c.Blue)
+}
@@ -0,0 +1,148 @@
+package jtracer
+
+import (
+	"math"
+)
+
+// ShadingModel computes the color of a point on a surface lit by a single light. color is the surface color at
+// the point, already resolved from the material's pattern if it has one.
+type ShadingModel interface {
+	Shade(m Material, color Color, light Light, point, eyev, normalv Tuple, inShadow bool) Color
+}
+
+// PhongShading is the Phong reflection model described in The Ray Tracer Challenge
+type PhongShading struct{}
+
+// BlinnPhongShading replaces Phong's reflection vector with the halfway vector between the light and the eye,
+// which keeps highlights round at grazing angles
+type BlinnPhongShading struct{}
+
+// LambertShading only has ambient and diffuse terms, for perfectly matte surfaces
+type LambertShading struct{}
+
+// ToonShading quantizes diffuse light into flat bands, gives highlights a hard edge and draws an outline where the
+// surface turns away from the eye.
+type ToonShading struct {
+	Bands        int     // number of diffuse bands, 3 when zero
+	Outline      float64 // surfaces whose normal is within this cosine of perpendicular to the eye are outlined
+	OutlineColor Color
+}
+
+// DefaultToonBands is the number of diffuse bands used by ToonShading when none is given
+const DefaultToonBands = 3
+
+// lightTerms holds the quantities shared by every shading model
+type lightTerms struct {
+	effectiveColor Color   // the surface color combined with the light's color/intensity
+	ambient        Color   // the ambient contribution
+	lightv         Tuple   // the direction to the light source
+	lightDotNormal float64 // cosine of the angle between the light vector and the normal vector
+}
+
+func newLightTerms(m Material, color Color, light Light, point, normalv Tuple) lightTerms {
+	effectiveColor := color.Multiply(&light.Intensity)
+	lightv := light.Position.Subtract(&point).Normalize()
+
+	return lightTerms{
+		effectiveColor: *effectiveColor,
+		ambient:        *effectiveColor.MultiplyByScalar(m.Ambient),
+		lightv:         *lightv,
+		lightDotNormal: lightv.Dot(&normalv),
+	}
+}
+
+func (PhongShading) Shade(m Material, color Color, light Light, point, eyev, normalv Tuple, inShadow bool) Color {
+	t := newLightTerms(m, color, light, point, normalv)
+
+	// light_dot_normal represents the cosine of the angle between the
+	// light vector and the normal vector. A negative number means the
+	// light is on the other side of the surface.
+
+	var diffuse, specular Color
+	if t.lightDotNormal < 0 || inShadow {
+		diffuse = Black
+		specular = Black
+	} else {
+		diffuse = *t.effectiveColor.MultiplyByScalar(m.Diffuse)
+		diffuse = *diffuse.MultiplyByScalar(t.lightDotNormal)
+
+		//  reflect_dot_eye represents the cosine of the angle between the
+		//  reflection vector and the eye vector. A negative number means the
+		//  light reflects away from the eye.
+		reflectV := t.lightv.Multiply(-1).Reflect(normalv)
+		reflectDotEye := reflectV.Dot(&eyev)
+
+		if reflectDotEye <= 0 {
+			specular = Black
+		} else {
+			// compute the specular contribution
+			factor := math.Pow(reflectDotEye, m.Shininess)
+			specular = light.Intensity
+			specular = *specular.MultiplyByScalar(m.Specular)
+			specular = *specular.MultiplyByScalar(factor)
+		}
+	}
+
+	c := t.ambient.Add(&diffuse)
+	c = c.Add(&specular)
+	return *c
+}
+
+func (BlinnPhongShading) Shade(m Material, color Color, light Light, point, eyev, normalv Tuple, inShadow bool) Color {
+	t := newLightTerms(m, color, light, point, normalv)
+	if t.lightDotNormal < 0 || inShadow {
+		return t.ambient
+	}
+
+	diffuse := t.effectiveColor.MultiplyByScalar(m.Diffuse * t.lightDotNormal)
+
+	// the halfway vector lies between the light and the eye; the closer it is to the normal the brighter the highlight
+	halfv := t.lightv.Add(&eyev).Normalize()
+	halfDotNormal := halfv.Dot(&normalv)
+
+	specular := Black
+	if halfDotNormal > 0 {
+		specular = *light.Intensity.MultiplyByScalar(m.Specular * math.Pow(halfDotNormal, m.Shininess))
+	}
+
+	return *t.ambient.Add(diffuse).Add(&specular)
+}
+
+func (LambertShading) Shade(m Material, color Color, light Light, point, _, normalv Tuple, inShadow bool) Color {
+	t := newLightTerms(m, color, light, point, normalv)
+	if t.lightDotNormal < 0 || inShadow {
+		return t.ambient
+	}
+
+	return *t.ambient.Add(t.effectiveColor.MultiplyByScalar(m.Diffuse * t.lightDotNormal))
+}
+
+func (s ToonShading) Shade(m Material, color Color, light Light, point, eyev, normalv Tuple, inShadow bool) Color {
+	// silhouettes are where the surface turns away from the eye
+	if eyev.Dot(&normalv) < s.Outline {
+		return s.OutlineColor
+	}
+
+	t := newLightTerms(m, color, light, point, normalv)
+	if t.lightDotNormal < 0 || inShadow {
+		return t.ambient
+	}
+
+	bands := s.Bands
+	if bands <= 0 {
+		bands = DefaultToonBands
+	}
+
+	// snap the diffuse intensity up to the next band so that fully lit surfaces stay fully lit
+	band := math.Ceil(t.lightDotNormal*float64(bands)) / float64(bands)
+	diffuse := t.effectiveColor.MultiplyByScalar(m.Diffuse * band)
+
+	// highlights are either on or off
+	specular := Black
+	reflectV := t.lightv.Multiply(-1).Reflect(normalv)
+	if reflectDotEye := reflectV.Dot(&eyev); reflectDotEye > 0 && math.Pow(reflectDotEye, m.Shininess) > 0.5 {
+		specular = *light.Intensity.MultiplyByScalar(m.Specular)
+	}
+
+	return *t.ambient.Add(diffuse).Add(&specular)
+}
@@ -0,0 +1,104 @@
+package jtracer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestShadingModel_Shade(t *testing.T) {
+	m := NewMaterial()
+
+	type args struct {
+		light   Light
+		eyev    Tuple
+		normalv Tuple
+	}
+	headOn := args{
+		light:   NewPointLight(*NewPoint(0, 0, -10), White),
+		eyev:    *NewVector(0, 0, -1),
+		normalv: *NewVector(0, 0, -1),
+	}
+	lightAt45 := args{
+		light:   NewPointLight(*NewPoint(0, 10, -10), White),
+		eyev:    *NewVector(0, 0, -1),
+		normalv: *NewVector(0, 0, -1),
+	}
+
+	tests := []struct {
+		name    string
+		shading ShadingModel
+		args    args
+		want    Color
+	}{
+		{
+			name:    "phong with the eye between the light and the surface",
+			shading: PhongShading{},
+			args:    headOn,
+			want:    Color{1.9, 1.9, 1.9},
+		},
+		{
+			name:    "blinn-phong with the eye between the light and the surface",
+			shading: BlinnPhongShading{},
+			args:    headOn,
+			want:    Color{1.9, 1.9, 1.9},
+		},
+		{
+			name:    "blinn-phong keeps a highlight when the light is offset 45 degrees",
+			shading: BlinnPhongShading{},
+			args:    lightAt45,
+			want: func() Color {
+				diffuse := 0.9 * math.Sqrt(2) / 2
+				specular := 0.9 * math.Pow(math.Cos(math.Pi/8), 200)
+				v := 0.1 + diffuse + specular
+				return Color{v, v, v}
+			}(),
+		},
+		{
+			name:    "lambert has no highlight",
+			shading: LambertShading{},
+			args:    headOn,
+			want:    Color{1.0, 1.0, 1.0},
+		},
+		{
+			name:    "toon rounds diffuse light up to the next band",
+			shading: ToonShading{Bands: 3},
+			args:    lightAt45,
+			want:    Color{1.0, 1.0, 1.0},
+		},
+		{
+			name:    "toon with more bands",
+			shading: ToonShading{Bands: 4},
+			args: args{
+				light:   NewPointLight(*NewPoint(0, 10, -1), White),
+				eyev:    *NewVector(0, 0, -1),
+				normalv: *NewVector(0, 0, -1),
+			},
+			want: Color{0.1 + 0.9*0.25, 0.1 + 0.9*0.25, 0.1 + 0.9*0.25},
+		},
+		{
+			name:    "toon highlights are either fully on or off",
+			shading: ToonShading{},
+			args:    headOn,
+			want:    Color{1.9, 1.9, 1.9},
+		},
+		{
+			name:    "toon outlines surfaces seen edge on",
+			shading: ToonShading{Outline: 0.2, OutlineColor: Red},
+			args: args{
+				light:   NewPointLight(*NewPoint(0, 0, -10), White),
+				eyev:    *NewVector(0, 0, -1),
+				normalv: *NewVector(0, 0.99, -0.1).Normalize(),
+			},
+			want: Red,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.Shading = tt.shading
+			got := m.Lighting(NewSphere(), tt.args.light, *NewPoint(0, 0, 0), tt.args.eyev, tt.args.normalv, false)
+			if !got.Equals(&tt.want) {
+				t.Errorf("Lighting() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
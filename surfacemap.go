@@ -0,0 +1,136 @@
+package jtracer
+
+import "math"
+
+// DefaultBumpDepth is the object space height a BumpMap raises white above black when its Depth is zero
+const DefaultBumpDepth = 0.01
+
+// uvDelta is the step along the surface used to measure how texture coordinates change
+const uvDelta = 1e-4
+
+// NormalMap tilts normals by the directions stored in a tangent space normal map. Red runs along the direction u
+// increases, green along the direction v increases and blue straight out of the surface, each mapped from [0, 1] to
+// [-1, 1], so the flat blue (0.5, 0.5, 1) leaves the normal unchanged.
+type NormalMap struct {
+	Image    *Canvas
+	File     string   // the image file the map was loaded from, if any
+	Mapper   UVMapper // ShapeMapper of the shape when nil
+	Strength float64  // scales the tilt away from the surface normal, 1 when zero
+}
+
+// BumpMap raises the surface by the brightness of a grayscale height map, tilting normals away from its slopes
+type BumpMap struct {
+	Image  *Canvas
+	File   string   // the image file the map was loaded from, if any
+	Mapper UVMapper // ShapeMapper of the shape when nil
+	Depth  float64  // object space height of white above black, DefaultBumpDepth when zero
+}
+
+// ShapeMapper returns the mapping that suits a shape's geometry best
+func ShapeMapper(s Shape) UVMapper {
+	if _, ok := s.(*Plane); ok {
+		return PlanarMap{}
+	}
+	return SphericalMap{}
+}
+
+// MappedNormalAt returns the world space normal at a point after tilting it by the material's normal and bump maps
+func (m Material) MappedNormalAt(object Shape, worldPoint Tuple) Tuple {
+	p := *object.GetInverse().MultiplyByTuple(worldPoint)
+	n := object.LocalNormalAt(p)
+	n = *n.Normalize()
+
+	if m.NormalMap != nil {
+		n = m.NormalMap.Perturb(object, p, n)
+	}
+	if m.BumpMap != nil {
+		n = m.BumpMap.Perturb(object, p, n)
+	}
+
+	worldNormal := object.GetInverseTranspose().MultiplyByTuple(n)
+	worldNormal.W = 0
+	return *worldNormal.Normalize()
+}
+
+// Perturb tilts the object space normal n at the object space point p
+func (nm *NormalMap) Perturb(object Shape, p, n Tuple) Tuple {
+	mapper := mapperOrDefault(nm.Mapper, object)
+	u, v := mapper.Map(p)
+	c := nm.Image.Sample(u, v)
+
+	// build the tangent frame from the directions in which u and v increase
+	gradU, gradV := uvGradients(mapper, p, n)
+	tangent := gradU.Subtract(n.Multiply(gradU.Dot(&n)))
+	if tangent.Magnitude() == 0 {
+		return n
+	}
+	tangent = tangent.Normalize()
+	bitangent := n.Cross(tangent)
+	if bitangent.Dot(&gradV) < 0 {
+		bitangent = bitangent.Negate()
+	}
+
+	strength := nonZero(nm.Strength, 1)
+	x, y, z := (2*c.Red-1)*strength, (2*c.Green-1)*strength, 2*c.Blue-1
+
+	return *tangent.Multiply(x).Add(bitangent.Multiply(y)).Add(n.Multiply(z)).Normalize()
+}
+
+// Perturb tilts the object space normal n at the object space point p
+func (bm *BumpMap) Perturb(object Shape, p, n Tuple) Tuple {
+	mapper := mapperOrDefault(bm.Mapper, object)
+	u, v := mapper.Map(p)
+
+	height := func(u, v float64) float64 {
+		c := bm.Image.Sample(u, v)
+		return c.Luminance()
+	}
+
+	// slope of the height map one texel either side of the point
+	du, dv := 1/float64(bm.Image.Width), 1/float64(bm.Image.Height)
+	dhdu := (height(u+du, v) - height(u-du, v)) / (2 * du)
+	dhdv := (height(u, v+dv) - height(u, v-dv)) / (2 * dv)
+
+	gradU, gradV := uvGradients(mapper, p, n)
+	gradient := gradU.Multiply(dhdu).Add(gradV.Multiply(dhdv))
+	tangential := gradient.Subtract(n.Multiply(gradient.Dot(&n)))
+
+	return *n.Subtract(tangential.Multiply(nonZero(bm.Depth, DefaultBumpDepth))).Normalize()
+}
+
+// uvGradients returns how quickly u and v change moving across the surface with normal n at p
+func uvGradients(mapper UVMapper, p, n Tuple) (gradU, gradV Tuple) {
+	a, b := tangentBasis(n)
+
+	u0, v0 := mapper.Map(p)
+	ua, va := mapper.Map(*p.Add(a.Multiply(uvDelta)))
+	ub, vb := mapper.Map(*p.Add(b.Multiply(uvDelta)))
+
+	// texture coordinates wrap around, so a tiny step can appear to jump by almost a whole unit
+	rate := func(d float64) float64 {
+		return wrapDelta(d) / uvDelta
+	}
+
+	gradU = *a.Multiply(rate(ua - u0)).Add(b.Multiply(rate(ub - u0)))
+	gradV = *a.Multiply(rate(va - v0)).Add(b.Multiply(rate(vb - v0)))
+	return gradU, gradV
+}
+
+// tangentBasis returns two unit vectors perpendicular to each other and to n
+func tangentBasis(n Tuple) (a, b Tuple) {
+	helper := NewVector(1, 0, 0)
+	if math.Abs(n.X) > 0.9 {
+		helper = NewVector(0, 1, 0)
+	}
+
+	a = *helper.Cross(&n).Normalize()
+	b = *n.Cross(&a)
+	return a, b
+}
+
+func mapperOrDefault(m UVMapper, object Shape) UVMapper {
+	if m == nil {
+		return ShapeMapper(object)
+	}
+	return m
+}
@@ -0,0 +1,121 @@
+package jtracer
+
+import (
+	"math"
+	"testing"
+)
+
+// filledCanvas returns a canvas of the given size with every pixel set to c
+func filledCanvas(width, height int, c Color) *Canvas {
+	canvas := NewCanvas(width, height)
+	for y := range canvas.Data {
+		for x := range canvas.Data[y] {
+			canvas.Data[y][x] = c
+		}
+	}
+	return canvas
+}
+
+func TestNormalMap_Perturb(t *testing.T) {
+	tests := []struct {
+		name   string
+		object Shape
+		nm     NormalMap
+		point  Tuple
+		want   Tuple
+	}{
+		{
+			name:   "a flat normal map leaves a sphere's normal unchanged",
+			object: NewSphere(),
+			nm:     NormalMap{Image: filledCanvas(2, 2, Color{0.5, 0.5, 1})},
+			point:  *NewPoint(math.Sqrt(3)/3, math.Sqrt(3)/3, math.Sqrt(3)/3),
+			want:   *NewVector(math.Sqrt(3)/3, math.Sqrt(3)/3, math.Sqrt(3)/3),
+		},
+		{
+			name:   "red tilts the normal towards increasing u",
+			object: NewPlane(),
+			nm:     NormalMap{Image: filledCanvas(2, 2, Color{0.75, 0.5, 1})},
+			point:  *NewPoint(0.5, 0, 0.5),
+			want:   *NewVector(1/math.Sqrt(5), 2/math.Sqrt(5), 0),
+		},
+		{
+			name:   "green tilts the normal towards increasing v",
+			object: NewPlane(),
+			nm:     NormalMap{Image: filledCanvas(2, 2, Color{0.5, 0.75, 1})},
+			point:  *NewPoint(0.5, 0, 0.5),
+			want:   *NewVector(0, 2/math.Sqrt(5), 1/math.Sqrt(5)),
+		},
+		{
+			name:   "strength exaggerates the tilt",
+			object: NewPlane(),
+			nm:     NormalMap{Image: filledCanvas(2, 2, Color{0.75, 0.5, 1}), Strength: 2},
+			point:  *NewPoint(0.5, 0, 0.5),
+			want:   *NewVector(math.Sqrt(2)/2, math.Sqrt(2)/2, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := tt.object.LocalNormalAt(tt.point)
+			if got := tt.nm.Perturb(tt.object, tt.point, n); !got.Equals(&tt.want) {
+				t.Errorf("Perturb() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBumpMap_Perturb(t *testing.T) {
+	// a height map that rises from left to right
+	ramp := NewCanvas(4, 1)
+	for x := 0; x < 4; x++ {
+		g := float64(x) / 4
+		ramp.Data[0][x] = Color{g, g, g}
+	}
+
+	tests := []struct {
+		name string
+		bm   BumpMap
+		want Tuple
+	}{
+		{
+			name: "a flat height map leaves the normal unchanged",
+			bm:   BumpMap{Image: filledCanvas(4, 4, White)},
+			want: *NewVector(0, 1, 0),
+		},
+		{
+			name: "the normal tilts away from a rising slope",
+			bm:   BumpMap{Image: ramp, Depth: 0.1},
+			want: *NewVector(-0.1, 1, 0).Normalize(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPlane()
+			if got := tt.bm.Perturb(p, *NewPoint(0.5, 0, 0.5), *NewVector(0, 1, 0)); !got.Equals(&tt.want) {
+				t.Errorf("Perturb() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareComputations_SurfaceMaps(t *testing.T) {
+	p := NewPlane()
+	p.SetTransform(NewTranslation(0, 1, 0))
+	p.Material.NormalMap = &NormalMap{Image: filledCanvas(2, 2, Color{1, 0.5, 0.5})}
+
+	r := NewRay(NewPoint(0.5, 2, 0.5), NewVector(0, -1, 0))
+	i := Intersection{T: 1, Object: p}
+	comps := i.PrepareComputations(r, Intersections{i})
+
+	if want := NewVector(1, 0, 0); !comps.Normalv.Equals(want) {
+		t.Errorf("PrepareComputations() normal = %v, want %v", comps.Normalv, want)
+	}
+}
+
+func TestShapeMapper(t *testing.T) {
+	if _, ok := ShapeMapper(NewPlane()).(PlanarMap); !ok {
+		t.Errorf("ShapeMapper() of a plane = %T, want PlanarMap", ShapeMapper(NewPlane()))
+	}
+	if _, ok := ShapeMapper(NewSphere()).(SphericalMap); !ok {
+		t.Errorf("ShapeMapper() of a sphere = %T, want SphericalMap", ShapeMapper(NewSphere()))
+	}
+}
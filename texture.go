@@ -0,0 +1,330 @@
+package jtracer
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// UVMapper maps a point in pattern space onto the two dimensional texture coordinates u and v, each in [0, 1)
+type UVMapper interface {
+	Map(p Tuple) (u, v float64)
+}
+
+// SphericalMap wraps a texture around a unit sphere like a world map around a globe
+type SphericalMap struct{}
+
+// PlanarMap repeats a texture every unit across the xz plane
+type PlanarMap struct{}
+
+// CylindricalMap wraps a texture around the y axis, repeating every unit along it
+type CylindricalMap struct{}
+
+// CubicMap projects each face of a unit cube onto its own region of a texture laid out as a horizontal cross:
+//
+//	    up
+//	left front right back
+//	    down
+type CubicMap struct{}
+
+func (SphericalMap) Map(p Tuple) (u, v float64) {
+	// compute the azimuthal angle, -π < theta <= π, which increases clockwise when viewed from above
+	theta := math.Atan2(p.X, p.Z)
+
+	// compute the polar angle, 0 <= phi <= π
+	radius := math.Sqrt(p.X*p.X + p.Y*p.Y + p.Z*p.Z)
+	phi := math.Acos(p.Y / radius)
+
+	// subtract from 1 so that u increases counter-clockwise when viewed from above
+	rawU := theta / (2 * math.Pi)
+	u = 1 - (rawU + 0.5)
+
+	// flip v so that 0 is the south pole and 1 the north pole
+	v = 1 - phi/math.Pi
+
+	return u, v
+}
+
+func (PlanarMap) Map(p Tuple) (u, v float64) {
+	return fraction(p.X), fraction(p.Z)
+}
+
+func (CylindricalMap) Map(p Tuple) (u, v float64) {
+	theta := math.Atan2(p.X, p.Z)
+	rawU := theta / (2 * math.Pi)
+	return 1 - (rawU + 0.5), fraction(p.Y)
+}
+
+// CubeFace identifies a face of the unit cube
+type CubeFace int
+
+const (
+	CubeLeft CubeFace = iota
+	CubeFront
+	CubeRight
+	CubeBack
+	CubeUp
+	CubeDown
+)
+
+// FaceFromPoint returns the face of the unit cube nearest a point
+func FaceFromPoint(p Tuple) CubeFace {
+	coord := math.Max(math.Abs(p.X), math.Max(math.Abs(p.Y), math.Abs(p.Z)))
+
+	switch coord {
+	case p.X:
+		return CubeRight
+	case -p.X:
+		return CubeLeft
+	case p.Y:
+		return CubeUp
+	case -p.Y:
+		return CubeDown
+	case p.Z:
+		return CubeFront
+	}
+
+	return CubeBack
+}
+
+// CubeFaceUV returns the texture coordinates of a point within the given face of the unit cube
+func CubeFaceUV(face CubeFace, p Tuple) (u, v float64) {
+	switch face {
+	case CubeFront:
+		return math.Mod(p.X+1, 2) / 2, math.Mod(p.Y+1, 2) / 2
+	case CubeBack:
+		return math.Mod(1-p.X, 2) / 2, math.Mod(p.Y+1, 2) / 2
+	case CubeLeft:
+		return math.Mod(p.Z+1, 2) / 2, math.Mod(p.Y+1, 2) / 2
+	case CubeRight:
+		return math.Mod(1-p.Z, 2) / 2, math.Mod(p.Y+1, 2) / 2
+	case CubeUp:
+		return math.Mod(p.X+1, 2) / 2, math.Mod(1-p.Z, 2) / 2
+	}
+
+	return math.Mod(p.X+1, 2) / 2, math.Mod(p.Z+1, 2) / 2
+}
+
+// crossLayout gives the column and row, counting up from the bottom, of each face in a horizontal cross
+var crossLayout = map[CubeFace][2]float64{
+	CubeLeft:  {0, 1},
+	CubeFront: {1, 1},
+	CubeRight: {2, 1},
+	CubeBack:  {3, 1},
+	CubeUp:    {1, 2},
+	CubeDown:  {1, 0},
+}
+
+func (CubicMap) Map(p Tuple) (u, v float64) {
+	face := FaceFromPoint(p)
+	faceU, faceV := CubeFaceUV(face, p)
+	cell := crossLayout[face]
+
+	return (cell[0] + faceU) / 4, (cell[1] + faceV) / 3
+}
+
+// fraction returns the fractional part of f, always in [0, 1)
+func fraction(f float64) float64 {
+	return f - math.Floor(f)
+}
+
+// TextureMapPattern looks up colors in an image using a UVMapper
+type TextureMapPattern struct {
+	Mapper  UVMapper
+	Image   *Canvas
+	File    string    // the image file the texture was loaded from, if any
+	Mipmaps []*Canvas // Image and its successively halved copies, from BuildMipmaps, for filtering
+	AbstractPattern
+}
+
+func NewTextureMapPattern(mapper UVMapper, img *Canvas) *TextureMapPattern {
+	p := &TextureMapPattern{Mapper: mapper, Image: img, Mipmaps: BuildMipmaps(img)}
+	p.SetTransform(IdentityMatrix)
+	return p
+}
+
+func (s *TextureMapPattern) ColorAt(p Tuple) Color {
+	u, v := s.Mapper.Map(p)
+	return s.Image.Sample(u, v)
+}
+
+// Sample returns the color at texture coordinates u and v with bilinear filtering. u wraps around the canvas
+// horizontally; v runs from 0 at the bottom row to 1 at the top and is clamped at the edges. Coordinates that aren't
+// finite, as a mapping gives at its poles, are taken as 0.
+func (c *Canvas) Sample(u, v float64) Color {
+	u, v = finite(u), finite(v)
+	x := fraction(u)*float64(c.Width) - 0.5
+	y := (1-v)*float64(c.Height) - 0.5
+
+	x0, y0 := math.Floor(x), math.Floor(y)
+	tx, ty := x-x0, y-y0
+
+	texel := func(x, y float64) Color {
+		xi := int(x) % c.Width
+		if xi < 0 {
+			xi += c.Width
+		}
+		yi := int(math.Min(math.Max(y, 0), float64(c.Height-1)))
+		return c.Data[yi][xi]
+	}
+
+	top := blend(texel(x0, y0), texel(x0+1, y0), tx)
+	bottom := blend(texel(x0, y0+1), texel(x0+1, y0+1), tx)
+	return blend(top, bottom, ty)
+}
+
+// finite returns f, or 0 when f is infinite or not a number
+func finite(f float64) float64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0
+	}
+	return f
+}
+
+// LoadImage reads a PNG, JPEG, PPM or Radiance HDR file into a canvas
+func LoadImage(path string) (*Canvas, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	switch strings.ToLower(path[strings.LastIndex(path, ".")+1:]) {
+	case "ppm":
+		return ReadPPM(f)
+	case "hdr":
+		return ReadHDR(f)
+	}
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+
+	return CanvasFromImage(img), nil
+}
+
+// CanvasFromImage converts a decoded image into a canvas of colors in the range 0 to 1
+func CanvasFromImage(img image.Image) *Canvas {
+	bounds := img.Bounds()
+	c := NewCanvas(bounds.Dx(), bounds.Dy())
+	for y := 0; y < c.Height; y++ {
+		for x := 0; x < c.Width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			c.Data[y][x] = Color{float64(r) / 0xffff, float64(g) / 0xffff, float64(b) / 0xffff}
+		}
+	}
+	return c
+}
+
+// ReadPPM reads a plain (P3) or raw (P6) portable pixmap
+func ReadPPM(r io.Reader) (*Canvas, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := ppmToken(br)
+	if err != nil {
+		return nil, err
+	}
+	if magic != "P3" && magic != "P6" {
+		return nil, fmt.Errorf("ppm: unsupported format %q", magic)
+	}
+
+	var header [3]int
+	for i := range header {
+		token, err := ppmToken(br)
+		if err != nil {
+			return nil, err
+		}
+		if header[i], err = strconv.Atoi(token); err != nil {
+			return nil, fmt.Errorf("ppm: invalid header: %w", err)
+		}
+	}
+	width, height, maxValue := header[0], header[1], float64(header[2])
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("ppm: invalid size %dx%d", width, height)
+	}
+	if maxValue < 1 || maxValue > 65535 {
+		return nil, fmt.Errorf("ppm: invalid maximum value %d", header[2])
+	}
+
+	c := NewCanvas(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var rgb [3]float64
+			for i := range rgb {
+				var value int
+				if magic == "P3" {
+					token, err := ppmToken(br)
+					if err != nil {
+						return nil, err
+					}
+					if value, err = strconv.Atoi(token); err != nil {
+						return nil, fmt.Errorf("ppm: invalid pixel: %w", err)
+					}
+				} else {
+					value, err = ppmByte(br, maxValue > 255)
+					if err != nil {
+						return nil, err
+					}
+				}
+				rgb[i] = float64(value) / maxValue
+			}
+			c.Data[y][x] = Color{rgb[0], rgb[1], rgb[2]}
+		}
+	}
+
+	return c, nil
+}
+
+// ppmToken reads the next whitespace separated token, skipping # comments
+func ppmToken(br *bufio.Reader) (string, error) {
+	var token []byte
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(token) > 0 {
+				return string(token), nil
+			}
+			return "", fmt.Errorf("ppm: unexpected end of file")
+		}
+
+		switch {
+		case b == '#' && len(token) == 0:
+			if _, err := br.ReadString('\n'); err != nil {
+				return "", fmt.Errorf("ppm: unexpected end of file")
+			}
+		case b == ' ' || b == '\t' || b == '\n' || b == '\r':
+			if len(token) > 0 {
+				return string(token), nil
+			}
+		default:
+			token = append(token, b)
+		}
+	}
+}
+
+// ppmByte reads a single binary sample, which takes two bytes when the maximum value exceeds 255
+func ppmByte(br *bufio.Reader, wide bool) (int, error) {
+	hi, err := br.ReadByte()
+	if err != nil {
+		return 0, fmt.Errorf("ppm: unexpected end of file")
+	}
+	if !wide {
+		return int(hi), nil
+	}
+
+	lo, err := br.ReadByte()
+	if err != nil {
+		return 0, fmt.Errorf("ppm: unexpected end of file")
+	}
+	return int(hi)<<8 | int(lo), nil
+}
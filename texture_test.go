@@ -0,0 +1,237 @@
+package jtracer
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUVMapper_Map(t *testing.T) {
+	tests := []struct {
+		name   string
+		mapper UVMapper
+		p      Tuple
+		u, v   float64
+	}{
+		{name: "using a spherical mapping on a 3D point", mapper: SphericalMap{}, p: *NewPoint(0, 0, -1), u: 0.0, v: 0.5},
+		{name: "using a spherical mapping on a 3D point", mapper: SphericalMap{}, p: *NewPoint(1, 0, 0), u: 0.25, v: 0.5},
+		{name: "using a spherical mapping on a 3D point", mapper: SphericalMap{}, p: *NewPoint(0, 0, 1), u: 0.5, v: 0.5},
+		{name: "using a spherical mapping on a 3D point", mapper: SphericalMap{}, p: *NewPoint(-1, 0, 0), u: 0.75, v: 0.5},
+		{name: "using a spherical mapping on a 3D point", mapper: SphericalMap{}, p: *NewPoint(0, 1, 0), u: 0.5, v: 1.0},
+		{name: "using a spherical mapping on a 3D point", mapper: SphericalMap{}, p: *NewPoint(0, -1, 0), u: 0.5, v: 0.0},
+		{name: "using a spherical mapping on a 3D point", mapper: SphericalMap{}, p: *NewPoint(math.Sqrt(2)/2, math.Sqrt(2)/2, 0), u: 0.25, v: 0.75},
+		{name: "using a planar mapping on a 3D point", mapper: PlanarMap{}, p: *NewPoint(0.25, 0, 0.5), u: 0.25, v: 0.5},
+		{name: "using a planar mapping on a 3D point", mapper: PlanarMap{}, p: *NewPoint(0.25, 0, -0.25), u: 0.25, v: 0.75},
+		{name: "using a planar mapping on a 3D point", mapper: PlanarMap{}, p: *NewPoint(0.25, 0.5, -0.25), u: 0.25, v: 0.75},
+		{name: "using a planar mapping on a 3D point", mapper: PlanarMap{}, p: *NewPoint(1.25, 0, 0.5), u: 0.25, v: 0.5},
+		{name: "using a planar mapping on a 3D point", mapper: PlanarMap{}, p: *NewPoint(0.25, 0, -1.75), u: 0.25, v: 0.25},
+		{name: "using a cylindrical mapping on a 3D point", mapper: CylindricalMap{}, p: *NewPoint(0, 0, -1), u: 0.0, v: 0.0},
+		{name: "using a cylindrical mapping on a 3D point", mapper: CylindricalMap{}, p: *NewPoint(0, 0.5, -1), u: 0.0, v: 0.5},
+		{name: "using a cylindrical mapping on a 3D point", mapper: CylindricalMap{}, p: *NewPoint(0, 1, -1), u: 0.0, v: 0.0},
+		{name: "using a cylindrical mapping on a 3D point", mapper: CylindricalMap{}, p: *NewPoint(0.70711, 0.5, -0.70711), u: 0.125, v: 0.5},
+		{name: "using a cylindrical mapping on a 3D point", mapper: CylindricalMap{}, p: *NewPoint(-0.70711, -0.25, 0.70711), u: 0.625, v: 0.75},
+		{name: "a cubic mapping places the front face in the middle of the cross", mapper: CubicMap{}, p: *NewPoint(0, 0, 1), u: 0.375, v: 0.5},
+		{name: "a cubic mapping places the up face at the top of the cross", mapper: CubicMap{}, p: *NewPoint(0, 1, 0), u: 0.375, v: 2.5 / 3},
+		{name: "a cubic mapping places the back face on the right of the cross", mapper: CubicMap{}, p: *NewPoint(0, 0, -1), u: 0.875, v: 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, v := tt.mapper.Map(tt.p)
+			if !cmp.Equal(u, tt.u, float64Comparer) || !cmp.Equal(v, tt.v, float64Comparer) {
+				t.Errorf("Map() = %v, %v, want %v, %v", u, v, tt.u, tt.v)
+			}
+		})
+	}
+}
+
+func TestFaceFromPoint(t *testing.T) {
+	tests := []struct {
+		p    Tuple
+		want CubeFace
+	}{
+		{*NewPoint(-1, 0.5, -0.25), CubeLeft},
+		{*NewPoint(1.1, -0.75, 0.8), CubeRight},
+		{*NewPoint(0.1, 0.6, 0.9), CubeFront},
+		{*NewPoint(-0.7, 0, -2), CubeBack},
+		{*NewPoint(0.5, 1, 0.9), CubeUp},
+		{*NewPoint(-0.2, -1.3, 1.1), CubeDown},
+	}
+	for _, tt := range tests {
+		if got := FaceFromPoint(tt.p); got != tt.want {
+			t.Errorf("FaceFromPoint(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestCubeFaceUV(t *testing.T) {
+	tests := []struct {
+		name string
+		face CubeFace
+		p    Tuple
+		u, v float64
+	}{
+		{name: "uv mapping the front face of a cube", face: CubeFront, p: *NewPoint(-0.5, 0.5, 1), u: 0.25, v: 0.75},
+		{name: "uv mapping the back face of a cube", face: CubeBack, p: *NewPoint(0.5, 0.5, -1), u: 0.25, v: 0.75},
+		{name: "uv mapping the left face of a cube", face: CubeLeft, p: *NewPoint(-1, 0.5, -0.5), u: 0.25, v: 0.75},
+		{name: "uv mapping the right face of a cube", face: CubeRight, p: *NewPoint(1, 0.5, 0.5), u: 0.25, v: 0.75},
+		{name: "uv mapping the upper face of a cube", face: CubeUp, p: *NewPoint(-0.5, 1, -0.5), u: 0.25, v: 0.75},
+		{name: "uv mapping the lower face of a cube", face: CubeDown, p: *NewPoint(-0.5, -1, 0.5), u: 0.25, v: 0.75},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, v := CubeFaceUV(tt.face, tt.p)
+			if !cmp.Equal(u, tt.u, float64Comparer) || !cmp.Equal(v, tt.v, float64Comparer) {
+				t.Errorf("CubeFaceUV() = %v, %v, want %v, %v", u, v, tt.u, tt.v)
+			}
+		})
+	}
+}
+
+func TestCanvas_Sample(t *testing.T) {
+	c := NewCanvas(2, 2)
+	c.WritePixel(0, 0, &Red)
+	c.WritePixel(1, 0, &White)
+	c.WritePixel(0, 1, &Black)
+	c.WritePixel(1, 1, &Color{0, 0, 1})
+
+	tests := []struct {
+		name string
+		u, v float64
+		want Color
+	}{
+		{name: "the center of a texel is its own color", u: 0.25, v: 0.75, want: Red},
+		{name: "the bottom row is at v = 0", u: 0.75, v: 0.25, want: Color{0, 0, 1}},
+		{name: "between two texels the colors are blended", u: 0.5, v: 0.75, want: Color{1, 0.5, 0.5}},
+		{name: "the middle of the canvas blends all four texels", u: 0.5, v: 0.5, want: Color{0.5, 0.25, 0.5}},
+		{name: "u wraps around horizontally", u: 0, v: 0.75, want: Color{1, 0.5, 0.5}},
+		{name: "coordinates that aren't numbers are taken as 0", u: math.NaN(), v: math.NaN(), want: Color{0, 0, 0.5}},
+		{name: "infinite coordinates are taken as 0", u: math.Inf(1), v: math.Inf(-1), want: Color{0, 0, 0.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Sample(tt.u, tt.v); !got.Equals(&tt.want) {
+				t.Errorf("Sample() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextureMapPattern_ColorAt_Origin(t *testing.T) {
+	c := NewCanvas(3, 3)
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			c.WritePixel(x, y, &Red)
+		}
+	}
+	p := NewTextureMapPattern(SphericalMap{}, c)
+
+	// a spherical map has no direction at the origin, so it maps it to coordinates that aren't numbers
+	if got := p.ColorAt(*NewPoint(0, 0, 0)); !got.Equals(&Red) {
+		t.Errorf("ColorAt(origin) = %v, want %v", got, Red)
+	}
+}
+
+func TestReadPPM(t *testing.T) {
+	tests := []struct {
+		name    string
+		ppm     string
+		want    Color
+		wantErr bool
+	}{
+		{
+			name: "reading a plain ppm",
+			ppm: `P3
+# a comment
+2 1
+255
+255 127 0  0 0 0
+`,
+			want: Color{1, 127.0 / 255, 0},
+		},
+		{
+			name: "reading a raw ppm",
+			ppm:  "P6\n1 1\n255\n\xff\x7f\x00",
+			want: Color{1, 127.0 / 255, 0},
+		},
+		{
+			name:    "reading a file that is not a ppm",
+			ppm:     "P5\n1 1\n255\n\x00",
+			wantErr: true,
+		},
+		{
+			name:    "reading a truncated ppm",
+			ppm:     "P3\n2 1\n255\n255 127",
+			wantErr: true,
+		},
+		{
+			name:    "reading a ppm with a negative width",
+			ppm:     "P3\n-2 1\n255\n",
+			wantErr: true,
+		},
+		{
+			name:    "reading a ppm with no pixels",
+			ppm:     "P6\n0 0\n255\n",
+			wantErr: true,
+		},
+		{
+			name:    "reading a ppm with a maximum value of 0",
+			ppm:     "P3\n1 1\n0\n0 0 0\n",
+			wantErr: true,
+		},
+		{
+			name:    "reading a ppm with a maximum value above 16 bits",
+			ppm:     "P3\n1 1\n65536\n0 0 0\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ReadPPM(strings.NewReader(tt.ppm))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadPPM() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := c.PixelAt(0, 0); !got.Equals(&tt.want) {
+				t.Errorf("ReadPPM() pixel = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadImage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "texture.png")
+	c := NewCanvas(3, 2)
+	c.WritePixel(2, 1, &Red)
+	if err := c.SavePNG(path); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadImage(path)
+	if err != nil {
+		t.Fatalf("LoadImage() error = %v", err)
+	}
+	if got.Width != 3 || got.Height != 2 || !got.PixelAt(2, 1).Equals(&Red) {
+		t.Errorf("LoadImage() = %v, want a 3x2 canvas with a red pixel", got)
+	}
+
+	if _, err := LoadImage(filepath.Join(t.TempDir(), "missing.png")); err == nil {
+		t.Errorf("LoadImage() of a missing file succeeded")
+	}
+}
+
+func TestTextureMapPattern_ColorAt(t *testing.T) {
+	c := NewCanvas(4, 1)
+	for x, color := range []Color{Red, White, Black, {0, 0, 1}} {
+		c.WritePixel(x, 0, &color)
+	}
+	p := NewTextureMapPattern(PlanarMap{}, c)
+
+	want := Black
+	if got := p.ColorAt(*NewPoint(0.625, 0, 0.5)); !got.Equals(&want) {
+		t.Errorf("ColorAt() = %v, want %v", got, want)
+	}
+}
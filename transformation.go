@@ -1,6 +1,9 @@
 package jtracer
 
-import "math"
+import (
+	"errors"
+	"math"
+)
 
 func NewTranslation(x, y, z float64) Matrix {
 	return Matrix{
@@ -77,3 +80,146 @@ func ViewTransform(from, to, up *Tuple) Matrix {
 		),
 	)
 }
+
+// ErrNotAffine is returned when decomposing a transform that doesn't keep parallel lines parallel
+var ErrNotAffine = errors.New("not an affine transform")
+
+// TransformStep is one of the named transforms of a scene file, such as translate, with its arguments
+type TransformStep struct {
+	Name string
+	Args []float64
+}
+
+// decomposeEpsilon is how close a step must be to doing nothing for DecomposeTransform to leave it out
+const decomposeEpsilon = 1e-9
+
+// DecomposeTransform breaks an affine transform into a shear, a scale, rotations about x, y and z, and a
+// translation, applied in that order. Steps that would do nothing are left out, so the identity gives none. A
+// transform that flattens space, such as a scale of zero, can't be decomposed.
+func DecomposeTransform(m Matrix) ([]TransformStep, error) {
+	if len(m) != 4 || m[3][0] != 0 || m[3][1] != 0 || m[3][2] != 0 || m[3][3] != 1 {
+		return nil, ErrNotAffine
+	}
+
+	// factor the linear part into a rotation q and an upper triangular r by Gram-Schmidt on its columns
+	var q, r [3][3]float64
+	for j := 0; j < 3; j++ {
+		v := [3]float64{m[0][j], m[1][j], m[2][j]}
+		for k := 0; k < j; k++ {
+			r[k][j] = q[0][k]*m[0][j] + q[1][k]*m[1][j] + q[2][k]*m[2][j]
+			for i := range v {
+				v[i] -= r[k][j] * q[i][k]
+			}
+		}
+
+		r[j][j] = math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+		if r[j][j] < epsilon {
+			return nil, ErrSingularTransform
+		}
+		for i := range v {
+			q[i][j] = v[i] / r[j][j]
+		}
+	}
+
+	// a reflection is a rotation with a negative scale along one of the axes; choose whichever axis leaves the least
+	// rotation
+	flips := []int{-1}
+	if det3(q) < 0 {
+		flips = []int{0, 1, 2}
+	}
+
+	var x, y, z float64
+	var scale []float64
+	best := math.Inf(1)
+	for _, flip := range flips {
+		fq := q
+		fs := []float64{r[0][0], r[1][1], r[2][2]}
+		if flip >= 0 {
+			for i := 0; i < 3; i++ {
+				fq[i][flip] = -fq[i][flip]
+			}
+			fs[flip] = -fs[flip]
+		}
+
+		if fx, fy, fz := eulerAngles(fq); math.Abs(fx)+math.Abs(fy)+math.Abs(fz) < best-decomposeEpsilon {
+			x, y, z, scale = fx, fy, fz, fs
+			best = math.Abs(fx) + math.Abs(fy) + math.Abs(fz)
+		}
+	}
+
+	var steps []TransformStep
+	shear := []float64{r[0][1] / r[0][0], r[0][2] / r[0][0], 0, r[1][2] / r[1][1], 0, 0}
+	snap(shear, scale)
+	if !isNear(shear, 0) {
+		steps = append(steps, TransformStep{"shear", shear})
+	}
+	if !isNear(scale, 1) {
+		steps = append(steps, TransformStep{"scale", scale})
+	}
+	for _, rotation := range []struct {
+		name  string
+		angle float64
+	}{{"rotate-x", x}, {"rotate-y", y}, {"rotate-z", z}} {
+		if !isNear([]float64{rotation.angle}, 0) {
+			steps = append(steps, TransformStep{rotation.name, []float64{rotation.angle}})
+		}
+	}
+	if translation := []float64{m[0][3], m[1][3], m[2][3]}; !isNear(translation, 0) {
+		snap(translation)
+		steps = append(steps, TransformStep{"translate", translation})
+	}
+
+	return steps, nil
+}
+
+// snap rounds numbers within decomposeEpsilon of a whole number to it, clearing away rounding errors
+func snap(fss ...[]float64) {
+	for _, fs := range fss {
+		for i, f := range fs {
+			if r := math.Round(f); math.Abs(f-r) < decomposeEpsilon {
+				fs[i] = r
+			}
+		}
+	}
+}
+
+// isNear reports whether every one of fs is within decomposeEpsilon of f
+func isNear(fs []float64, f float64) bool {
+	for _, v := range fs {
+		if math.Abs(v-f) > decomposeEpsilon {
+			return false
+		}
+	}
+	return true
+}
+
+// wrapAngle returns the angle equivalent to a, in the range -π to π
+func wrapAngle(a float64) float64 {
+	return math.Remainder(a, 2*math.Pi)
+}
+
+// det3 returns the determinant of a 3x3 matrix
+func det3(m [3][3]float64) float64 {
+	return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+}
+
+// eulerAngles returns the angles of the rotations about x, y and z, applied in that order, that compose the rotation
+// q. As x+π, π-y and z+π compose the same rotation, whichever turns least is chosen.
+func eulerAngles(q [3][3]float64) (x, y, z float64) {
+	y = math.Asin(math.Max(-1, math.Min(1, -q[2][0])))
+	if math.Cos(y) > decomposeEpsilon {
+		x = math.Atan2(q[2][1], q[2][2])
+		z = math.Atan2(q[1][0], q[0][0])
+	} else {
+		// gimbal lock, where rotations about x and z are the same, so z is left out
+		x = math.Atan2(-q[1][2], q[1][1])
+	}
+
+	x2, y2, z2 := wrapAngle(x+math.Pi), wrapAngle(math.Pi-y), wrapAngle(z+math.Pi)
+	if math.Abs(x2)+math.Abs(y2)+math.Abs(z2) < math.Abs(x)+math.Abs(y)+math.Abs(z)-decomposeEpsilon {
+		return x2, y2, z2
+	}
+	return x, y, z
+}
@@ -0,0 +1,71 @@
+package jtracer
+
+import "math"
+
+// SurfacePattern is implemented by patterns whose color depends on which way the surface faces as well as where it
+// is. PatternAtShape passes them the normal, transformed into pattern space along with the point.
+type SurfacePattern interface {
+	Pattern
+	ColorAtSurface(point, normal Tuple) Color
+}
+
+// TriplanarPattern projects an image, or an inner pattern, along each of the x, y and z axes and blends the three
+// projections by how squarely the surface faces each axis. It textures any shape without needing texture coordinates,
+// and without the seams and pinching of wrapping a single projection around it.
+type TriplanarPattern struct {
+	Image     *Canvas
+	File      string  // the image file the texture was loaded from, if any
+	Pattern   Pattern // when set, projected in place of the image, flattened onto its xz plane
+	Sharpness float64 // raises the blend weights to this power, narrowing the transitions; 1 when zero
+	AbstractPattern
+}
+
+func NewTriplanarPattern(img *Canvas) *TriplanarPattern {
+	p := &TriplanarPattern{Image: img, Sharpness: 4}
+	p.SetTransform(IdentityMatrix)
+	return p
+}
+
+// ColorAt blends the projections by the direction of the point from the origin, which is the surface normal for a
+// sphere. Shapes of other kinds are given their normal through ColorAtSurface.
+func (s *TriplanarPattern) ColorAt(p Tuple) Color {
+	return s.ColorAtSurface(p, *NewVector(p.X, p.Y, p.Z))
+}
+
+func (s *TriplanarPattern) ColorAtSurface(p, normal Tuple) Color {
+	sharpness := nonZero(s.Sharpness, 1)
+	wx := math.Pow(math.Abs(normal.X), sharpness)
+	wy := math.Pow(math.Abs(normal.Y), sharpness)
+	wz := math.Pow(math.Abs(normal.Z), sharpness)
+
+	total := wx + wy + wz
+	if total == 0 {
+		wy, total = 1, 1
+	}
+
+	var c Color
+	for _, projection := range []struct {
+		weight float64
+		u, v   float64
+	}{
+		{wx, p.Z, p.Y},
+		{wy, p.X, p.Z},
+		{wz, p.X, p.Y},
+	} {
+		if projection.weight == 0 {
+			continue
+		}
+		sample := s.project(projection.u, projection.v)
+		c = *c.Add(sample.MultiplyByScalar(projection.weight / total))
+	}
+
+	return c
+}
+
+// project returns the color at coordinates u and v of the flat texture being projected
+func (s *TriplanarPattern) project(u, v float64) Color {
+	if s.Pattern != nil {
+		return PatternAt(s.Pattern, *NewPoint(u, 0, v))
+	}
+	return s.Image.Sample(fraction(u), fraction(v))
+}
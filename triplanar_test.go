@@ -0,0 +1,84 @@
+package jtracer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTriplanarPattern_ColorAtSurface(t *testing.T) {
+	tests := []struct {
+		name      string
+		sharpness float64
+		point     Tuple
+		normal    Tuple
+		want      Color
+	}{
+		{
+			name:   "a surface facing along x sees the yz projection",
+			point:  *NewPoint(1, 0.2, 0.3),
+			normal: *NewVector(1, 0, 0),
+			want:   Color{0.3, 0, 0.2},
+		},
+		{
+			name:   "a surface facing along y sees the xz projection",
+			point:  *NewPoint(0.1, 1, 0.3),
+			normal: *NewVector(0, 1, 0),
+			want:   Color{0.1, 0, 0.3},
+		},
+		{
+			name:   "a surface facing along -z sees the xy projection",
+			point:  *NewPoint(0.1, 0.2, -1),
+			normal: *NewVector(0, 0, -1),
+			want:   Color{0.1, 0, 0.2},
+		},
+		{
+			name:      "a surface between two axes blends their projections",
+			sharpness: 1,
+			point:     *NewPoint(0.5, 0.5, 0.2),
+			normal:    *NewVector(math.Sqrt(2)/2, math.Sqrt(2)/2, 0),
+			want:      Color{0.35, 0, 0.35},
+		},
+		{
+			name:      "sharpness favors the axis the surface faces most",
+			sharpness: 8,
+			point:     *NewPoint(0.5, 0.5, 0.2),
+			normal:    *NewVector(0.9, 0.1, 0).Normalize(),
+			want:      Color{0.2, 0, 0.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewTriplanarPattern(nil)
+			p.Pattern = NewTestPattern()
+			p.Sharpness = tt.sharpness
+			if got := p.ColorAtSurface(tt.point, tt.normal); !got.Equals(&tt.want) {
+				t.Errorf("ColorAtSurface() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTriplanarPattern_Image(t *testing.T) {
+	img := NewCanvas(2, 1)
+	img.Data[0][0] = Red
+	img.Data[0][1] = White
+	p := NewTriplanarPattern(img)
+
+	if got := p.ColorAtSurface(*NewPoint(0.25, 0, 0.5), *NewVector(0, 1, 0)); !got.Equals(&Red) {
+		t.Errorf("ColorAtSurface() = %v, want %v", got, Red)
+	}
+	if got := p.ColorAtSurface(*NewPoint(0.75, 0, 0.5), *NewVector(0, 1, 0)); !got.Equals(&White) {
+		t.Errorf("ColorAtSurface() = %v, want %v", got, White)
+	}
+}
+
+func TestPatternAtShape_SurfacePattern(t *testing.T) {
+	p := NewTriplanarPattern(nil)
+	p.Pattern = NewTestPattern()
+
+	// a point on a plane lies along x and z from the origin, but the plane faces straight up
+	want := Color{0.1, 0, 0.3}
+	if got := PatternAtShape(p, NewPlane(), *NewPoint(0.1, 0, 0.3)); !got.Equals(&want) {
+		t.Errorf("PatternAtShape() = %v, want %v", got, want)
+	}
+}
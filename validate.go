@@ -0,0 +1,229 @@
+package jtracer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrNoCamera          = errors.New("no camera")
+	ErrNoLight           = errors.New("no light")
+	ErrSingularTransform = errors.New("singular transform")
+	ErrNoRefraction      = errors.New("transparent material has a refractive index of 1, so it won't bend light")
+	ErrUnusedDefine      = errors.New("unused define")
+)
+
+// Severity says whether a problem stops a scene from rendering as intended
+type Severity int
+
+const (
+	Warning Severity = iota
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Problem is something wrong with a scene, found by ValidateScene. Err is a *SceneError pointing at the YAML at fault
+// where there is any.
+type Problem struct {
+	Severity Severity
+	Err      error
+}
+
+func (p Problem) Error() string {
+	return p.Severity.String() + ": " + p.Err.Error()
+}
+
+func (p Problem) Unwrap() error {
+	return p.Err
+}
+
+// HasErrors reports whether any of the problems is an Error rather than a Warning
+func HasErrors(problems []Problem) bool {
+	for _, p := range problems {
+		if p.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateSceneFile reads a scene file and checks it with ValidateScene, or ValidateSceneJSON when its name ends in
+// .json. The error is for the file itself being unreadable; problems with its contents are returned as Problems.
+func ValidateSceneFile(path string) ([]Problem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if isJSONFile(path) {
+		return validateSceneJSON(data, path, filepath.Dir(path)), nil
+	}
+	return validateScene(data, path, filepath.Dir(path)), nil
+}
+
+// ValidateScene loads a scene as ParseScene does, but carries on past entries it can't load so that every problem is
+// reported, then lints what was loaded for mistakes that would spoil a render without stopping it
+func ValidateScene(data []byte, dir string) []Problem {
+	return validateScene(data, "", dir)
+}
+
+// validateScene checks a scene read from file, if any. Defines that go unused are only reported in file itself, as
+// included libraries are expected to define more than any one scene needs.
+func validateScene(data []byte, file, dir string) []Problem {
+	var including []string
+	if file != "" {
+		including = []string{file}
+	}
+
+	entries, err := sceneEntries(data, file, dir, including)
+	if err != nil {
+		return []Problem{{Error, err}}
+	}
+
+	var problems []Problem
+	report := func(severity Severity, err error, entry sceneEntry) {
+		problems = append(problems, Problem{severity, entry.locate(err)})
+	}
+
+	defs, errs := collectDefines(entries)
+	entries, repeatErrs := expandRepeats(entries, defs)
+	for _, err := range append(errs, repeatErrs...) {
+		problems = append(problems, Problem{Error, err})
+	}
+
+	var scene Scene
+	added := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.node.Kind != yaml.MappingNode || lookup(entry.node, "define") != nil {
+			continue
+		}
+
+		objects := len(scene.Objects)
+		if err := scene.add(entry, defs); err != nil {
+			// skip the entry and carry on
+			if len(scene.Objects) > objects {
+				// the shape was added before the problem was found
+				scene.Objects = scene.Objects[:objects]
+			}
+			report(Error, err, entry)
+			continue
+		}
+
+		added[lookup(entry.node, "add").Value] = true
+		if len(scene.Objects) > objects {
+			for _, p := range lintShape(scene.Objects[objects], entry.node) {
+				report(p.Severity, p.Err, entry)
+			}
+		}
+	}
+
+	whole := sceneEntry{file: file, index: -1}
+	if !added["camera"] {
+		report(Error, ErrNoCamera, whole)
+	}
+	if !added["light"] && !added["environment"] {
+		report(Error, ErrNoLight, whole)
+	}
+
+	var unused []*define
+	for _, def := range defs {
+		if !def.used && def.entry.file == file {
+			unused = append(unused, def)
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool { return unused[i].order < unused[j].order })
+	for _, def := range unused {
+		report(Warning, nodeError(def.name, "define", fmt.Errorf("%w %q", ErrUnusedDefine, def.name.Value)), def.entry)
+	}
+
+	return problems
+}
+
+// ValidateSceneJSON checks a JSON scene as ValidateScene does. The types of a JSON scene are checked as it is decoded,
+// so a scene that doesn't decode is reported as a single problem.
+func ValidateSceneJSON(data []byte, dir string) []Problem {
+	return validateSceneJSON(data, "", dir)
+}
+
+func validateSceneJSON(data []byte, file, dir string) []Problem {
+	scene, err := parseSceneJSON(data, file, dir)
+	if err != nil {
+		return []Problem{{Error, err}}
+	}
+
+	problems := LintScene(scene)
+	for _, p := range problems {
+		inEntry(p.Err, -1, file)
+	}
+	return problems
+}
+
+// LintScene checks a scene that has already been built, by a loader or in code, for a missing camera or light, for an
+// environment map without pixels, and for the mistakes in its objects that ValidateScene reports. Problems with an
+// object are a *SceneError whose Index is that of the object.
+func LintScene(scene *Scene) []Problem {
+	var problems []Problem
+	report := func(severity Severity, err error, index int) {
+		problems = append(problems, Problem{severity, inEntry(err, index, "")})
+	}
+
+	if scene.Camera.Hsize == 0 {
+		report(Error, ErrNoCamera, -1)
+	}
+	if scene.Light == (Light{}) && scene.Environment == nil {
+		report(Error, ErrNoLight, -1)
+	}
+	if env := scene.Environment; env != nil && (env.Map == nil || env.Map.Width == 0 || env.Map.Height == 0) {
+		report(Error, nodeError(nil, "environment", fmt.Errorf("%w: the environment map has no pixels", ErrInvalidValue)), -1)
+	}
+	for i, s := range scene.Objects {
+		for _, p := range lintShape(s, nil) {
+			report(p.Severity, p.Err, i)
+		}
+	}
+
+	return problems
+}
+
+// lintShape checks a shape loaded from entry, if any, for a transform that can't be inverted and for transparency that
+// doesn't refract
+func lintShape(s Shape, entry *yaml.Node) []Problem {
+	var problems []Problem
+
+	if isSingular(s.GetTransform()) {
+		problems = append(problems, Problem{Error, nodeError(lookup(entry, "transform"), "transform", ErrSingularTransform)})
+	}
+
+	m := s.GetMaterial()
+	if m.HasPattern && isSingular(m.Pattern.GetTransform()) {
+		problems = append(problems, Problem{Error, nodeError(lookup(entry, "material"), "pattern", ErrSingularTransform)})
+	}
+	if m.Transparency > 0 && m.RefractiveIndex == 1 && m.Dispersion.Model == NoDispersion {
+		problems = append(problems, Problem{Warning, nodeError(lookup(entry, "material"), "material", ErrNoRefraction)})
+	}
+
+	return problems
+}
+
+// isSingular reports whether a transform squashes space flat, as a scale of zero does, so that it has no inverse. The
+// determinant is measured against the lengths of the transformed axes, so that a transform that only makes things
+// very small isn't taken for one that flattens them.
+func isSingular(m Matrix) bool {
+	size := 1.0
+	for col := 0; col < 3; col++ {
+		size *= math.Sqrt(m[0][col]*m[0][col] + m[1][col]*m[1][col] + m[2][col]*m[2][col])
+	}
+	return math.Abs(m.Determinant()) <= epsilon*size
+}
@@ -0,0 +1,127 @@
+package jtracer
+
+import (
+	"errors"
+	"testing"
+)
+
+const validCameraAndLight = `
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+  from: [0, 0, -5]
+  to: [0, 0, 0]
+  up: [0, 1, 0]
+- add: light
+  at: [-10, 10, -10]
+  intensity: [1, 1, 1]
+`
+
+func TestValidateScene(t *testing.T) {
+	type problem struct {
+		severity Severity
+		err      error
+		index    int
+	}
+	tests := []struct {
+		name string
+		yaml string
+		want []problem
+	}{
+		{
+			name: "a complete scene has no problems",
+			yaml: validCameraAndLight + "- add: sphere\n",
+		},
+		{
+			name: "an empty scene has neither camera nor light",
+			yaml: "",
+			want: []problem{{Error, ErrNoCamera, -1}, {Error, ErrNoLight, -1}},
+		},
+		{
+			name: "every entry that can't be loaded is reported",
+			yaml: validCameraAndLight + "- add: cube\n- add: sphere\n  material:\n    colour: [1, 0, 0]\n- add: plane\n",
+			want: []problem{{Error, ErrUnknownType, 2}, {Error, ErrUnknownKey, 3}},
+		},
+		{
+			name: "a zero scale is singular",
+			yaml: validCameraAndLight + "- add: sphere\n  transform:\n    - [scale, 1, 0, 1]\n",
+			want: []problem{{Error, ErrSingularTransform, 2}},
+		},
+		{
+			name: "a singular pattern transform",
+			yaml: validCameraAndLight + "- add: plane\n  material:\n    pattern:\n      type: stripes\n      colors: [[1, 1, 1], [0, 0, 0]]\n      transform:\n        - [scale, 0, 0, 0]\n",
+			want: []problem{{Error, ErrSingularTransform, 2}},
+		},
+		{
+			name: "a small scale isn't singular",
+			yaml: validCameraAndLight + "- add: sphere\n  transform:\n    - [scale, 0.02, 0.02, 0.02]\n",
+		},
+		{
+			name: "a small pattern scale isn't singular",
+			yaml: validCameraAndLight + "- add: plane\n  material:\n    pattern:\n      type: stripes\n      colors: [[1, 1, 1], [0, 0, 0]]\n      transform:\n        - [scale, 0.01, 0.01, 0.01]\n",
+		},
+		{
+			name: "axes turned onto each other are singular",
+			yaml: validCameraAndLight + "- add: sphere\n  transform:\n    - [shear, 1, 0, 1, 0, 0, 0]\n",
+			want: []problem{{Error, ErrSingularTransform, 2}},
+		},
+		{
+			name: "transparency without refraction",
+			yaml: validCameraAndLight + "- add: sphere\n  material:\n    transparency: 0.9\n",
+			want: []problem{{Warning, ErrNoRefraction, 2}},
+		},
+		{
+			name: "transparency with refraction",
+			yaml: validCameraAndLight + "- add: sphere\n  material:\n    transparency: 0.9\n    refractive-index: 1.5\n",
+		},
+		{
+			name: "unused defines",
+			yaml: "- define: red\n  value:\n    color: [1, 0, 0]\n- define: blue\n  value:\n    color: [0, 0, 1]\n" +
+				validCameraAndLight + "- add: sphere\n  material: blue\n- define: green\n  value:\n    color: [0, 1, 0]\n",
+			want: []problem{{Warning, ErrUnusedDefine, 0}, {Warning, ErrUnusedDefine, 5}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ValidateScene([]byte(tt.yaml), ".")
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("ValidateScene() = %v, want %d problems", got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				var se *SceneError
+				if !errors.As(got[i], &se) {
+					t.Fatalf("ValidateScene()[%d] = %v, want a *SceneError", i, got[i])
+				}
+				if got[i].Severity != want.severity || se.Index != want.index || !errors.Is(got[i], want.err) {
+					t.Errorf("ValidateScene()[%d] = %v, want a %v in entry %d: %v", i, got[i], want.severity, want.index, want.err)
+				}
+			}
+
+			wantErrors := false
+			for _, p := range tt.want {
+				wantErrors = wantErrors || p.severity == Error
+			}
+			if HasErrors(got) != wantErrors {
+				t.Errorf("HasErrors() = %v, want %v", HasErrors(got), wantErrors)
+			}
+		})
+	}
+}
+
+func TestValidateSceneFile_BundledScenes(t *testing.T) {
+	files := bundledScenes(t)
+
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			problems, err := ValidateSceneFile(file)
+			if err != nil {
+				t.Fatalf("ValidateSceneFile() error = %v", err)
+			}
+			if HasErrors(problems) {
+				t.Errorf("ValidateSceneFile() = %v, want no errors", problems)
+			}
+		})
+	}
+}
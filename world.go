@@ -6,8 +6,10 @@ import (
 )
 
 type World struct {
-	Objects []Shape
-	Light   Light
+	Objects     []Shape
+	Light       Light
+	Background  Background        // seen by rays that miss every object, black when nil
+	Environment *EnvironmentLight // lights the world from every direction when set, and is seen when Background is nil
 }
 
 func NewWorld() World {
@@ -55,17 +57,35 @@ func (w World) ColorAt(r Ray, remaining int) *Color {
 	xs := w.Intersect(r)
 	hit := xs.Hit()
 	if hit == nil {
-		return &Black
+		return w.BackgroundColor(r)
 	}
 
 	comps := hit.PrepareComputations(r, xs)
 	return w.ShadeHit(comps, remaining)
 }
 
+// BackgroundColor returns the color seen along a ray that misses every object
+func (w World) BackgroundColor(r Ray) *Color {
+	background := w.Background
+	if background == nil && w.Environment != nil {
+		background = w.Environment
+	}
+	if background == nil {
+		return &Black
+	}
+
+	c := background.ColorAt(*r.Direction)
+	return &c
+}
+
 func (w World) ShadeHit(comps Computations, remaining int) *Color {
 	shadowed := w.IsShadowed(comps.OverPoint)
 
-	surface := comps.Object.GetMaterial().Lighting(comps.Object, w.Light, comps.OverPoint, comps.Eyev, comps.Normalv, shadowed)
+	surface := comps.SurfaceMaterial().Lighting(comps.Object, w.Light, comps.OverPoint, comps.Eyev, comps.Normalv, shadowed)
+	if w.Environment != nil {
+		environment := w.EnvironmentLighting(comps, remaining)
+		surface = *surface.Add(&environment)
+	}
 	reflected := w.ReflectedColor(comps, remaining)
 	refracted := w.RefractedColor(comps, remaining)
 
@@ -98,10 +118,12 @@ func (w World) ShadeHit(comps Computations, remaining int) *Color {
 
 func (w World) IsShadowed(p Tuple) bool {
 	v := w.Light.Position.Subtract(&p)
-	distance := v.Magnitude()
-	direction := v.Normalize()
+	return w.isOccluded(p, *v.Normalize(), v.Magnitude())
+}
 
-	r := NewRay(&p, direction)
+// isOccluded reports whether any object lies along direction from p nearer than distance
+func (w World) isOccluded(p, direction Tuple, distance float64) bool {
+	r := NewRay(&p, &direction)
 	intersections := w.Intersect(r)
 
 	h := intersections.Hit()
@@ -119,15 +141,33 @@ func (w World) ReflectedColor(comps Computations, remaining int) Color {
 		return Black
 	}
 
-	reflectRay := Ray{Origin: &comps.OverPoint, Direction: &comps.Reflectv}
-	color := w.ColorAt(reflectRay, remaining-1)
+	material := comps.Object.GetMaterial()
+
+	var color Color
+	if material.Roughness > 0 {
+		color = w.traceLobe(comps.OverPoint, comps.Reflectv, comps.Normalv, 1, material, remaining, comps.Wavelength)
+	} else {
+		reflectRay := Ray{
+			Origin:        &comps.OverPoint,
+			Direction:     &comps.Reflectv,
+			Wavelength:    comps.Wavelength,
+			Differentials: comps.ReflectedDifferentials(),
+		}
+		color = *w.ColorAt(reflectRay, remaining-1)
+	}
 	//
 	//spew.Dump("OrigRay", NewVector(0, -math.Sqrt(2)/2, math.Sqrt(2)/2))
 	//spew.Dump("Reflectv", comps.Reflectv)
 	//spew.Dump("Color at the result of reflected ray", color)
 	//spew.Dump("Reflectivity of this material", comps.Object.GetMaterial().Reflectivity)
 
-	return *color.MultiplyByScalar(comps.Object.GetMaterial().Reflectivity)
+	// metals reflect more strongly, and more neutrally, as the viewing angle approaches grazing
+	if material.IsConductor() {
+		fresnel := ConductorFresnel(material.Conductor, comps.Eyev.Dot(&comps.Normalv))
+		color = *color.Multiply(&fresnel)
+	}
+
+	return *color.MultiplyByScalar(material.Reflectivity)
 }
 
 func (w World) RefractedColor(comps Computations, remaining int) Color {
@@ -135,35 +175,35 @@ func (w World) RefractedColor(comps Computations, remaining int) Color {
 		return Black
 	}
 
-	// Start check for total internal reflection
-	// find the ratio of the first index of refraction to the second
-	nRatio := comps.N1 / comps.N2
-	// cos(theta_i) is the same as the dot product of the two vectors
-	cosI := comps.Eyev.Dot(&comps.Normalv)
-	sin2T := (nRatio * nRatio) * (1 - (cosI * cosI))
-	if sin2T > 1 {
-		return Black
+	// white light entering or leaving a dispersive material is split into separate wavelengths, each of which
+	// refracts at its own angle, and recombined afterwards
+	if comps.Wavelength == 0 && comps.dispersive() {
+		var result Color
+		for _, sample := range SpectralSamples {
+			c := comps
+			c.Wavelength = sample.Wavelength
+			c.N1, c.N2 = comps.IndicesAt(sample.Wavelength)
+
+			color := w.RefractedColor(c, remaining)
+			result = *result.Add(color.Multiply(&sample.Weight))
+		}
+		return result
 	}
-	// End check for total internal reflection TODO: move to function?
 
-	// Find cos(theta_t) via trigonometric identity
-	//cos_t ← sqrt(1.0 - sin2_t)
-
-	cosT := math.Sqrt(1.0 - sin2T)
-
-	// Compute the direction of the refracted ray
-	//direction ← comps.normalv * (n_ratio * cos_i - cos_t) -
-	//             comps.eyev * n_ratio
-
-	//foo := (nRatio * cosI) - cosT
-	//foo2 := comps.Eyev.Multiply(nRatio)
+	direction, ok := refractDirection(comps.Eyev, comps.Normalv, comps.N1/comps.N2)
+	if !ok {
+		return Black
+	}
 
-	baz1 := comps.Normalv.Multiply((nRatio * cosI) - cosT)
-	baz2 := comps.Eyev.Multiply(nRatio)
+	material := comps.Object.GetMaterial()
+	if material.Roughness > 0 {
+		color := w.traceLobe(comps.UnderPoint, direction, comps.Normalv, -1, material, remaining, comps.Wavelength)
+		return *color.MultiplyByScalar(material.Transparency)
+	}
 
-	// Create the refracted ray
-	//refract_ray ← ray(comps.under_point, direction)
-	refractRay := NewRay(&comps.UnderPoint, baz1.Subtract(baz2))
+	refractRay := NewRay(&comps.UnderPoint, &direction)
+	refractRay.Wavelength = comps.Wavelength
+	refractRay.Differentials = comps.RefractedDifferentials(direction)
 
 	//
 	//# Find the color of the refracted ray, making sure to multiply
@@ -176,3 +216,21 @@ func (w World) RefractedColor(comps Computations, remaining int) Color {
 
 	return *color.MultiplyByScalar(comps.Object.GetMaterial().Transparency)
 }
+
+// refractDirection bends a ray arriving opposite eyev through a surface with the given normal, where nRatio is the
+// ratio of the refractive index being exited to the one being entered. It reports false when the ray is totally
+// internally reflected instead.
+func refractDirection(eyev, normalv Tuple, nRatio float64) (Tuple, bool) {
+	// cos(theta_i) is the same as the dot product of the two vectors
+	cosI := eyev.Dot(&normalv)
+	sin2T := (nRatio * nRatio) * (1 - (cosI * cosI))
+	if sin2T > 1 {
+		return Tuple{}, false
+	}
+
+	// Find cos(theta_t) via trigonometric identity
+	cosT := math.Sqrt(1.0 - sin2T)
+
+	// direction = normalv * (n_ratio * cos_i - cos_t) - eyev * n_ratio
+	return *normalv.Multiply((nRatio * cosI) - cosT).Subtract(eyev.Multiply(nRatio)), true
+}
@@ -27,7 +27,7 @@ func TestWorld_Intersect(t *testing.T) {
 	}{
 		{
 			name:   "intersect a world with a ray",
-			fields: fields(dw),
+			fields: fields{Objects: dw.Objects, Light: dw.Light},
 			args: args{
 				r: Ray{
 					Origin:    NewPoint(0, 0, -5),
@@ -267,7 +267,7 @@ func TestWorld_ReflectedColor(t *testing.T) {
 	}{
 		{
 			name:   "the reflected color for a nonreflective material",
-			fields: fields(dw),
+			fields: fields{Objects: dw.Objects, Light: dw.Light},
 			args: args{
 				comps: func() Computations {
 					shape := dw.Objects[1].(*Sphere)
@@ -284,7 +284,7 @@ func TestWorld_ReflectedColor(t *testing.T) {
 		},
 		{
 			name:   "the reflected color for a reflective material",
-			fields: fields(defaultWorldWithReflectivePlane),
+			fields: fields{Objects: defaultWorldWithReflectivePlane.Objects, Light: defaultWorldWithReflectivePlane.Light},
 			args: args{
 				comps: func() Computations {
 					i := Intersection{T: math.Sqrt(2), Object: defaultWorldWithReflectivePlane.Objects[2]}
@@ -303,7 +303,7 @@ func TestWorld_ReflectedColor(t *testing.T) {
 		},
 		{
 			name:   "the reflected color at the maximum recursive depth",
-			fields: fields(defaultWorldWithReflectivePlane),
+			fields: fields{Objects: defaultWorldWithReflectivePlane.Objects, Light: defaultWorldWithReflectivePlane.Light},
 			args: args{
 				remaining: 0,
 				comps: func() Computations {
@@ -388,7 +388,7 @@ func TestWorld_RefractedColor(t *testing.T) {
 	}{
 		{
 			name:   "the refracted color with an opaque surface",
-			fields: fields(dw),
+			fields: fields{Objects: dw.Objects, Light: dw.Light},
 			args: args{
 				comps: func() Computations {
 					xs := Intersections{